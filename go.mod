@@ -1,3 +1,9 @@
 module github.com/ishk9/flog
 
 go 1.25.3
+
+require (
+	github.com/klauspost/compress v1.20.1
+	github.com/ulikunitz/xz v0.5.17
+	gopkg.in/yaml.v3 v3.0.1
+)
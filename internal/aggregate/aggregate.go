@@ -0,0 +1,207 @@
+// Package aggregate provides group-by aggregation over filtered log entries.
+package aggregate
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Func represents an aggregation function.
+type Func int
+
+const (
+	FuncCount Func = iota // Number of entries in the group
+	FuncSum               // Sum of a numeric field
+	FuncAvg               // Mean of a numeric field
+	FuncMin               // Minimum of a numeric field
+	FuncMax               // Maximum of a numeric field
+)
+
+var funcNames = map[string]Func{
+	"count": FuncCount,
+	"sum":   FuncSum,
+	"avg":   FuncAvg,
+	"min":   FuncMin,
+	"max":   FuncMax,
+}
+
+// MissingKey is the group key used for entries without the group-by field.
+const MissingKey = "(none)"
+
+// Spec describes an aggregation: a function and the field it applies to.
+type Spec struct {
+	Func  Func
+	Field string // Empty for FuncCount
+}
+
+// ParseSpec parses an --agg value: count, sum:field, avg:field, min:field
+// or max:field.
+func ParseSpec(s string) (Spec, error) {
+	name, field, _ := strings.Cut(strings.TrimSpace(s), ":")
+	fn, ok := funcNames[name]
+	if !ok {
+		return Spec{}, fmt.Errorf("aggregate: unknown function %q", name)
+	}
+	if fn == FuncCount {
+		if field != "" {
+			return Spec{}, fmt.Errorf("aggregate: count takes no field")
+		}
+		return Spec{Func: fn}, nil
+	}
+	if field == "" {
+		return Spec{}, fmt.Errorf("aggregate: %s requires a field (%s:field)", name, name)
+	}
+	return Spec{Func: fn, Field: field}, nil
+}
+
+// String returns the spec as a column label, e.g. "count" or "avg(duration)".
+func (s Spec) String() string {
+	for name, fn := range funcNames {
+		if fn == s.Func {
+			if s.Field == "" {
+				return name
+			}
+			return name + "(" + s.Field + ")"
+		}
+	}
+	return "?"
+}
+
+// Group holds the running aggregate for one group key.
+type Group struct {
+	Key     string
+	Count   int64   // Entries in the group
+	Numeric int64   // Entries with a numeric value for the spec field
+	Sum     float64 // Sum of numeric values
+	Min     float64 // Smallest numeric value
+	Max     float64 // Largest numeric value
+}
+
+// Value returns the group's result for the given spec. Sum/avg/min/max
+// are NaN when the group saw no numeric values.
+func (g *Group) Value(spec Spec) float64 {
+	if spec.Func == FuncCount {
+		return float64(g.Count)
+	}
+	if g.Numeric == 0 {
+		return math.NaN()
+	}
+	switch spec.Func {
+	case FuncSum:
+		return g.Sum
+	case FuncAvg:
+		return g.Sum / float64(g.Numeric)
+	case FuncMin:
+		return g.Min
+	default:
+		return g.Max
+	}
+}
+
+// Aggregator collects entries into groups keyed by a field value.
+// It is not safe for concurrent use; feed it from the pipeline's merger.
+type Aggregator struct {
+	GroupBy string
+	Spec    Spec
+	groups  map[string]*Group
+}
+
+// New creates an Aggregator grouping by the given field.
+func New(groupBy string, spec Spec) *Aggregator {
+	return &Aggregator{
+		GroupBy: groupBy,
+		Spec:    spec,
+		groups:  make(map[string]*Group),
+	}
+}
+
+// Add folds a matched entry into its group. Multi-valued fields such as
+// _tags add the entry to one group per value.
+func (a *Aggregator) Add(entry *parser.LogEntry) {
+	v, ok := entry.Fields[a.GroupBy]
+	if !ok {
+		a.addTo(MissingKey, entry)
+		return
+	}
+	if values, ok := v.([]string); ok {
+		for _, key := range values {
+			a.addTo(key, entry)
+		}
+		return
+	}
+	a.addTo(filter.ToString(v), entry)
+}
+
+func (a *Aggregator) addTo(key string, entry *parser.LogEntry) {
+	g, ok := a.groups[key]
+	if !ok {
+		g = &Group{Key: key}
+		a.groups[key] = g
+	}
+	g.Count++
+
+	if a.Spec.Func == FuncCount {
+		return
+	}
+	f, ok := filter.ToFloat(entry.Fields[a.Spec.Field])
+	if !ok {
+		return
+	}
+	if g.Numeric == 0 || f < g.Min {
+		g.Min = f
+	}
+	if g.Numeric == 0 || f > g.Max {
+		g.Max = f
+	}
+	g.Sum += f
+	g.Numeric++
+}
+
+// Results returns the groups sorted by aggregate value (descending), then
+// by key. Groups without a numeric value sort last.
+func (a *Aggregator) Results() []*Group {
+	groups := make([]*Group, 0, len(a.groups))
+	for _, g := range a.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		vi, vj := groups[i].Value(a.Spec), groups[j].Value(a.Spec)
+		switch {
+		case math.IsNaN(vi) != math.IsNaN(vj):
+			return math.IsNaN(vj)
+		case vi != vj && !math.IsNaN(vi):
+			return vi > vj
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+// Render writes the sorted results as an aligned table.
+func (a *Aggregator) Render(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\n", a.GroupBy, a.Spec)
+	for _, g := range a.Results() {
+		fmt.Fprintf(tw, "%s\t%s\n", g.Key, FormatValue(g.Value(a.Spec)))
+	}
+	return tw.Flush()
+}
+
+// FormatValue renders an aggregate value, dropping needless decimals.
+func FormatValue(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
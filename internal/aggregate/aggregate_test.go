@@ -0,0 +1,205 @@
+package aggregate
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func entry(fields map[string]any) *parser.LogEntry {
+	return &parser.LogEntry{Fields: fields}
+}
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Spec
+		err  bool
+	}{
+		{"count", Spec{Func: FuncCount}, false},
+		{" avg:duration ", Spec{Func: FuncAvg, Field: "duration"}, false},
+		{"max:bytes", Spec{Func: FuncMax, Field: "bytes"}, false},
+		{"count:x", Spec{}, true},
+		{"sum", Spec{}, true},
+		{"median:x", Spec{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSpec(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseSpec(%q) = %+v, %v; want %+v, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+		if err == nil {
+			if back, _ := ParseSpec(specArg(got)); back != got {
+				t.Errorf("%q: %s does not parse back", tt.in, got)
+			}
+		}
+	}
+}
+
+// specArg turns a Spec label such as "avg(duration)" back into an --agg
+// value.
+func specArg(s Spec) string {
+	if s.Field == "" {
+		return s.String()
+	}
+	label := s.String()
+	return label[:len(label)-len(s.Field)-2] + ":" + s.Field
+}
+
+func TestAggregator(t *testing.T) {
+	entries := []map[string]any{
+		{"host": "a", "ms": int64(10)},
+		{"host": "a", "ms": "30"},
+		{"host": "b", "ms": 5.5},
+		{"host": "b", "ms": "slow"},
+		{"host": "c"},
+		{"ms": int64(1)},
+		{"host": int64(7), "ms": int64(2)},
+	}
+	tests := []struct {
+		spec string
+		want map[string]float64 // Group key → value; NaN for none
+		keys []string           // Expected order
+	}{
+		{"count", map[string]float64{"a": 2, "b": 2, "c": 1, MissingKey: 1, "7": 1}, []string{"a", "b", "(none)", "7", "c"}},
+		{"sum:ms", map[string]float64{"a": 40, "b": 5.5, "c": math.NaN(), MissingKey: 1, "7": 2}, []string{"a", "b", "7", "(none)", "c"}},
+		{"avg:ms", map[string]float64{"a": 20, "b": 5.5, "c": math.NaN(), MissingKey: 1, "7": 2}, []string{"a", "b", "7", "(none)", "c"}},
+		{"min:ms", map[string]float64{"a": 10, "b": 5.5, "c": math.NaN(), MissingKey: 1, "7": 2}, []string{"a", "b", "7", "(none)", "c"}},
+		{"max:ms", map[string]float64{"a": 30, "b": 5.5, "c": math.NaN(), MissingKey: 1, "7": 2}, []string{"a", "b", "7", "(none)", "c"}},
+	}
+	for _, tt := range tests {
+		spec, err := ParseSpec(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := New("host", spec)
+		for _, fields := range entries {
+			a.Add(entry(fields))
+		}
+		var keys []string
+		for _, g := range a.Results() {
+			keys = append(keys, g.Key)
+			want := tt.want[g.Key]
+			if got := g.Value(spec); got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+				t.Errorf("%s: group %s = %v, want %v", tt.spec, g.Key, got, want)
+			}
+		}
+		if !reflect.DeepEqual(keys, tt.keys) {
+			t.Errorf("%s: order %q, want %q", tt.spec, keys, tt.keys)
+		}
+	}
+}
+
+func TestAggregatorMultiValued(t *testing.T) {
+	a := New("_tags", Spec{Func: FuncCount})
+	a.Add(entry(map[string]any{"_tags": []string{"db", "slow"}}))
+	a.Add(entry(map[string]any{"_tags": []string{"db"}}))
+	var buf bytes.Buffer
+	if err := a.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "_tags  count\ndb     2\nslow   1\n"; buf.String() != want {
+		t.Errorf("Render:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{3, "3"},
+		{-2, "-2"},
+		{2.5, "2.500"},
+		{1.0 / 3, "0.333"},
+		{1e16, "10000000000000000.000"},
+		{math.NaN(), "-"},
+	}
+	for _, tt := range tests {
+		if got := FormatValue(tt.v); got != tt.want {
+			t.Errorf("FormatValue(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestTop(t *testing.T) {
+	top := NewTop("ip", 2)
+	for _, ip := range []any{"10.0.0.1", "10.0.0.2", "10.0.0.1", nil, "10.0.0.3", "10.0.0.2", "10.0.0.1"} {
+		fields := map[string]any{}
+		if ip != nil {
+			fields["ip"] = ip
+		}
+		top.Add(entry(fields))
+	}
+	want := []TopValue{
+		{Value: "10.0.0.1", Count: 3, Percent: 100 * 3.0 / 7},
+		{Value: "10.0.0.2", Count: 2, Percent: 100 * 2.0 / 7},
+	}
+	if got := top.Results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Results() = %+v, want %+v", got, want)
+	}
+	if top.Total() != 7 {
+		t.Errorf("Total() = %d, want 7", top.Total())
+	}
+	top.N = 0
+	if got := len(top.Results()); got != 4 {
+		t.Errorf("with N=0: %d rows, want 4 (three values and %s)", got, MissingKey)
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	for _, approx := range []bool{false, true} {
+		d := NewDistinct("user", approx)
+		for _, v := range []any{"ann", "bob", "ann", int64(1), "1", nil} {
+			fields := map[string]any{}
+			if v != nil {
+				fields["user"] = v
+			}
+			d.Add(entry(fields))
+		}
+		// int64(1) and "1" render alike and count once.
+		if got := d.Count(); got != 3 {
+			t.Errorf("approx=%v: Count() = %d, want 3", approx, got)
+		}
+		if d.Approximate() != approx {
+			t.Errorf("Approximate() = %v, want %v", d.Approximate(), approx)
+		}
+	}
+}
+
+func TestDuplicateReport(t *testing.T) {
+	r := NewDuplicateReport("id")
+	adds := []struct {
+		file string
+		id   any
+	}{
+		{"b.log", "x"}, {"a.log", "x"}, {"a.log", "x"},
+		{"a.log", "y"}, {"c.log", "y"},
+		{"a.log", "z"}, {"a.log", "z"},
+		{"c.log", nil},
+	}
+	for _, add := range adds {
+		fields := map[string]any{}
+		if add.id != nil {
+			fields["id"] = add.id
+		}
+		r.Add(add.file, entry(fields))
+	}
+	want := []Duplicate{
+		{Value: "x", Total: 3, Files: []FileCount{{"a.log", 2}, {"b.log", 1}}},
+		{Value: "y", Total: 2, Files: []FileCount{{"a.log", 1}, {"c.log", 1}}},
+	}
+	if got := r.Duplicates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Duplicates() = %+v, want %+v", got, want)
+	}
+	var buf bytes.Buffer
+	if err := r.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id  total  files\nx   3      a.log (2), b.log (1)\ny   2      a.log (1), c.log (1)\n"; buf.String() != want {
+		t.Errorf("Render:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
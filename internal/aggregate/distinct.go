@@ -0,0 +1,55 @@
+package aggregate
+
+import (
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Distinct counts unique values of a field over matched entries, either
+// exactly (memory grows with cardinality) or approximately via HyperLogLog.
+// Entries without the field are ignored.
+type Distinct struct {
+	Field string
+	exact map[string]struct{}
+	hll   *HyperLogLog
+}
+
+// NewDistinct creates a distinct-value counter for field. With approx set
+// it uses a HyperLogLog sketch for very high cardinality fields.
+func NewDistinct(field string, approx bool) *Distinct {
+	d := &Distinct{Field: field}
+	if approx {
+		d.hll = NewHyperLogLog()
+	} else {
+		d.exact = make(map[string]struct{})
+	}
+	return d
+}
+
+// Add records the entry's value for the field.
+func (d *Distinct) Add(entry *parser.LogEntry) {
+	v, ok := entry.Fields[d.Field]
+	if !ok {
+		return
+	}
+	s := filter.ToString(v)
+	if d.hll != nil {
+		d.hll.Add(s)
+		return
+	}
+	d.exact[s] = struct{}{}
+}
+
+// Count returns the number of distinct values seen (estimated when
+// approximate).
+func (d *Distinct) Count() uint64 {
+	if d.hll != nil {
+		return d.hll.Count()
+	}
+	return uint64(len(d.exact))
+}
+
+// Approximate reports whether Count is a HyperLogLog estimate.
+func (d *Distinct) Approximate() bool {
+	return d.hll != nil
+}
@@ -0,0 +1,95 @@
+package aggregate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// FileCount is the number of occurrences of a key in one file.
+type FileCount struct {
+	File  string
+	Count int64
+}
+
+// Duplicate is a key value seen in more than one file.
+type Duplicate struct {
+	Value string
+	Total int64       // Occurrences across all files
+	Files []FileCount // Sorted by file name
+}
+
+// DuplicateReport tracks which files each value of a key field appears in,
+// to verify exactly-once delivery across shipped log files.
+type DuplicateReport struct {
+	Key  string
+	seen map[string]map[string]int64 // value → file → count
+}
+
+// NewDuplicateReport creates a report keyed on the given field.
+func NewDuplicateReport(key string) *DuplicateReport {
+	return &DuplicateReport{
+		Key:  key,
+		seen: make(map[string]map[string]int64),
+	}
+}
+
+// Add records an entry read from file. Entries without the key are ignored.
+func (r *DuplicateReport) Add(file string, entry *parser.LogEntry) {
+	v, ok := entry.Fields[r.Key]
+	if !ok {
+		return
+	}
+	value := filter.ToString(v)
+
+	files, ok := r.seen[value]
+	if !ok {
+		files = make(map[string]int64, 1)
+		r.seen[value] = files
+	}
+	files[file]++
+}
+
+// Duplicates returns the values that appear in more than one file, sorted
+// by total occurrences (descending), then value.
+func (r *DuplicateReport) Duplicates() []Duplicate {
+	var dups []Duplicate
+	for value, files := range r.seen {
+		if len(files) < 2 {
+			continue
+		}
+		d := Duplicate{Value: value}
+		for file, n := range files {
+			d.Files = append(d.Files, FileCount{File: file, Count: n})
+			d.Total += n
+		}
+		sort.Slice(d.Files, func(i, j int) bool { return d.Files[i].File < d.Files[j].File })
+		dups = append(dups, d)
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].Total != dups[j].Total {
+			return dups[i].Total > dups[j].Total
+		}
+		return dups[i].Value < dups[j].Value
+	})
+	return dups
+}
+
+// Render writes the duplicates as an aligned table.
+func (r *DuplicateReport) Render(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\ttotal\tfiles\n", r.Key)
+	for _, d := range r.Duplicates() {
+		parts := make([]string, len(d.Files))
+		for i, f := range d.Files {
+			parts[i] = fmt.Sprintf("%s (%d)", f.File, f.Count)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", d.Value, d.Total, strings.Join(parts, ", "))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,145 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// maxFilledBuckets caps how many empty buckets are inserted between the
+// first and last timestamps; wider ranges list only non-empty buckets.
+const maxFilledBuckets = 10000
+
+// ParseInterval parses a bucket interval such as "1m", "5m", "1h" or "1d".
+func ParseInterval(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("aggregate: invalid interval %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("aggregate: invalid interval %q", s)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("aggregate: interval must be positive, got %q", s)
+	}
+	return d, nil
+}
+
+// Bucket is the number of entries in one time interval.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// TimeHistogram buckets matched entries by timestamp into fixed intervals.
+type TimeHistogram struct {
+	Interval  time.Duration
+	TimeField string // Empty for the entry's Timestamp or detected time field
+
+	buckets map[int64]int64 // Bucket start (unix nanos) → count
+	Skipped int64           // Entries without a parseable timestamp
+}
+
+// NewTimeHistogram creates a histogram with the given bucket interval.
+func NewTimeHistogram(interval time.Duration, timeField string) *TimeHistogram {
+	return &TimeHistogram{
+		Interval:  interval,
+		TimeField: timeField,
+		buckets:   make(map[int64]int64),
+	}
+}
+
+// Add counts the entry in the bucket containing its timestamp. Without a
+// TimeField, the entry's normalized Timestamp is used when set.
+func (h *TimeHistogram) Add(entry *parser.LogEntry) {
+	t := entry.Timestamp
+	if h.TimeField != "" || t.IsZero() {
+		probe := parser.LogEntry{Fields: entry.Fields}
+		if !parser.NormalizeTime(&probe, h.TimeField) {
+			h.Skipped++
+			return
+		}
+		t = probe.Timestamp
+	}
+	h.buckets[t.Truncate(h.Interval).UnixNano()]++
+}
+
+// Buckets returns the buckets in time order, including empty buckets
+// between the first and last so gaps are visible.
+func (h *TimeHistogram) Buckets() []Bucket {
+	if len(h.buckets) == 0 {
+		return nil
+	}
+
+	starts := make([]int64, 0, len(h.buckets))
+	for s := range h.buckets {
+		starts = append(starts, s)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	first, last := starts[0], starts[len(starts)-1]
+	step := int64(h.Interval)
+	if (last-first)/step >= maxFilledBuckets {
+		out := make([]Bucket, len(starts))
+		for i, s := range starts {
+			out[i] = Bucket{Start: time.Unix(0, s).UTC(), Count: h.buckets[s]}
+		}
+		return out
+	}
+
+	var out []Bucket
+	for s := first; s <= last; s += step {
+		out = append(out, Bucket{Start: time.Unix(0, s).UTC(), Count: h.buckets[s]})
+	}
+	return out
+}
+
+// RenderBars writes an ASCII bar chart, scaling the largest bucket to
+// width characters.
+func (h *TimeHistogram) RenderBars(w io.Writer, width int) error {
+	buckets := h.Buckets()
+	var peak int64
+	for _, b := range buckets {
+		peak = max(peak, b.Count)
+	}
+
+	layout := time.RFC3339
+	if h.Interval%time.Minute == 0 {
+		layout = "2006-01-02 15:04"
+	}
+
+	for _, b := range buckets {
+		bar := 0
+		if peak > 0 {
+			bar = int(b.Count * int64(width) / peak)
+		}
+		if bar == 0 && b.Count > 0 {
+			bar = 1
+		}
+		if _, err := fmt.Fprintf(w, "%s  %-*s  %d\n", b.Start.Format(layout), width, strings.Repeat("█", bar), b.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderJSON writes the buckets as a JSON array of {start, count}.
+func (h *TimeHistogram) RenderJSON(w io.Writer) error {
+	buckets := h.Buckets()
+	if buckets == nil {
+		buckets = []Bucket{}
+	}
+	return json.NewEncoder(w).Encode(buckets)
+}
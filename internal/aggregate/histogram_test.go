@@ -0,0 +1,122 @@
+package aggregate
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{"1m", time.Minute, false},
+		{"90s", 90 * time.Second, false},
+		{"1h", time.Hour, false},
+		{"2d", 48 * time.Hour, false},
+		{"0s", 0, true},
+		{"-5m", 0, true},
+		{"xd", 0, true},
+		{"5", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseInterval(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseInterval(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func at(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// TestTimeHistogramEdges checks which bucket entries on and around bucket
+// boundaries fall into, and that gaps are filled with empty buckets.
+func TestTimeHistogramEdges(t *testing.T) {
+	h := NewTimeHistogram(time.Minute, "")
+	for _, ts := range []string{
+		"2024-03-01T10:00:00Z",           // First instant of 10:00
+		"2024-03-01T10:00:59.999999999Z", // Last instant of 10:00
+		"2024-03-01T10:01:00Z",           // First instant of 10:01
+		"2024-03-01T12:03:00+02:00",      // 10:03 UTC
+	} {
+		h.Add(&parser.LogEntry{Timestamp: at(ts)})
+	}
+	h.Add(&parser.LogEntry{Fields: map[string]any{"msg": "no time"}})
+
+	want := []Bucket{
+		{at("2024-03-01T10:00:00Z"), 2},
+		{at("2024-03-01T10:01:00Z"), 1},
+		{at("2024-03-01T10:02:00Z"), 0},
+		{at("2024-03-01T10:03:00Z"), 1},
+	}
+	got := h.Buckets()
+	if len(got) != len(want) {
+		t.Fatalf("Buckets() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || got[i].Count != want[i].Count {
+			t.Errorf("bucket %d = %v %d, want %v %d", i, got[i].Start, got[i].Count, want[i].Start, want[i].Count)
+		}
+	}
+	if h.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", h.Skipped)
+	}
+
+	var buf bytes.Buffer
+	if err := h.RenderBars(&buf, 4); err != nil {
+		t.Fatal(err)
+	}
+	wantBars := "2024-03-01 10:00  ████  2\n" +
+		"2024-03-01 10:01  ██    1\n" +
+		"2024-03-01 10:02        0\n" +
+		"2024-03-01 10:03  ██    1\n"
+	if buf.String() != wantBars {
+		t.Errorf("RenderBars:\n%s\nwant:\n%s", buf.String(), wantBars)
+	}
+}
+
+func TestTimeHistogramField(t *testing.T) {
+	h := NewTimeHistogram(24*time.Hour, "when")
+	h.Add(&parser.LogEntry{Timestamp: at("2024-03-05T00:00:00Z"), Fields: map[string]any{"when": "2024-03-01T23:59:59Z"}})
+	h.Add(&parser.LogEntry{Fields: map[string]any{"when": "2024-03-02T00:00:00Z"}})
+	h.Add(&parser.LogEntry{Fields: map[string]any{"ts": "2024-03-02T00:00:00Z"}})
+	got := h.Buckets()
+	if len(got) != 2 || !got[0].Start.Equal(at("2024-03-01T00:00:00Z")) || got[0].Count != 1 || got[1].Count != 1 {
+		t.Errorf("Buckets() = %v, want one entry on each of March 1 and 2", got)
+	}
+	if h.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", h.Skipped)
+	}
+}
+
+// TestTimeHistogramSparse checks that ranges of maxFilledBuckets buckets
+// or more list only the non-empty ones.
+func TestTimeHistogramSparse(t *testing.T) {
+	for _, span := range []int{maxFilledBuckets - 1, maxFilledBuckets} {
+		h := NewTimeHistogram(time.Second, "")
+		start := at("2024-01-01T00:00:00Z")
+		h.Add(&parser.LogEntry{Timestamp: start})
+		h.Add(&parser.LogEntry{Timestamp: start.Add(time.Duration(span) * time.Second)})
+		want := span + 1
+		if span >= maxFilledBuckets {
+			want = 2
+		}
+		if got := len(h.Buckets()); got != want {
+			t.Errorf("span %d: %d buckets, want %d", span, got, want)
+		}
+	}
+	var buf bytes.Buffer
+	if err := NewTimeHistogram(time.Minute, "").RenderJSON(&buf); err != nil || buf.String() != "[]\n" {
+		t.Errorf("empty RenderJSON = %q, %v; want []", buf.String(), err)
+	}
+}
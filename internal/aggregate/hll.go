@@ -0,0 +1,57 @@
+package aggregate
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+)
+
+// hllPrecision is the number of index bits; 2^14 registers give a standard
+// error of about 0.8% in 16KB.
+const hllPrecision = 14
+
+// HyperLogLog estimates the number of distinct strings in constant memory.
+type HyperLogLog struct {
+	seed      maphash.Seed
+	registers []uint8
+}
+
+// NewHyperLogLog creates an empty HyperLogLog sketch.
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{
+		seed:      maphash.MakeSeed(),
+		registers: make([]uint8, 1<<hllPrecision),
+	}
+}
+
+// Add records a value in the sketch.
+func (h *HyperLogLog) Add(s string) {
+	x := maphash.String(h.seed, s)
+	idx := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// Count returns the estimated number of distinct values added.
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+
+	// Small-range correction: linear counting is more accurate here.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
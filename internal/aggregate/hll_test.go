@@ -0,0 +1,40 @@
+package aggregate
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// TestHyperLogLogAccuracy checks estimates against the sketch's standard
+// error of about 0.8%: within 4% of the true count, and exact enough at
+// small counts, where linear counting applies.
+func TestHyperLogLogAccuracy(t *testing.T) {
+	tests := []struct {
+		n   int
+		tol float64 // Allowed relative error
+	}{
+		{0, 0},
+		{1, 0},
+		{100, 0.02},
+		{1000, 0.02},
+		{10000, 0.04},
+		{40000, 0.04}, // Around the switch from linear counting
+		{200000, 0.04},
+		{1000000, 0.04},
+	}
+	for _, tt := range tests {
+		h := NewHyperLogLog()
+		for i := range tt.n {
+			h.Add("user-" + strconv.Itoa(i))
+		}
+		// Repeats do not count.
+		for i := range min(tt.n, 1000) {
+			h.Add("user-" + strconv.Itoa(i))
+		}
+		got := float64(h.Count())
+		if err := math.Abs(got-float64(tt.n)) / math.Max(float64(tt.n), 1); err > tt.tol {
+			t.Errorf("n=%d: estimate %.0f, error %.2f%% above %.0f%%", tt.n, got, 100*err, 100*tt.tol)
+		}
+	}
+}
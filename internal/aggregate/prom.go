@@ -0,0 +1,91 @@
+package aggregate
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MetricPrefix starts the names of exported Prometheus metrics.
+const MetricPrefix = "flog_"
+
+// MetricName returns the Prometheus metric name for the aggregation, e.g.
+// flog_count or flog_avg_duration_ms. Characters not allowed in metric
+// names become underscores.
+func (a *Aggregator) MetricName() string {
+	return sanitizeName(MetricPrefix+strings.TrimSuffix(a.Spec.String(), ")"), true)
+}
+
+// RenderProm writes the results in the Prometheus text exposition format
+// (--agg-out prom), one gauge sample per group labelled with its key, for
+// node_exporter's textfile collector:
+//
+//	# TYPE flog_count gauge
+//	flog_count{status="500"} 12
+//
+// Write it with output.CreateFile so the collector never reads a partial
+// file.
+func (a *Aggregator) RenderProm(w io.Writer) error {
+	name := a.MetricName()
+	label := sanitizeName(a.GroupBy, false)
+	if _, err := fmt.Fprintf(w, "# HELP %s flog %s grouped by %s.\n# TYPE %s gauge\n", name, escapeHelp(a.Spec.String()), escapeHelp(a.GroupBy), name); err != nil {
+		return err
+	}
+	for _, g := range a.Results() {
+		if _, err := fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", name, label, escapeLabel(g.Key), promValue(g.Value(a.Spec))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sanitizeName replaces characters not allowed in a Prometheus metric name,
+// or label name when metric is false (no colons), with underscores and
+// guards a leading digit. Label names starting with "__" are reserved and
+// get a prefix.
+func sanitizeName(s string, metric bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+		case r == ':' && metric:
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if name == "" || (!metric && strings.HasPrefix(name, "__")) {
+		name = "field" + name
+	}
+	return name
+}
+
+// escapeLabel escapes a label value: backslash, double quote and newline.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// escapeHelp escapes HELP text: backslash and newline.
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// promValue formats a sample value; groups without numeric values are NaN.
+func promValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,96 @@
+package aggregate
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		in     string
+		metric bool
+		want   string
+	}{
+		{"flog_avg(duration.ms", true, "flog_avg_duration_ms"},
+		{"http:status", true, "http:status"},
+		{"http:status", false, "http_status"},
+		{"9lives", false, "_9lives"},
+		{"a9", false, "a9"},
+		{"req-id", false, "req_id"},
+		{"café", false, "caf_"},
+		{"__name__", false, "field__name__"},
+		{"_x", false, "_x"},
+		{"", false, "field"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeName(tt.in, tt.metric); got != tt.want {
+			t.Errorf("sanitizeName(%q, %v) = %q, want %q", tt.in, tt.metric, got, tt.want)
+		}
+	}
+}
+
+func TestPromEscaping(t *testing.T) {
+	tests := []struct {
+		in, label, help string
+	}{
+		{`plain`, `plain`, `plain`},
+		{`say "hi"`, `say \"hi\"`, `say "hi"`},
+		{`C:\logs`, `C:\\logs`, `C:\\logs`},
+		{"two\nlines", `two\nlines`, `two\nlines`},
+		{`\"`, `\\\"`, `\\"`},
+	}
+	for _, tt := range tests {
+		if got := escapeLabel(tt.in); got != tt.label {
+			t.Errorf("escapeLabel(%q) = %q, want %q", tt.in, got, tt.label)
+		}
+		if got := escapeHelp(tt.in); got != tt.help {
+			t.Errorf("escapeHelp(%q) = %q, want %q", tt.in, got, tt.help)
+		}
+	}
+}
+
+func TestPromValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{12, "12"},
+		{0.25, "0.25"},
+		{1e21, "1e+21"},
+		{math.NaN(), "NaN"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+	}
+	for _, tt := range tests {
+		if got := promValue(tt.v); got != tt.want {
+			t.Errorf("promValue(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestRenderProm(t *testing.T) {
+	a := New("req.path", Spec{Func: FuncAvg, Field: "duration.ms"})
+	for _, fields := range []map[string]any{
+		{"req.path": `/a"b`, "duration.ms": int64(3)},
+		{"req.path": `/a"b`, "duration.ms": int64(5)},
+		{"req.path": "/x\n", "duration.ms": 1.5},
+		{"req.path": `C:\`},
+	} {
+		a.Add(&parser.LogEntry{Fields: fields})
+	}
+	var buf bytes.Buffer
+	if err := a.RenderProm(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP flog_avg_duration_ms flog avg(duration.ms) grouped by req.path.\n" +
+		"# TYPE flog_avg_duration_ms gauge\n" +
+		`flog_avg_duration_ms{req_path="/a\"b"} 4` + "\n" +
+		`flog_avg_duration_ms{req_path="/x\n"} 1.5` + "\n" +
+		`flog_avg_duration_ms{req_path="C:\\"} NaN` + "\n"
+	if buf.String() != want {
+		t.Errorf("RenderProm:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
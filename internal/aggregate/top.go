@@ -0,0 +1,76 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// TopValue is one row of a top report.
+type TopValue struct {
+	Value   string  `json:"value"`
+	Count   int64   `json:"count"`
+	Percent float64 `json:"percent"` // Share of matched entries
+}
+
+// Top reports the most frequent values of a field among matched entries
+// (--top N --by field), such as the client IPs behind most 5xx errors.
+// Entries without the field are counted under MissingKey.
+type Top struct {
+	N     int // Rows to report; 0 or less for all values
+	agg   *Aggregator
+	total int64
+}
+
+// NewTop creates a report of the n most frequent values of field.
+func NewTop(field string, n int) *Top {
+	return &Top{N: n, agg: New(field, Spec{Func: FuncCount})}
+}
+
+// Add counts a matched entry.
+func (t *Top) Add(entry *parser.LogEntry) {
+	t.total++
+	t.agg.Add(entry)
+}
+
+// Total returns the number of entries added.
+func (t *Top) Total() int64 {
+	return t.total
+}
+
+// Results returns the top values by count (descending), then by value.
+// Percentages are relative to Total, so multi-valued fields such as _tags
+// may add up to more than 100.
+func (t *Top) Results() []TopValue {
+	groups := t.agg.Results()
+	if t.N > 0 && len(groups) > t.N {
+		groups = groups[:t.N]
+	}
+	out := make([]TopValue, len(groups))
+	for i, g := range groups {
+		out[i] = TopValue{Value: g.Key, Count: g.Count, Percent: 100 * float64(g.Count) / float64(t.total)}
+	}
+	return out
+}
+
+// Render writes the results as an aligned table.
+func (t *Top) Render(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tcount\tpercent\n", t.agg.GroupBy)
+	for _, v := range t.Results() {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", v.Value, v.Count, v.Percent)
+	}
+	return tw.Flush()
+}
+
+// RenderJSON writes the report as {field, total, top: [{value, count, percent}]}.
+func (t *Top) RenderJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(struct {
+		Field string     `json:"field"`
+		Total int64      `json:"total"`
+		Top   []TopValue `json:"top"`
+	}{t.agg.GroupBy, t.total, t.Results()})
+}
@@ -0,0 +1,205 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// DefaultValueBuckets is the bucket count when --value-hist sets none.
+const DefaultValueBuckets = 10
+
+// fineScale is the number of fine bins per factor of e in magnitude. Values
+// are kept in these bins (about 1% wide) rather than individually, so memory
+// stays bounded however many entries are added.
+const fineScale = 100
+
+// ValueBucket is the number of values in [Low, High); the last bucket
+// includes High.
+type ValueBucket struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Count int64   `json:"count"`
+}
+
+// ValueHistogram buckets the values of a numeric field over matched entries
+// (--value-hist), e.g. to see the shape of a latency distribution. Bucket
+// bounds are exact; values are placed to within about 1%.
+type ValueHistogram struct {
+	Field   string
+	Buckets int  // Number of buckets
+	Log     bool // Log-scale buckets; non-positive values are skipped
+
+	pos, neg map[int64]int64 // Fine bin → count, by sign of the value
+	zero     int64
+	min, max float64
+	n        int64
+	Skipped  int64 // Entries without a numeric value (or non-positive in log scale)
+}
+
+// ParseValueHist parses a --value-hist value: a field followed by options,
+// separated by spaces or commas: "duration_ms buckets=20 log" or
+// "duration_ms,buckets=20,scale=log".
+func ParseValueHist(s string) (*ValueHistogram, error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("aggregate: value histogram requires a field")
+	}
+
+	h := NewValueHistogram(parts[0], DefaultValueBuckets, false)
+	for _, opt := range parts[1:] {
+		key, value, _ := strings.Cut(opt, "=")
+		switch {
+		case key == "buckets":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("aggregate: invalid bucket count %q", value)
+			}
+			h.Buckets = n
+		case opt == "log" || opt == "scale=log":
+			h.Log = true
+		case opt == "linear" || opt == "scale=linear":
+			h.Log = false
+		default:
+			return nil, fmt.Errorf("aggregate: unknown value histogram option %q", opt)
+		}
+	}
+	return h, nil
+}
+
+// NewValueHistogram creates a histogram of field with the given number of
+// buckets, log-scaled when logScale is set.
+func NewValueHistogram(field string, buckets int, logScale bool) *ValueHistogram {
+	return &ValueHistogram{
+		Field:   field,
+		Buckets: buckets,
+		Log:     logScale,
+		pos:     make(map[int64]int64),
+		neg:     make(map[int64]int64),
+	}
+}
+
+// Add records the entry's value for the field.
+func (h *ValueHistogram) Add(entry *parser.LogEntry) {
+	f, ok := filter.ToFloat(entry.Fields[h.Field])
+	if !ok || math.IsNaN(f) || math.IsInf(f, 0) || (h.Log && f <= 0) {
+		h.Skipped++
+		return
+	}
+
+	switch {
+	case f > 0:
+		h.pos[fineBin(f)]++
+	case f < 0:
+		h.neg[fineBin(-f)]++
+	default:
+		h.zero++
+	}
+	if h.n == 0 || f < h.min {
+		h.min = f
+	}
+	if h.n == 0 || f > h.max {
+		h.max = f
+	}
+	h.n++
+}
+
+func fineBin(f float64) int64 {
+	return int64(math.Floor(math.Log(f) * fineScale))
+}
+
+// fineValue returns the midpoint of a fine bin.
+func fineValue(bin int64) float64 {
+	return math.Exp((float64(bin) + 0.5) / fineScale)
+}
+
+// Count returns the number of values recorded.
+func (h *ValueHistogram) Count() int64 {
+	return h.n
+}
+
+// Results returns the buckets spanning the smallest to the largest value,
+// in ascending order.
+func (h *ValueHistogram) Results() []ValueBucket {
+	if h.n == 0 {
+		return nil
+	}
+	n := max(h.Buckets, 1)
+	if h.min == h.max {
+		return []ValueBucket{{Low: h.min, High: h.max, Count: h.n}}
+	}
+
+	scale := func(v float64) float64 { return v }
+	unscale := scale
+	if h.Log {
+		scale, unscale = math.Log, math.Exp
+	}
+	lo, hi := scale(h.min), scale(h.max)
+	width := (hi - lo) / float64(n)
+
+	out := make([]ValueBucket, n)
+	for i := range out {
+		out[i].Low = unscale(lo + float64(i)*width)
+		out[i].High = unscale(lo + float64(i+1)*width)
+	}
+	out[0].Low, out[n-1].High = h.min, h.max
+
+	place := func(v float64, count int64) {
+		v = math.Min(math.Max(v, h.min), h.max)
+		i := int((scale(v) - lo) / width)
+		out[min(max(i, 0), n-1)].Count += count
+	}
+	for bin, count := range h.pos {
+		place(fineValue(bin), count)
+	}
+	for bin, count := range h.neg {
+		place(-fineValue(bin), count)
+	}
+	if h.zero > 0 {
+		place(0, h.zero)
+	}
+	return out
+}
+
+// RenderBars writes an ASCII bar chart, scaling the largest bucket to
+// width characters.
+func (h *ValueHistogram) RenderBars(w io.Writer, width int) error {
+	buckets := h.Results()
+	var peak int64
+	labels := make([]string, len(buckets))
+	labelWidth := 0
+	for i, b := range buckets {
+		peak = max(peak, b.Count)
+		labels[i] = FormatValue(b.Low) + ".." + FormatValue(b.High)
+		labelWidth = max(labelWidth, len(labels[i]))
+	}
+
+	for i, b := range buckets {
+		bar := 0
+		if peak > 0 {
+			bar = int(b.Count * int64(width) / peak)
+		}
+		if bar == 0 && b.Count > 0 {
+			bar = 1
+		}
+		if _, err := fmt.Fprintf(w, "%*s  %-*s  %d\n", labelWidth, labels[i], width, strings.Repeat("█", bar), b.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderJSON writes the buckets as a JSON array of {low, high, count}.
+func (h *ValueHistogram) RenderJSON(w io.Writer) error {
+	buckets := h.Results()
+	if buckets == nil {
+		buckets = []ValueBucket{}
+	}
+	return json.NewEncoder(w).Encode(buckets)
+}
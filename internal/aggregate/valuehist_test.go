@@ -0,0 +1,145 @@
+package aggregate
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestParseValueHist(t *testing.T) {
+	tests := []struct {
+		in      string
+		field   string
+		buckets int
+		log     bool
+		err     bool
+	}{
+		{"duration_ms", "duration_ms", DefaultValueBuckets, false, false},
+		{"duration_ms buckets=20 log", "duration_ms", 20, true, false},
+		{"duration_ms,buckets=5,scale=log", "duration_ms", 5, true, false},
+		{"size log linear", "size", DefaultValueBuckets, false, false},
+		{"", "", 0, false, true},
+		{"size buckets=0", "", 0, false, true},
+		{"size buckets=x", "", 0, false, true},
+		{"size scale=sqrt", "", 0, false, true},
+	}
+	for _, tt := range tests {
+		h, err := ParseValueHist(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseValueHist(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if err == nil && (h.Field != tt.field || h.Buckets != tt.buckets || h.Log != tt.log) {
+			t.Errorf("ParseValueHist(%q) = %s buckets=%d log=%v, want %s buckets=%d log=%v",
+				tt.in, h.Field, h.Buckets, h.Log, tt.field, tt.buckets, tt.log)
+		}
+	}
+}
+
+// TestValueHistogramBuckets checks bucket bounds and counts. Values sit
+// away from inner bounds, since they are placed to within about 1%.
+func TestValueHistogramBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets int
+		log     bool
+		values  []any
+		want    []ValueBucket
+		skipped int64
+	}{
+		{
+			name:    "linear",
+			buckets: 4,
+			values:  []any{int64(0), 5.0, "30", int64(60), 99.0, int64(100)},
+			want:    []ValueBucket{{0, 25, 2}, {25, 50, 1}, {50, 75, 1}, {75, 100, 2}},
+		},
+		{
+			name:    "negative and zero",
+			buckets: 2,
+			values:  []any{-50.0, -20.0, int64(0), 20.0, 50.0, "n/a"},
+			want:    []ValueBucket{{-50, 0, 2}, {0, 50, 3}},
+			skipped: 1,
+		},
+		{
+			name:    "log",
+			buckets: 3,
+			log:     true,
+			values:  []any{1.0, 3.0, 30.0, 300.0, 1000.0, 0.0, -5.0},
+			want:    []ValueBucket{{1, 10, 2}, {10, 100, 1}, {100, 1000, 2}},
+			skipped: 2,
+		},
+		{
+			name:    "single value",
+			buckets: 10,
+			values:  []any{7.0, 7.0, nil},
+			want:    []ValueBucket{{7, 7, 2}},
+			skipped: 1,
+		},
+		{
+			name:    "empty",
+			buckets: 10,
+			values:  []any{nil, "x"},
+			skipped: 2,
+		},
+	}
+	for _, tt := range tests {
+		h := NewValueHistogram("v", tt.buckets, tt.log)
+		for _, v := range tt.values {
+			fields := map[string]any{}
+			if v != nil {
+				fields["v"] = v
+			}
+			h.Add(&parser.LogEntry{Fields: fields})
+		}
+		got := h.Results()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Results() = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i, b := range got {
+			w := tt.want[i]
+			if !near(b.Low, w.Low) || !near(b.High, w.High) || b.Count != w.Count {
+				t.Errorf("%s: bucket %d = %v, want %v", tt.name, i, b, w)
+			}
+		}
+		if h.Skipped != tt.skipped {
+			t.Errorf("%s: Skipped = %d, want %d", tt.name, h.Skipped, tt.skipped)
+		}
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestValueHistogramRender(t *testing.T) {
+	h := NewValueHistogram("ms", 2, false)
+	for _, v := range []float64{1, 2, 9, 10} {
+		h.Add(&parser.LogEntry{Fields: map[string]any{"ms": v}})
+	}
+	h.Add(&parser.LogEntry{Fields: map[string]any{"ms": 3.0}})
+
+	var buf bytes.Buffer
+	if err := h.RenderBars(&buf, 6); err != nil {
+		t.Fatal(err)
+	}
+	want := " 1..5.500  ██████  3\n5.500..10  ████    2\n"
+	if buf.String() != want {
+		t.Errorf("RenderBars:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := h.RenderJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"low":1,"high":5.5,"count":3},{"low":5.5,"high":10,"count":2}]` + "\n"; buf.String() != want {
+		t.Errorf("RenderJSON = %s, want %s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := NewValueHistogram("ms", 2, false).RenderJSON(&buf); err != nil || buf.String() != "[]\n" {
+		t.Errorf("empty RenderJSON = %q, %v; want []", buf.String(), err)
+	}
+}
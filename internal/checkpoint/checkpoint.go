@@ -0,0 +1,158 @@
+// Package checkpoint tracks which input files earlier batch runs have fully
+// processed, so incremental runs over rotated archives can skip them.
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// headSize is how much of each file is hashed to recognize it. Hashing the
+// head (not the whole file) keeps checks cheap and stays stable as a file
+// grows by appends.
+const headSize = 64 * 1024
+
+// Status describes a file relative to the recorded state.
+type Status int
+
+const (
+	StatusNew       Status = iota // Never processed
+	StatusUnchanged               // Processed and not modified since
+	StatusGrown                   // Processed, then appended to
+	StatusChanged                 // Replaced or rewritten; process again
+)
+
+// FileState is the recorded fingerprint of a processed file.
+type FileState struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Hash    string    `json:"hash"` // SHA-256 of the first 64KB
+}
+
+// State is the set of processed files, persisted as JSON.
+type State struct {
+	Files map[string]FileState `json:"files"`
+
+	path string
+}
+
+// Load reads the state file at path. A missing file yields an empty state.
+func Load(path string) (*State, error) {
+	s := &State{Files: make(map[string]FileState), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]FileState)
+	}
+	return s, nil
+}
+
+// Check compares the file at path with its recorded state. For
+// StatusGrown, the returned FileState holds the previous size, which is the
+// offset new data starts at.
+func (s *State) Check(path string) (Status, FileState, error) {
+	prev, ok := s.Files[key(path)]
+	if !ok {
+		return StatusNew, FileState{}, nil
+	}
+
+	cur, err := fingerprint(path, prev.Size)
+	if err != nil {
+		return 0, prev, err
+	}
+
+	switch {
+	case cur.Size == prev.Size && cur.ModTime.Equal(prev.ModTime) && cur.Hash == prev.Hash:
+		return StatusUnchanged, prev, nil
+	case cur.Size > prev.Size && cur.Hash == prev.Hash:
+		return StatusGrown, prev, nil
+	}
+	return StatusChanged, prev, nil
+}
+
+// Mark records the current fingerprint of path as fully processed.
+func (s *State) Mark(path string) error {
+	fs, err := fingerprint(path, -1)
+	if err != nil {
+		return err
+	}
+	s.Files[key(path)] = fs
+	return nil
+}
+
+// Save writes the state back atomically (temp file + rename).
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".flog-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// fingerprint stats and hashes path. The hash covers the first 64KB, or
+// only the first limit bytes when limit is smaller, so a grown file can be
+// compared against the head it had when recorded.
+func fingerprint(path string, limit int64) (FileState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileState{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return FileState{}, err
+	}
+
+	n := int64(headSize)
+	if limit >= 0 && limit < n {
+		n = limit
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, n); err != nil && !errors.Is(err, io.EOF) {
+		return FileState{}, err
+	}
+
+	return FileState{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Hash:    hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// key normalizes path so relative and absolute spellings share an entry.
+func key(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/ishk9/flog/internal/filter"
+)
+
+// ExpandAliases rewrites the fields of chain that start with an alias to
+// the aliased path: with "uid: user.id", uid:42 becomes user.id:42 and
+// lower(uid) becomes lower(user.id). Nested fields under an alias
+// (alias.sub, alias[].sub) are rewritten too.
+func (c *Config) ExpandAliases(chain *filter.FilterChain) {
+	if chain == nil || len(c.Aliases) == 0 {
+		return
+	}
+	for i := range chain.Conditions {
+		chain.Conditions[i].Field = c.ExpandField(chain.Conditions[i].Field)
+	}
+	for _, sub := range chain.SubChains {
+		c.ExpandAliases(sub)
+	}
+}
+
+// ExpandField returns field with a leading alias replaced by its target,
+// in each field argument of a function call.
+func (c *Config) ExpandField(field string) string {
+	if strings.HasSuffix(field, ")") {
+		if e, err := filter.ParseExpr(field); err == nil && e.Func != "" {
+			return e.MapFields(c.ExpandField).String()
+		}
+	}
+	head := aliasHead(field)
+	if target, ok := c.Aliases[head]; ok {
+		return target + field[len(head):]
+	}
+	return field
+}
+
+// aliasHead returns the first path segment of field: everything before
+// the first "." or "[".
+func aliasHead(field string) string {
+	if i := strings.IndexAny(field, ".["); i >= 0 {
+		return field[:i]
+	}
+	return field
+}
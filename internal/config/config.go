@@ -0,0 +1,421 @@
+// Package config loads user configuration: named filter presets, a default
+// output format, field aliases and per-environment profiles.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/output"
+	"github.com/ishk9/flog/internal/parser"
+	"github.com/ishk9/flog/internal/workdir"
+)
+
+// DefaultOutput is the output format used when neither flags nor config
+// choose one.
+const DefaultOutput = "raw"
+
+// outputFormats lists the values accepted for --output and output keys.
+var outputFormats = []string{"raw", "pretty", "json", "jsonl-meta", "junit", "csv", "tsv", "elastic-bulk"}
+
+// Config is the contents of a config file:
+//
+//	output: pretty
+//	theme: mine
+//	themes:
+//	  mine:
+//	    base: solarized
+//	    fields: {user: magenta}
+//	work_dir: /scratch/flog
+//	work_dir_quota: 20G
+//	levels: "trace<debug<info<warn|warning<error|err<fatal|critical"
+//	rename:
+//	  lvl: level
+//	redact: [password, user.email]
+//	aliases:
+//	  uid: user.id
+//	presets:
+//	  prod-errors:
+//	    filter: "env:prod,level:error"
+//	    output: json
+//	  slow: "duration_ms>1000"
+//	profiles:
+//	  prod:
+//	    inputs: [/var/log/app/*.log]
+//	    aliases:
+//	      uid: ctx.user_id
+//	    rename:
+//	      severity: level
+//	    redact: [card_number]
+//	    work_dir: /data/flog
+//	    output_file: prod-results.json.gz
+type Config struct {
+	Output     string             `yaml:"output"`         // Default output format
+	OutputFile string             `yaml:"output_file"`    // Default --output-file
+	Inputs     []string           `yaml:"inputs"`         // Inputs read when none are given
+	Aliases    map[string]string  `yaml:"aliases"`        // Alias → field path
+	Presets    map[string]Preset  `yaml:"presets"`        // Named saved filters
+	Profiles   map[string]Profile `yaml:"profiles"`       // Named environments (--profile)
+	WorkDir    string             `yaml:"work_dir"`       // Spill, cache and state directory (--work-dir)
+	WorkQuota  string             `yaml:"work_dir_quota"` // Size limit of WorkDir, such as "20G"
+	Levels     string             `yaml:"levels"`         // Severity order of log levels (--levels)
+	Rename     Renames            `yaml:"rename"`         // Field as logged → common name (--rename)
+	Redact     []string           `yaml:"redact"`         // Fields masked in output (--redact)
+
+	Theme  string                      `yaml:"theme"`  // Default --theme of pretty output
+	Themes map[string]output.ThemeSpec `yaml:"themes"` // Named themes, over the built-in ones
+
+	Path string `yaml:"-"` // File the config was read from, if any
+}
+
+// Preset is a named filter with optional settings of its own. In YAML it
+// is either a mapping or just the filter string.
+type Preset struct {
+	Filter      string `yaml:"filter"`
+	Output      string `yaml:"output"`
+	Description string `yaml:"description"`
+}
+
+// UnmarshalYAML accepts the short form "name: <filter>".
+func (p *Preset) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&p.Filter)
+	}
+	type plain Preset
+	return n.Decode((*plain)(p))
+}
+
+// Renames lists field renamings in the order written, which is the order
+// they apply in (see parser.RenameParser). In YAML it is a mapping from
+// the field as logged to its common name.
+type Renames []parser.Rename
+
+// UnmarshalYAML reads the mapping in document order.
+func (r *Renames) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: rename: want a mapping of logged name to common name", n.Line)
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		var rn parser.Rename
+		if err := n.Content[i].Decode(&rn.From); err != nil {
+			return err
+		}
+		if err := n.Content[i+1].Decode(&rn.To); err != nil {
+			return err
+		}
+		*r = append(*r, rn)
+	}
+	return nil
+}
+
+// Profile bundles the settings of one environment. Its values override the
+// top-level ones when selected with --profile. Its aliases and renames are
+// added to the top-level ones, replacing any of the same name, and its
+// redacted fields are added to the top-level list: a profile can mask
+// more but never less.
+type Profile struct {
+	Output     string            `yaml:"output"`
+	OutputFile string            `yaml:"output_file"`
+	Inputs     []string          `yaml:"inputs"`
+	Aliases    map[string]string `yaml:"aliases"`
+	Rename     Renames           `yaml:"rename"`
+	Redact     []string          `yaml:"redact"`
+	Levels     string            `yaml:"levels"`
+	WorkDir    string            `yaml:"work_dir"`
+	WorkQuota  string            `yaml:"work_dir_quota"`
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/flog/config.yaml, falling back to
+// ~/.config/flog/config.yaml.
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "flog", "config.yaml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "flog", "config.yaml"), nil
+}
+
+// Load reads and validates the config file at path (--config). A missing
+// file is an error.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &Config{Path: path}
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// LoadDefault reads the config at DefaultPath. A missing file yields an
+// empty config, so running without one needs no setup.
+func LoadDefault() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return &Config{}, nil
+	}
+	c, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	return c, err
+}
+
+// Validate checks output formats, the work directory quota, the level
+// order, the renames, the redacted fields, the themes, that every preset
+// filter parses, and that aliases are plain field names pointing at non-alias
+// fields; all of them also with each profile applied.
+func (c *Config) Validate() error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(c.Profiles) {
+		p, err := c.Profile(name)
+		if err != nil {
+			return err
+		}
+		if err := p.check(); err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+
+	for _, name := range sortedKeys(c.Presets) {
+		p := c.Presets[name]
+		if strings.TrimSpace(p.Filter) == "" {
+			return fmt.Errorf("preset %q: empty filter", name)
+		}
+		if _, err := filter.ParseQuery(p.Filter); err != nil {
+			return fmt.Errorf("preset %q: %w", name, err)
+		}
+		if err := checkOutput(p.Output); err != nil {
+			return fmt.Errorf("preset %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// check validates the settings a profile can change.
+func (c *Config) check() error {
+	if err := checkOutput(c.Output); err != nil {
+		return err
+	}
+	if err := checkAliases(c.Aliases); err != nil {
+		return err
+	}
+	if c.WorkQuota != "" {
+		if _, err := workdir.ParseSize(c.WorkQuota); err != nil {
+			return err
+		}
+	}
+	if c.Levels != "" {
+		if _, err := filter.ParseLevels(c.Levels, nil); err != nil {
+			return err
+		}
+	}
+	if err := parser.CheckRenames(c.Rename); err != nil {
+		return err
+	}
+	for _, f := range c.Redact {
+		if !isFieldName(f) {
+			return fmt.Errorf("redact %q: not a field name", f)
+		}
+	}
+	for _, name := range sortedKeys(c.Themes) {
+		if _, err := c.Themes[name].Theme(); err != nil {
+			return fmt.Errorf("theme %q: %w", name, err)
+		}
+	}
+	if c.Theme != "" {
+		if _, err := c.LookupTheme(c.Theme); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LookupTheme returns the theme called name: one defined under themes,
+// else a built-in one (see output.LookupTheme).
+func (c *Config) LookupTheme(name string) (*output.Theme, error) {
+	if spec, ok := c.Themes[name]; ok {
+		return spec.Theme()
+	}
+	return output.LookupTheme(name)
+}
+
+// Profile returns the config with the named profile applied.
+func (c *Config) Profile(name string) (*Config, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("config: unknown profile %q", name)
+	}
+
+	merged := *c
+	merged.Output = firstNonEmpty(p.Output, c.Output)
+	merged.OutputFile = firstNonEmpty(p.OutputFile, c.OutputFile)
+	if len(p.Inputs) > 0 {
+		merged.Inputs = p.Inputs
+	}
+	merged.Levels = firstNonEmpty(p.Levels, c.Levels)
+	merged.WorkDir = firstNonEmpty(p.WorkDir, c.WorkDir)
+	merged.WorkQuota = firstNonEmpty(p.WorkQuota, c.WorkQuota)
+	if len(p.Aliases) > 0 {
+		merged.Aliases = make(map[string]string, len(c.Aliases)+len(p.Aliases))
+		for k, v := range c.Aliases {
+			merged.Aliases[k] = v
+		}
+		for k, v := range p.Aliases {
+			merged.Aliases[k] = v
+		}
+	}
+	if len(p.Rename) > 0 {
+		// Top-level renames keep their place unless the profile
+		// replaces them; the profile's others apply after them.
+		merged.Rename = slices.Clone(c.Rename)
+		for _, r := range p.Rename {
+			if i := slices.IndexFunc(merged.Rename, func(m parser.Rename) bool { return m.From == r.From }); i >= 0 {
+				merged.Rename[i] = r
+			} else {
+				merged.Rename = append(merged.Rename, r)
+			}
+		}
+	}
+	if len(p.Redact) > 0 {
+		merged.Redact = appendNew(slices.Clone(c.Redact), p.Redact...)
+	}
+	return &merged, nil
+}
+
+// appendNew appends the values not already in list.
+func appendNew(list []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func checkAliases(aliases map[string]string) error {
+	for _, name := range sortedKeys(aliases) {
+		target := aliases[name]
+		if !isFieldName(name) || aliasHead(name) != name {
+			return fmt.Errorf("alias %q: not a plain field name", name)
+		}
+		if target == "" {
+			return fmt.Errorf("alias %q: empty target", name)
+		}
+		if _, ok := aliases[aliasHead(target)]; ok {
+			return fmt.Errorf("alias %q: target %q is itself an alias", name, target)
+		}
+	}
+	return nil
+}
+
+func checkOutput(format string) error {
+	if format == "" || slices.Contains(outputFormats, format) {
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (want %s)", format, strings.Join(outputFormats, "|"))
+}
+
+// isFieldName reports whether name parses as a bare field in the query
+// language, so aliases can be written anywhere a field can.
+func isFieldName(name string) bool {
+	chain, err := filter.ParseQuery(name + "?")
+	return err == nil && len(chain.Conditions) == 1 && chain.Conditions[0].Field == name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Settings are the options of a run that flags and config both provide.
+type Settings struct {
+	Filter     string   // Query (-f)
+	Output     string   // Output format (-o)
+	OutputFile string   // Output file (--output-file); empty for stdout
+	Inputs     []string // Input files (arguments)
+	Redact     []string // Fields masked in output (--redact)
+	Theme      string   // Theme of pretty output (--theme)
+}
+
+// Resolve merges command-line flags over the config, where flags holds
+// only values set explicitly on the command line. Scalar settings take
+// the first of: flag, preset, config default; inputs given as arguments
+// replace the configured ones, and fields given to --redact are masked
+// along with the configured ones. Select a profile first with Profile to
+// resolve against it. A preset's filter is
+// combined with -f using AND, so -f narrows a preset rather than
+// discarding it.
+func (c *Config) Resolve(preset string, flags Settings) (Settings, error) {
+	var p Preset
+	if preset != "" {
+		var ok bool
+		if p, ok = c.Presets[preset]; !ok {
+			return Settings{}, fmt.Errorf("config: unknown preset %q", preset)
+		}
+	}
+
+	s := Settings{
+		Filter:     flags.Filter,
+		Output:     firstNonEmpty(flags.Output, p.Output, c.Output, DefaultOutput),
+		OutputFile: firstNonEmpty(flags.OutputFile, c.OutputFile),
+		Inputs:     flags.Inputs,
+		Redact:     appendNew(slices.Clone(c.Redact), flags.Redact...),
+		Theme:      firstNonEmpty(flags.Theme, c.Theme),
+	}
+	if len(s.Inputs) == 0 {
+		s.Inputs = c.Inputs
+	}
+	if p.Filter != "" {
+		if s.Filter == "" {
+			s.Filter = p.Filter
+		} else {
+			s.Filter = "(" + p.Filter + "),(" + s.Filter + ")"
+		}
+	}
+	if err := checkOutput(s.Output); err != nil {
+		return Settings{}, fmt.Errorf("config: %w", err)
+	}
+	if s.Theme != "" {
+		if _, err := c.LookupTheme(s.Theme); err != nil {
+			return Settings{}, fmt.Errorf("config: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
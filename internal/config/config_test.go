@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRenameKeepsDocumentOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("rename:\n  severity: level\n  lvl: level\n  msg: message\n"), 0o644)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Renames{{From: "severity", To: "level"}, {From: "lvl", To: "level"}, {From: "msg", To: "message"}}
+	if !reflect.DeepEqual(c.Rename, want) {
+		t.Errorf("Rename = %v, want %v", c.Rename, want)
+	}
+}
+
+func TestProfileSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+levels: "debug<info<error"
+work_dir: /tmp/flog
+rename:
+  lvl: level
+  msg: message
+redact: [password]
+profiles:
+  prod:
+    levels: "info<warn<error"
+    work_dir: /data/flog
+    rename:
+      msg: text
+      sev: level
+    redact: [card, password]
+`), 0o644)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.Profile("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Levels != "info<warn<error" || p.WorkDir != "/data/flog" {
+		t.Errorf("Levels, WorkDir = %q, %q", p.Levels, p.WorkDir)
+	}
+	wantRename := Renames{{From: "lvl", To: "level"}, {From: "msg", To: "text"}, {From: "sev", To: "level"}}
+	if !reflect.DeepEqual(p.Rename, wantRename) {
+		t.Errorf("Rename = %v, want %v", p.Rename, wantRename)
+	}
+	if want := []string{"password", "card"}; !reflect.DeepEqual(p.Redact, want) {
+		t.Errorf("Redact = %v, want %v", p.Redact, want)
+	}
+	if want := []string{"password"}; !reflect.DeepEqual(c.Redact, want) {
+		t.Errorf("top-level Redact changed to %v", c.Redact)
+	}
+	s, err := p.Resolve("", Settings{Redact: []string{"token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"password", "card", "token"}; !reflect.DeepEqual(s.Redact, want) {
+		t.Errorf("resolved Redact = %v, want %v", s.Redact, want)
+	}
+}
+
+func TestProfileIsValidated(t *testing.T) {
+	for _, profile := range []string{
+		`levels: "info<"`,
+		`work_dir_quota: lots`,
+		`redact: ["a,b"]`,
+		"rename:\n      a: a",
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		os.WriteFile(path, []byte("profiles:\n  prod:\n    "+profile+"\n"), 0o644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load accepted profile %s", profile)
+		}
+	}
+}
+
+func TestThemes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+theme: mine
+themes:
+  mine:
+    base: light
+    levels: {error: "bold white on red"}
+    fields: {user: magenta}
+`), 0o644)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.Resolve("", Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Theme != "mine" {
+		t.Errorf("resolved Theme = %q, want mine", s.Theme)
+	}
+	theme, err := c.LookupTheme("mine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := theme.Levels["error"]; got != "\x1b[1;37;41m" {
+		t.Errorf("error level style = %q", got)
+	}
+	if got := theme.Fields["user"]; got != "\x1b[35m" {
+		t.Errorf("user field style = %q", got)
+	}
+	if got := theme.Tokens["key"]; got != "\x1b[34m" {
+		t.Errorf("key style = %q, want the light base's", got)
+	}
+	if _, err := c.LookupTheme("solarized"); err != nil {
+		t.Errorf("built-in theme: %v", err)
+	}
+
+	for _, bad := range []string{
+		"theme: neon",
+		"themes:\n  mine:\n    base: neon",
+		"themes:\n  mine:\n    tokens: {keys: red}",
+		"themes:\n  mine:\n    fields: {user: mauve}",
+	} {
+		os.WriteFile(path, []byte(bad+"\n"), 0o644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load accepted %s", bad)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// arrayElements returns the values of a flattened array: base[0]rest,
+// base[1]rest, ... until the first missing index. A []string field (such
+// as _tags) is returned element by element.
+func arrayElements(entry *parser.LogEntry, base, rest string) []any {
+	if values, ok := entry.Fields[base].([]string); ok && rest == "" {
+		elems := make([]any, len(values))
+		for i, v := range values {
+			elems[i] = v
+		}
+		return elems
+	}
+
+	var elems []any
+	for i := 0; ; i++ {
+		v, ok := entry.Fields[base+"["+strconv.Itoa(i)+"]"+rest]
+		if !ok {
+			return elems
+		}
+		elems = append(elems, v)
+	}
+}
+
+// length resolves len(field): the element count of an array, or the
+// character count of a scalar string value.
+func length(entry *parser.LogEntry, field string) (int, bool) {
+	if v, ok := entry.Fields[field]; ok {
+		switch t := v.(type) {
+		case []string:
+			return len(t), true
+		case string:
+			return utf8.RuneCountInString(t), true
+		}
+		return 0, false
+	}
+
+	n := 0
+	for {
+		if _, ok := entry.Fields[field+"["+strconv.Itoa(n)+"]"]; !ok {
+			break
+		}
+		n++
+	}
+	if n == 0 && !hasPrefixKey(entry, field+"[") {
+		return 0, false
+	}
+	return n, true
+}
+
+// hasPrefixKey reports whether any field name starts with prefix.
+func hasPrefixKey(entry *parser.LogEntry, prefix string) bool {
+	for k := range entry.Fields {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
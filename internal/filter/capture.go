@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// savedField is the value a field had before a chain was evaluated.
+type savedField struct {
+	name    string
+	value   any
+	present bool
+}
+
+// captureNames returns the named groups of the regex conditions in chain
+// and its sub-chains, MatchedBranchField when one of them is an OR of
+// several branches and MatchedPatternsField when one is a wide OR of
+// substring conditions: the fields its evaluation may set.
+func (m *FieldMatcher) captureNames(chain *FilterChain) []string {
+	if v, ok := m.captures.Load(chain); ok {
+		return v.([]string)
+	}
+	v, _ := m.captures.LoadOrStore(chain, appendCaptureNames(nil, chain))
+	return v.([]string)
+}
+
+func appendCaptureNames(names []string, chain *FilterChain) []string {
+	if chain.Logic == LogicOr && len(chain.Conditions)+len(chain.SubChains) >= 2 && !slices.Contains(names, MatchedBranchField) {
+		names = append(names, MatchedBranchField)
+	}
+	if chain.Logic == LogicOr && countContains(chain) >= minIndexedContains && !slices.Contains(names, MatchedPatternsField) {
+		names = append(names, MatchedPatternsField)
+	}
+	for _, c := range chain.Conditions {
+		if re, ok := c.Value.(*regexp.Regexp); ok && c.Operator == OpRegex {
+			for _, name := range re.SubexpNames() {
+				if name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	for _, sub := range chain.SubChains {
+		names = appendCaptureNames(names, sub)
+	}
+	return names
+}
+
+// countContains returns the number of substring conditions in chain.
+func countContains(chain *FilterChain) int {
+	n := 0
+	for _, c := range chain.Conditions {
+		if c.Operator == OpContains {
+			n++
+		}
+	}
+	return n
+}
+
+// saveFields records the current values of names.
+func saveFields(entry *parser.LogEntry, names []string) []savedField {
+	saved := make([]savedField, len(names))
+	for i, name := range names {
+		v, ok := entry.Fields[name]
+		saved[i] = savedField{name: name, value: v, present: ok}
+	}
+	return saved
+}
+
+// restoreFields undoes the captures made since saveFields.
+func restoreFields(entry *parser.LogEntry, saved []savedField) {
+	for i := len(saved) - 1; i >= 0; i-- {
+		if s := saved[i]; s.present {
+			entry.Fields[s.name] = s.value
+		} else {
+			delete(entry.Fields, s.name)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestRegexCaptures(t *testing.T) {
+	msg := map[string]any{"msg": "user 42 logged in", "level": "info", "tags[0]": "a=1", "tags[1]": "b=2"}
+	tests := []struct {
+		query string
+		match bool
+		want  map[string]any // Captured fields
+	}{
+		{`msg~="user (?P<uid>\d+)"`, true, map[string]any{"uid": "42"}},
+		{`level:info,msg~="user (?P<uid>\d+)"`, true, map[string]any{"uid": "42"}},
+		{`!msg~="user (?P<uid>\d+)"`, false, nil},
+		{`!(msg~="user (?P<uid>\d+)"),level:info`, false, nil},
+		{`!(msg~="user (?P<uid>\d+)",level:debug)`, true, nil},
+		{`level:error,msg~="user (?P<uid>\d+)"`, false, nil},
+		{`level:info|msg~="user (?P<uid>\d+)"`, true, nil},
+		{`msg~="user (?P<uid>\d+)"|level:info`, true, map[string]any{"uid": "42"}},
+		{`(level:debug,msg~="(?P<who>\w+) 42")|msg~="(?P<verb>logged) in"`, true, map[string]any{"verb": "logged"}},
+		// Conditions are tried before groups, as for _matched_branch.
+		{`(level:info,msg~="(?P<who>\w+) 42")|msg~="(?P<verb>logged) in"`, true, map[string]any{"verb": "logged"}},
+		{`(level:info,msg~="(?P<who>\w+) 42")|(level:debug,msg~="(?P<verb>logged) in")`, true, map[string]any{"who": "user"}},
+		{`tags[]~="b=(?P<b>\d)"`, true, map[string]any{"b": "2"}},
+		{`msg~="user (?P<uid>\d+)",uid>40`, true, map[string]any{"uid": "42"}},
+		{`msg~="user (?P<uid>\d+)",uid>50`, false, nil},
+		{`(msg~="user (?P<uid>\d+)",uid>50)|(msg~="(?P<verb>logged) in",level:info)`, true, map[string]any{"verb": "logged"}},
+	}
+	for _, tt := range tests {
+		chain, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		for _, m := range []*FieldMatcher{{}, {Highlight: true}} {
+			e := &parser.LogEntry{Fields: clone(msg)}
+			if got := m.Match(e, chain); got != tt.match {
+				t.Errorf("%s: matched %v, want %v", tt.query, got, tt.match)
+			}
+			got := map[string]any{}
+			for k, v := range e.Fields {
+				if _, ok := msg[k]; !ok && k != MatchedBranchField {
+					got[k] = v
+				}
+			}
+			want := tt.want
+			if want == nil {
+				want = map[string]any{}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: captured %v, want %v", tt.query, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// TagsField is the synthetic field listing the classification rules an
+// entry matched, as a []string.
+const TagsField = "_tags"
+
+// Rule is a named filter used for classification.
+type Rule struct {
+	Name   string `yaml:"name"`
+	Filter string `yaml:"filter"`
+
+	chain *FilterChain
+}
+
+// Classifier tags entries with the names of every rule they match.
+type Classifier struct {
+	rules   []Rule
+	matcher Matcher
+}
+
+// NewClassifier parses each rule's filter. Rule names must be unique.
+func NewClassifier(rules []Rule, m Matcher) (*Classifier, error) {
+	seen := make(map[string]bool, len(rules))
+	compiled := make([]Rule, len(rules))
+	for i, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("classify: rule %d has no name", i+1)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("classify: duplicate rule %q", r.Name)
+		}
+		seen[r.Name] = true
+
+		chain, err := ParseQuery(r.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("classify: rule %q: %w", r.Name, err)
+		}
+		r.chain = chain
+		compiled[i] = r
+	}
+	return &Classifier{rules: compiled, matcher: m}, nil
+}
+
+// LoadClassifier reads rules from a YAML file of the form:
+//
+//	rules:
+//	  - name: auth-failure
+//	    filter: "event:login,result:failure"
+func LoadClassifier(path string, m Matcher) (*Classifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Rules []Rule `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("classify: %s: %w", path, err)
+	}
+	return NewClassifier(file.Rules, m)
+}
+
+// Classify sets TagsField to the names of the rules the entry matches, in
+// rule order, and returns them. Entries matching no rule get no field.
+func (c *Classifier) Classify(entry *parser.LogEntry) []string {
+	var tags []string
+	for i := range c.rules {
+		if c.matcher.Match(entry, c.rules[i].chain) {
+			tags = append(tags, c.rules[i].Name)
+		}
+	}
+	if tags != nil {
+		entry.Fields[TagsField] = tags
+	}
+	return tags
+}
+
+// ClassifyParser wraps a Parser and classifies every entry it parses, so
+// queries can filter on TagsField.
+type ClassifyParser struct {
+	parser.Parser
+	Classifier *Classifier
+}
+
+// NewClassifyParser wraps p to classify entries with c.
+func NewClassifyParser(p parser.Parser, c *Classifier) *ClassifyParser {
+	return &ClassifyParser{Parser: p, Classifier: c}
+}
+
+// Unwrap returns the wrapped parser.
+func (p *ClassifyParser) Unwrap() parser.Parser {
+	return p.Parser
+}
+
+// Rewrap returns a copy of p wrapping inner.
+func (p *ClassifyParser) Rewrap(inner parser.Parser) parser.Parser {
+	c := *p
+	c.Parser = inner
+	return &c
+}
+
+// Parse parses line with the wrapped parser and sets TagsField.
+func (p *ClassifyParser) Parse(line string) (*parser.LogEntry, error) {
+	entry, err := p.Parser.Parse(line)
+	if err != nil {
+		return entry, err
+	}
+	p.Classifier.Classify(entry)
+	return entry, nil
+}
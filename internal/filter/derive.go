@@ -0,0 +1,291 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// arithOps are the operators of ParseArith, applied to numbers.
+var arithOps = map[string]func(a, b float64) (float64, bool){
+	"+": func(a, b float64) (float64, bool) { return a + b, true },
+	"-": func(a, b float64) (float64, bool) { return a - b, true },
+	"*": func(a, b float64) (float64, bool) { return a * b, true },
+	"/": func(a, b float64) (float64, bool) { return a / b, b != 0 },
+}
+
+// evalArith applies an operator to the values of its two operands.
+// Numeric strings count as numbers; + joins operands that are not both
+// numbers as strings.
+func evalArith(entry *parser.LogEntry, op string, fn func(a, b float64) (float64, bool), args []*Expr) (any, bool) {
+	x, ok := args[0].Eval(entry)
+	if !ok || x == nil {
+		return nil, false
+	}
+	y, ok := args[1].Eval(entry)
+	if !ok || y == nil {
+		return nil, false
+	}
+	a, aok := ToFloat(x)
+	b, bok := ToFloat(y)
+	if !aok || !bok {
+		if op == "+" {
+			return ToString(x) + ToString(y), true
+		}
+		return nil, false
+	}
+	v, ok := fn(a, b)
+	if !ok {
+		return nil, false
+	}
+	return v, true
+}
+
+// ParseArith parses an expression that may also combine values with
+// + - * / and parentheses, and index arrays with [n]:
+//
+//	duration*1000
+//	split(addr,":")[0]
+//	(bytes_in + bytes_out) / 1024
+//
+// Field names may contain "-", so subtraction needs a space before the
+// operator (a - b).
+func ParseArith(s string) (*Expr, error) {
+	p := &arithParser{s: s}
+	e, err := p.sum()
+	if err == nil && p.skipSpace() < len(s) {
+		err = fmt.Errorf("unexpected %q", s[p.i:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query: %q: %v", s, err)
+	}
+	return e, nil
+}
+
+// arithParser is a recursive descent parser for ParseArith.
+type arithParser struct {
+	s string
+	i int
+}
+
+func (p *arithParser) skipSpace() int {
+	for p.i < len(p.s) && (p.s[p.i] == ' ' || p.s[p.i] == '\t') {
+		p.i++
+	}
+	return p.i
+}
+
+// sum := product (("+" | "-") product)*
+func (p *arithParser) sum() (*Expr, error) {
+	e, err := p.product()
+	for err == nil && p.skipSpace() < len(p.s) && strings.IndexByte("+-", p.s[p.i]) >= 0 {
+		op := p.s[p.i : p.i+1]
+		p.i++
+		var rhs *Expr
+		if rhs, err = p.product(); err == nil {
+			e = &Expr{Func: op, Args: []*Expr{e, rhs}}
+		}
+	}
+	return e, err
+}
+
+// product := unary (("*" | "/") unary)*
+func (p *arithParser) product() (*Expr, error) {
+	e, err := p.unary()
+	for err == nil && p.skipSpace() < len(p.s) && strings.IndexByte("*/", p.s[p.i]) >= 0 {
+		op := p.s[p.i : p.i+1]
+		p.i++
+		var rhs *Expr
+		if rhs, err = p.unary(); err == nil {
+			e = &Expr{Func: op, Args: []*Expr{e, rhs}}
+		}
+	}
+	return e, err
+}
+
+// unary := "-" unary | operand ("[" integer "]")*
+func (p *arithParser) unary() (*Expr, error) {
+	if p.skipSpace() < len(p.s) && p.s[p.i] == '-' {
+		p.i++
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := e.Value.(float64); ok && e.Literal {
+			return &Expr{Literal: true, Value: -f}, nil
+		}
+		return &Expr{Func: "-", Args: []*Expr{{Literal: true, Value: 0.0}, e}}, nil
+	}
+	e, err := p.operand()
+	for err == nil && p.i < len(p.s) && p.s[p.i] == '[' {
+		end := strings.IndexByte(p.s[p.i:], ']')
+		if end < 0 {
+			return nil, fmt.Errorf("expected ']'")
+		}
+		n, convErr := strconv.Atoi(strings.TrimSpace(p.s[p.i+1 : p.i+end]))
+		if convErr != nil {
+			return nil, fmt.Errorf("invalid index %q", p.s[p.i:p.i+end+1])
+		}
+		p.i += end + 1
+		e = &Expr{Func: "index", Args: []*Expr{e, {Literal: true, Value: float64(n)}}}
+	}
+	return e, err
+}
+
+// number reports whether a number literal starts at the current position.
+func (p *arithParser) number() bool {
+	digit := func(i int) bool { return i < len(p.s) && p.s[i] >= '0' && p.s[i] <= '9' }
+	return digit(p.i) || p.s[p.i] == '.' && digit(p.i+1)
+}
+
+// operand := "(" sum ")" | string | number | function "(" sum ("," sum)* ")" | field
+func (p *arithParser) operand() (*Expr, error) {
+	if p.skipSpace() == len(p.s) {
+		return nil, fmt.Errorf("expected a value")
+	}
+	rest := p.s[p.i:]
+	switch {
+	case rest[0] == '(':
+		p.i++
+		e, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpace() == len(p.s) || p.s[p.i] != ')' {
+			return nil, fmt.Errorf("expected ')'")
+		}
+		p.i++
+		return e, nil
+	case rest[0] == '"':
+		q, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, fmt.Errorf("unterminated string %s", rest)
+		}
+		p.i += len(q)
+		v, _ := strconv.Unquote(q)
+		return &Expr{Literal: true, Value: v}, nil
+	case p.number():
+		end := strings.IndexFunc(rest, func(r rune) bool {
+			return !strings.ContainsRune("0123456789.eE", r)
+		})
+		if end < 0 {
+			end = len(rest)
+		}
+		f, err := strconv.ParseFloat(rest[:end], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", rest[:end])
+		}
+		p.i += end
+		return &Expr{Literal: true, Value: f}, nil
+	}
+
+	end := strings.IndexAny(rest, " \t()+*/,\"")
+	if end < 0 {
+		end = len(rest)
+	}
+	name := rest[:end]
+	if name == "" {
+		return nil, fmt.Errorf("expected a value at %q", rest)
+	}
+	p.i += end
+	if p.i == len(p.s) || p.s[p.i] != '(' {
+		return &Expr{Field: name}, nil
+	}
+	fn, ok := exprFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	e := &Expr{Func: name}
+	p.i++
+	for {
+		arg, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		e.Args = append(e.Args, arg)
+		if p.skipSpace() == len(p.s) {
+			return nil, fmt.Errorf("expected ')' after %s(", name)
+		}
+		c := p.s[p.i]
+		p.i++
+		if c == ')' {
+			break
+		}
+		if c != ',' {
+			return nil, fmt.Errorf("expected ',' or ')' in %s(", name)
+		}
+	}
+	if len(e.Args) < fn.minArgs || (fn.maxArgs >= 0 && len(e.Args) > fn.maxArgs) {
+		return nil, fmt.Errorf("%s() takes %s", name, argCount(fn))
+	}
+	return e, nil
+}
+
+// Derivation computes a field for every entry (--derive name=expr), before
+// matching and output, so that values that are not literally in the log
+// can be filtered on and printed.
+type Derivation struct {
+	Field string
+	Expr  *Expr
+}
+
+// ParseDerivation parses a --derive value such as "latency_ms=duration*1000"
+// or `host=split(addr,":")[0]` (see ParseArith).
+func ParseDerivation(spec string) (*Derivation, error) {
+	name, expr, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("query: derive %q: want name=expression", spec)
+	}
+	e, err := ParseArith(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Derivation{Field: name, Expr: e}, nil
+}
+
+// Apply sets the derived field on entry. The field is left unset when the
+// expression has no value, as when a field it reads is missing.
+func (d *Derivation) Apply(entry *parser.LogEntry) {
+	if v, ok := d.Expr.Eval(entry); ok {
+		parser.Flatten(d.Field, v, entry.Fields)
+	}
+}
+
+// DeriveParser wraps a Parser and applies derivations to every entry, in
+// order, so later ones can read the fields of earlier ones.
+type DeriveParser struct {
+	parser.Parser
+	Derivations []*Derivation
+}
+
+// NewDeriveParser wraps p to apply ds.
+func NewDeriveParser(p parser.Parser, ds []*Derivation) *DeriveParser {
+	return &DeriveParser{Parser: p, Derivations: ds}
+}
+
+// Unwrap returns the wrapped parser.
+func (p *DeriveParser) Unwrap() parser.Parser {
+	return p.Parser
+}
+
+// Rewrap returns a copy of p wrapping inner.
+func (p *DeriveParser) Rewrap(inner parser.Parser) parser.Parser {
+	c := *p
+	c.Parser = inner
+	return &c
+}
+
+// Parse parses line with the wrapped parser and adds the derived fields.
+func (p *DeriveParser) Parse(line string) (*parser.LogEntry, error) {
+	entry, err := p.Parser.Parse(line)
+	if err != nil {
+		return entry, err
+	}
+	for _, d := range p.Derivations {
+		d.Apply(entry)
+	}
+	return entry, nil
+}
@@ -0,0 +1,85 @@
+package filter
+
+import (
+	"hash/maphash"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// minIndexedEq is the number of equality conditions on one field in an OR
+// chain from which they are answered by a hash lookup instead of one
+// comparison each.
+const minIndexedEq = 4
+
+// eqSet holds the hashed targets of the equality conditions on one field.
+// Targets are keyed the way FieldMatcher.equal compares them: numeric
+// values by canonical number text, everything else by string.
+type eqSet struct {
+	field   string
+	seed    maphash.Seed
+	targets map[uint64][]eqTarget
+}
+
+type eqTarget struct {
+	key  string
+	cond int // Index into chain.Conditions
+}
+
+func newEqSet(chain *FilterChain, field string, conds []int) eqSet {
+	set := eqSet{field: field, seed: maphash.MakeSeed(), targets: make(map[uint64][]eqTarget)}
+	for _, i := range conds {
+		key, _ := eqKey(chain.Conditions[i].Value)
+		h := maphash.String(set.seed, key)
+		set.targets[h] = append(set.targets[h], eqTarget{key: key, cond: i})
+	}
+	return set
+}
+
+// lookup returns the lowest index of a condition in the set the entry
+// satisfies, or -1.
+func (set *eqSet) lookup(entry *parser.LogEntry) int {
+	v, ok := lookup(entry, set.field)
+	if !ok {
+		return -1
+	}
+	key, ok := eqKey(v)
+	if !ok {
+		return -1
+	}
+	best := -1
+	for _, t := range set.targets[maphash.String(set.seed, key)] {
+		if t.key == key && (best < 0 || t.cond < best) {
+			best = t.cond
+		}
+	}
+	return best
+}
+
+// eqKey normalizes a value for hashed equality. NaN never compares equal
+// and time values need parsing, so neither is indexable.
+func eqKey(v any) (string, bool) {
+	if _, ok := v.(time.Time); ok {
+		return "", false
+	}
+	if f, ok := ToFloat(v); ok {
+		if math.IsNaN(f) {
+			return "", false
+		}
+		if f == 0 {
+			f = 0 // fold -0
+		}
+		return "n:" + strconv.FormatFloat(f, 'g', -1, 64), true
+	}
+	return "s:" + ToString(v), true
+}
+
+// indexableField reports whether field has a single value per entry that
+// compares by key, as opposed to an array path matched element by element
+// or TimestampField, which compares as a time.
+func indexableField(field string) bool {
+	return field != TimestampField && !strings.Contains(field, "[]")
+}
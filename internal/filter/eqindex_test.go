@@ -0,0 +1,119 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// TestEqIndexMatchesScan checks that answering a wide equality OR from
+// the hash index gives the result and branch of comparing each condition
+// in turn.
+func TestEqIndexMatchesScan(t *testing.T) {
+	queries := []string{
+		`v:200|v:true|v:abc|v:1.5|v:0|v:"2e2"|v:null`,
+		`v:"200"|v:false|v:ABC|v:-1|v:1e3|v:""`,
+		`v:x|v:200|v:y|v:200.0|w:1|v:z`,
+		`v:a|v:b|v:c|v:d|msg*="err"|v:true`,
+	}
+	values := []any{
+		int64(200), 200.0, "200", "200.0", "2e2", 200.5,
+		true, false, "true", "TRUE", "false",
+		"abc", "ABC", "", "null", nil,
+		1.5, "1.50", int64(0), -0.0, "-0", int64(-1), int64(1000), "1e3",
+		"x", "z", "d",
+	}
+	for _, q := range queries {
+		chain, err := ParseQuery(q)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", q, err)
+		}
+		indexed, scanned := &FieldMatcher{}, &FieldMatcher{noIndex: true}
+		if indexed.index(chain) == nil {
+			t.Fatalf("%s: not indexed", q)
+		}
+		entries := []map[string]any{{}, {"w": int64(1)}, {"msg": "an error"}}
+		for _, v := range values {
+			entries = append(entries, map[string]any{"v": v}, map[string]any{"v": v, "msg": "err"})
+		}
+		for _, fields := range entries {
+			a := &parser.LogEntry{Fields: clone(fields)}
+			b := &parser.LogEntry{Fields: clone(fields)}
+			if x, y := indexed.Match(a, chain), scanned.Match(b, chain); x != y {
+				t.Errorf("%s on %#v: indexed %v, scanned %v", q, fields, x, y)
+			}
+			if x, y := a.Fields[MatchedBranchField], b.Fields[MatchedBranchField]; x != y {
+				t.Errorf("%s on %#v: indexed branch %v, scanned %v", q, fields, x, y)
+			}
+		}
+	}
+}
+
+// TestMatchedPatternsDiscarded checks that the substrings found by a
+// pattern set inside a failed or negated chain are not reported.
+func TestMatchedPatternsDiscarded(t *testing.T) {
+	set := `(msg*=disk|msg*=full|msg*=oom|msg*=panic)`
+	tests := []struct {
+		query string
+		match bool
+		want  any // Expected _matched_patterns; nil for none
+	}{
+		{set, true, []string{"disk", "full"}},
+		{set + `,level:info`, false, nil},
+		{`!` + set, false, nil},
+		{`!` + set + `|level:error`, true, nil},
+		{`(` + set + `,level:info)|level:error`, true, nil},
+	}
+	for _, tt := range tests {
+		chain, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		e := &parser.LogEntry{Fields: map[string]any{"msg": "disk full", "level": "error"}}
+		if got := NewMatcher().Match(e, chain); got != tt.match {
+			t.Errorf("%s: matched %v, want %v", tt.query, got, tt.match)
+		}
+		if got := e.Fields[MatchedPatternsField]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %s = %v, want %v", tt.query, MatchedPatternsField, got, tt.want)
+		}
+	}
+}
+
+func clone(fields map[string]any) map[string]any {
+	c := make(map[string]any, len(fields))
+	for k, v := range fields {
+		c[k] = v
+	}
+	return c
+}
+
+func BenchmarkMatch(b *testing.B) {
+	var ids []string
+	for i := range 500 {
+		ids = append(ids, fmt.Sprintf("user.id:u%d", i))
+	}
+	chain, err := ParseQuery(strings.Join(ids, "|"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	entries := make([]*parser.LogEntry, 1000)
+	for i := range entries {
+		entries[i] = &parser.LogEntry{Fields: map[string]any{"user.id": fmt.Sprintf("u%d", i*7)}}
+	}
+	for _, bm := range []struct {
+		name string
+		m    *FieldMatcher
+	}{
+		{"index", &FieldMatcher{}},
+		{"scan", &FieldMatcher{noIndex: true}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bm.m.Match(entries[i%len(entries)], chain)
+			}
+		})
+	}
+}
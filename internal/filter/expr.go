@@ -0,0 +1,308 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Expr is a field reference or a function applied to expressions, used in
+// place of a plain field: len(message), lower(level), abs(delta),
+// coalesce(status,code), index(split(addr,":"),0). Function arguments may
+// also be number or quoted string literals.
+type Expr struct {
+	Field   string  // Field path, when Func is empty and Literal unset
+	Func    string  // Function name, or an arithmetic operator (see ParseArith)
+	Args    []*Expr // Function arguments
+	Literal bool    // The expression is the constant Value
+	Value   any     // float64 or string
+}
+
+// exprFunc describes a function callable in field position.
+type exprFunc struct {
+	minArgs, maxArgs int // maxArgs < 0 for variadic
+	eval             func(entry *parser.LogEntry, args []*Expr) (any, bool)
+}
+
+// exprFuncs lists the functions of the query language.
+var exprFuncs map[string]exprFunc
+
+func init() {
+	exprFuncs = map[string]exprFunc{
+		"len":      {1, 1, evalLen},
+		"lower":    {1, 1, stringFunc(strings.ToLower)},
+		"upper":    {1, 1, stringFunc(strings.ToUpper)},
+		"abs":      {1, 1, evalAbs},
+		"coalesce": {1, -1, evalCoalesce},
+		"split":    {2, 2, evalSplit},
+		"index":    {2, 2, evalIndex},
+	}
+}
+
+// IsFunc reports whether name is a function of the query language.
+func IsFunc(name string) bool {
+	_, ok := exprFuncs[name]
+	return ok
+}
+
+// ParseExpr parses a field expression. Array paths (tags[]) are not
+// allowed as function arguments.
+func ParseExpr(s string) (*Expr, error) {
+	e, rest, err := parseExpr(s)
+	if err != nil {
+		return nil, fmt.Errorf("query: %v", err)
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("query: unexpected %q in %q", rest, s)
+	}
+	return e, nil
+}
+
+func parseExpr(s string) (*Expr, string, error) {
+	if t := strings.TrimLeft(s, " \t"); strings.HasPrefix(t, `"`) {
+		q, err := strconv.QuotedPrefix(t)
+		if err != nil {
+			return nil, "", fmt.Errorf("unterminated string in %q", t)
+		}
+		v, _ := strconv.Unquote(q)
+		return &Expr{Literal: true, Value: v}, t[len(q):], nil
+	}
+	end := strings.IndexAny(s, "(),")
+	if end < 0 {
+		end = len(s)
+	}
+	name := strings.TrimSpace(s[:end])
+	if name == "" {
+		return nil, "", errors.New("expected field name")
+	}
+	if end == len(s) || s[end] != '(' {
+		if f, ok := ToFloat(name); ok {
+			return &Expr{Literal: true, Value: f}, s[end:], nil
+		}
+		return &Expr{Field: name}, s[end:], nil
+	}
+
+	fn, ok := exprFuncs[name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown function %q", name)
+	}
+	e := &Expr{Func: name}
+	rest := s[end+1:]
+	for {
+		arg, r, err := parseExpr(rest)
+		if err != nil {
+			return nil, "", err
+		}
+		if arg.Func == "" && strings.Contains(arg.Field, "[]") {
+			return nil, "", fmt.Errorf("%s() does not take array path %q", name, arg.Field)
+		}
+		e.Args = append(e.Args, arg)
+
+		r = strings.TrimLeft(r, " \t")
+		if r == "" {
+			return nil, "", fmt.Errorf("expected ')' after %s(", name)
+		}
+		rest = r[1:]
+		if r[0] == ')' {
+			break
+		}
+	}
+	if len(e.Args) < fn.minArgs || (fn.maxArgs >= 0 && len(e.Args) > fn.maxArgs) {
+		return nil, "", fmt.Errorf("%s() takes %s", name, argCount(fn))
+	}
+	return e, rest, nil
+}
+
+func argCount(fn exprFunc) string {
+	switch {
+	case fn.maxArgs < 0:
+		return fmt.Sprintf("at least %d arguments", fn.minArgs)
+	case fn.minArgs == 1 && fn.maxArgs == 1:
+		return "one argument"
+	}
+	return fmt.Sprintf("%d to %d arguments", fn.minArgs, fn.maxArgs)
+}
+
+// String renders the expression in query syntax.
+func (e *Expr) String() string {
+	switch {
+	case e.Literal:
+		if s, ok := e.Value.(string); ok {
+			return strconv.Quote(s)
+		}
+		return ToString(e.Value)
+	case e.Func == "":
+		return e.Field
+	case arithOps[e.Func] != nil:
+		return "(" + e.Args[0].String() + " " + e.Func + " " + e.Args[1].String() + ")"
+	}
+	args := make([]string, len(e.Args))
+	for i, a := range e.Args {
+		args[i] = a.String()
+	}
+	return e.Func + "(" + strings.Join(args, ",") + ")"
+}
+
+// MapFields returns a copy of the expression with every field path
+// replaced by fn(path).
+func (e *Expr) MapFields(fn func(string) string) *Expr {
+	if e.Literal {
+		return &Expr{Literal: true, Value: e.Value}
+	}
+	if e.Func == "" {
+		return &Expr{Field: fn(e.Field)}
+	}
+	c := &Expr{Func: e.Func, Args: make([]*Expr, len(e.Args))}
+	for i, a := range e.Args {
+		c.Args[i] = a.MapFields(fn)
+	}
+	return c
+}
+
+// Eval computes the expression for entry. The boolean is false when a
+// field is missing or a function does not apply to its argument.
+func (e *Expr) Eval(entry *parser.LogEntry) (any, bool) {
+	switch {
+	case e.Literal:
+		return e.Value, true
+	case e.Func == "":
+		return lookup(entry, e.Field)
+	}
+	if op := arithOps[e.Func]; op != nil {
+		return evalArith(entry, e.Func, op, e.Args)
+	}
+	return exprFuncs[e.Func].eval(entry, e.Args)
+}
+
+// evalLen is the element count of an array field, or the character count
+// of a string.
+func evalLen(entry *parser.LogEntry, args []*Expr) (any, bool) {
+	if a := args[0]; a.Func == "" {
+		n, ok := length(entry, a.Field)
+		return int64(n), ok
+	}
+	v, ok := args[0].Eval(entry)
+	if !ok {
+		return nil, false
+	}
+	if s, ok := v.(string); ok {
+		return int64(utf8.RuneCountInString(s)), true
+	}
+	return nil, false
+}
+
+func stringFunc(fn func(string) string) func(*parser.LogEntry, []*Expr) (any, bool) {
+	return func(entry *parser.LogEntry, args []*Expr) (any, bool) {
+		v, ok := args[0].Eval(entry)
+		if !ok || v == nil {
+			return nil, false
+		}
+		return fn(ToString(v)), true
+	}
+}
+
+func evalAbs(entry *parser.LogEntry, args []*Expr) (any, bool) {
+	v, ok := args[0].Eval(entry)
+	if !ok {
+		return nil, false
+	}
+	f, ok := ToFloat(v)
+	if !ok {
+		return nil, false
+	}
+	return math.Abs(f), true
+}
+
+// evalCoalesce returns the first argument that is present and not null.
+func evalCoalesce(entry *parser.LogEntry, args []*Expr) (any, bool) {
+	for _, a := range args {
+		if v, ok := a.Eval(entry); ok && v != nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// evalSplit splits a string at a separator, into an array of strings.
+func evalSplit(entry *parser.LogEntry, args []*Expr) (any, bool) {
+	v, ok := args[0].Eval(entry)
+	if !ok || v == nil {
+		return nil, false
+	}
+	sep, ok := args[1].Eval(entry)
+	if !ok {
+		return nil, false
+	}
+	parts := strings.Split(ToString(v), ToString(sep))
+	out := make([]any, len(parts))
+	for i, p := range parts {
+		out[i] = p
+	}
+	return out, true
+}
+
+// evalIndex returns an element of an array, counting from the end when
+// the index is negative. An array field is read from its flattened
+// elements (tags[0], tags[1], ...).
+func evalIndex(entry *parser.LogEntry, args []*Expr) (any, bool) {
+	iv, ok := args[1].Eval(entry)
+	if !ok {
+		return nil, false
+	}
+	f, ok := ToFloat(iv)
+	if !ok || f != math.Trunc(f) {
+		return nil, false
+	}
+	i := int(f)
+	if a := args[0]; a.Func == "" && !a.Literal {
+		if _, ok := entry.Fields[a.Field].([]any); !ok {
+			n, ok := length(entry, a.Field)
+			if i < 0 {
+				i += n
+			}
+			if !ok || i < 0 || i >= n {
+				return nil, false
+			}
+			return lookup(entry, a.Field+"["+strconv.Itoa(i)+"]")
+		}
+	}
+	v, ok := args[0].Eval(entry)
+	elems, isArray := v.([]any)
+	if !ok || !isArray {
+		return nil, false
+	}
+	if i < 0 {
+		i += len(elems)
+	}
+	if i < 0 || i >= len(elems) {
+		return nil, false
+	}
+	return elems[i], true
+}
+
+// exprCache maps condition field text to its compiled *Expr, or to nil
+// when the text is a plain field.
+var exprCache sync.Map
+
+// compiledExpr returns the expression for a condition field, or nil when
+// field is not a function call.
+func compiledExpr(field string) *Expr {
+	if !strings.HasSuffix(field, ")") {
+		return nil
+	}
+	if v, ok := exprCache.Load(field); ok {
+		return v.(*Expr)
+	}
+	e, err := ParseExpr(field)
+	if err != nil || e.Func == "" {
+		e = nil
+	}
+	exprCache.Store(field, e)
+	return e
+}
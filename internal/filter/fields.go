@@ -0,0 +1,40 @@
+package filter
+
+import "strings"
+
+// Fields returns the entry fields the chain's conditions read, with array
+// paths (tags[].name) reduced to their base. ok is false when a condition
+// reads fields that cannot be named up front, as _timestamp does when it
+// falls back to the detected time field.
+func (c *FilterChain) Fields() (fields []string, ok bool) {
+	seen := make(map[string]bool)
+	add := func(field string) {
+		field, _, _ = strings.Cut(field, "[]")
+		if field != RawField && !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	ok = true
+	var walk func(c *FilterChain)
+	walk = func(c *FilterChain) {
+		for _, cond := range c.Conditions {
+			e := compiledExpr(cond.Field)
+			if e == nil {
+				e = &Expr{Field: cond.Field}
+			}
+			e.MapFields(func(field string) string {
+				if field == TimestampField {
+					ok = false
+				}
+				add(field)
+				return field
+			})
+		}
+		for _, sub := range c.SubChains {
+			walk(sub)
+		}
+	}
+	walk(c)
+	return fields, ok
+}
@@ -16,6 +16,9 @@ const (
 	OpRegex                    // Regex match: field~=pattern
 	OpContains                 // Contains substring: field*=substring
 	OpExists                   // Field exists: field?
+	OpIn                       // Set membership: field in (a,b) or field:[a,b]
+	OpNotIn                    // Set exclusion: field not in (a,b) or field!=[a,b]
+	OpRange                    // Inclusive range: field><low..high or field:low..high
 )
 
 // Logic represents how conditions are combined.
@@ -30,7 +33,8 @@ const (
 type Condition struct {
 	Field    string   // Field path (e.g., "user.id", "level")
 	Operator Operator // Comparison operator
-	Value    any      // Target value to match against
+	Value    any      // Target value ([]any for OpIn, OpNotIn; [low, high] for OpRange)
+	Name     string   // Optional branch label: `level:"error" as err`
 }
 
 // FilterChain represents a combination of conditions with logic.
@@ -38,6 +42,8 @@ type FilterChain struct {
 	Conditions []Condition
 	Logic      Logic
 	SubChains  []*FilterChain // For nested AND/OR grouping
+	Negate     bool           // Invert the chain result: !(...)
+	Name       string         // Optional branch label: "(...) as name"
 }
 
 // Matcher evaluates filter conditions against log entries.
@@ -45,4 +51,3 @@ type Matcher interface {
 	// Match checks if a log entry satisfies the filter chain.
 	Match(entry *parser.LogEntry, chain *FilterChain) bool
 }
-
@@ -0,0 +1,88 @@
+package filter
+
+import "github.com/ishk9/flog/internal/parser"
+
+// chainIndex answers the wide parts of an OR chain in bulk: equality
+// conditions by hash lookup and substring conditions by one automaton pass
+// per field. Conditions it does not cover are evaluated one by one.
+type chainIndex struct {
+	eq       []eqSet
+	contains []containsSet
+	scan     []int // Conditions not covered by a set, in chain order
+}
+
+// buildChainIndex returns an index for chain, or nil when no field has
+// enough equality or substring conditions to benefit.
+func buildChainIndex(chain *FilterChain) *chainIndex {
+	if chain.Logic != LogicOr {
+		return nil
+	}
+
+	eqs := make(map[string][]int)
+	subs := make(map[string][]int)
+	var fields []string // First-seen order, so builds are deterministic
+	for i, c := range chain.Conditions {
+		if !indexableField(c.Field) {
+			continue
+		}
+		switch c.Operator {
+		case OpEq:
+			if _, ok := eqKey(c.Value); ok {
+				if len(eqs[c.Field]) == 0 && len(subs[c.Field]) == 0 {
+					fields = append(fields, c.Field)
+				}
+				eqs[c.Field] = append(eqs[c.Field], i)
+			}
+		case OpContains:
+			if ToString(c.Value) != "" {
+				if len(eqs[c.Field]) == 0 && len(subs[c.Field]) == 0 {
+					fields = append(fields, c.Field)
+				}
+				subs[c.Field] = append(subs[c.Field], i)
+			}
+		}
+	}
+
+	idx := &chainIndex{}
+	covered := make([]bool, len(chain.Conditions))
+	for _, field := range fields {
+		if conds := eqs[field]; len(conds) >= minIndexedEq {
+			idx.eq = append(idx.eq, newEqSet(chain, field, conds))
+			for _, i := range conds {
+				covered[i] = true
+			}
+		}
+		if conds := subs[field]; len(conds) >= minIndexedContains {
+			idx.contains = append(idx.contains, newContainsSet(chain, field, conds))
+			for _, i := range conds {
+				covered[i] = true
+			}
+		}
+	}
+	if len(idx.eq) == 0 && len(idx.contains) == 0 {
+		return nil
+	}
+	for i, c := range covered {
+		if !c {
+			idx.scan = append(idx.scan, i)
+		}
+	}
+	return idx
+}
+
+// lookup returns the lowest index of an indexed condition the entry
+// satisfies, or -1.
+func (idx *chainIndex) lookup(entry *parser.LogEntry) int {
+	best := -1
+	for i := range idx.eq {
+		if hit := idx.eq[i].lookup(entry); hit >= 0 && (best < 0 || hit < best) {
+			best = hit
+		}
+	}
+	for i := range idx.contains {
+		if hit := idx.contains[i].lookup(entry); hit >= 0 && (best < 0 || hit < best) {
+			best = hit
+		}
+	}
+	return best
+}
@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultLevelOrder is the severity order of DefaultLevels, least severe
+// first, with synonyms separated by "|".
+const DefaultLevelOrder = "trace<debug|dbg<info|information|informational|notice<warn|warning<error|err<fatal|critical|crit|panic|alert|emerg|emergency"
+
+// DefaultLevelFields are the fields DefaultLevels orders by severity.
+var DefaultLevelFields = []string{"level", "lvl", "severity", "loglevel", "log.level"}
+
+// DefaultLevels is the severity order a FieldMatcher uses unless its
+// Levels says otherwise.
+var DefaultLevels = mustLevels(DefaultLevelOrder, DefaultLevelFields)
+
+// Levels orders log levels by severity, so that on level fields ordering
+// conditions compare severities instead of strings: level>=warn matches
+// warn, WARNING, error and fatal, and level><debug..info matches debug
+// and info. Names are case-insensitive. Entries whose level is not a
+// known one do not match such conditions; conditions whose value is not a
+// known level, such as level>=3, compare as usual.
+type Levels struct {
+	Fields []string       // Fields holding a level
+	rank   map[string]int // Lower-cased name → severity
+}
+
+// ParseLevels parses a level order (--levels) such as
+// "trace<debug<info<warn|warning<error<fatal": levels from least to most
+// severe, separated by "<", each with its synonyms separated by "|".
+// fields are the fields holding a level (default DefaultLevelFields).
+func ParseLevels(order string, fields []string) (*Levels, error) {
+	if len(fields) == 0 {
+		fields = DefaultLevelFields
+	}
+	l := &Levels{Fields: fields, rank: make(map[string]int)}
+	for i, level := range strings.Split(order, "<") {
+		for _, name := range strings.Split(level, "|") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				return nil, fmt.Errorf("query: levels %q: empty level name", order)
+			}
+			if _, dup := l.rank[name]; dup {
+				return nil, fmt.Errorf("query: levels %q: %q is listed twice", order, name)
+			}
+			l.rank[name] = i
+		}
+	}
+	return l, nil
+}
+
+func mustLevels(order string, fields []string) *Levels {
+	l, err := ParseLevels(order, fields)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Rank returns the severity of the level v, if it is one.
+func (l *Levels) Rank(v any) (int, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	r, ok := l.rank[strings.ToLower(s)]
+	return r, ok
+}
+
+// compare orders two level names by severity. applies is false when
+// field is not a level field or expected is not a known level; ok is
+// false when actual is not a known level.
+func (l *Levels) compare(field string, actual, expected any) (cmp int, ok, applies bool) {
+	if l == nil || !slices.Contains(l.Fields, field) {
+		return 0, false, false
+	}
+	e, known := l.Rank(expected)
+	if !known {
+		return 0, false, false
+	}
+	a, known := l.Rank(actual)
+	return a - e, known, true
+}
+
+// orders reports whether c is an ordering or range condition on a level
+// field whose values are all known levels.
+func (l *Levels) orders(c *Condition) bool {
+	if !slices.Contains(l.Fields, c.Field) {
+		return false
+	}
+	values := []any{c.Value}
+	if c.Operator == OpRange {
+		values, _ = c.Value.([]any)
+	}
+	for _, v := range values {
+		if _, ok := l.Rank(v); !ok {
+			return false
+		}
+	}
+	return len(values) > 0
+}
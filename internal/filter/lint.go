@@ -0,0 +1,183 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Warning is a performance or correctness hint about one condition of a
+// query, with a suggested rewrite when there is one.
+type Warning struct {
+	Condition  string // The condition, in query syntax
+	Message    string
+	Suggestion string // Rewritten condition; empty when none applies
+}
+
+// String renders the warning for stderr.
+func (w Warning) String() string {
+	s := fmt.Sprintf("warning: %s: %s", w.Condition, w.Message)
+	if w.Suggestion != "" {
+		s += fmt.Sprintf("; try %s", w.Suggestion)
+	}
+	return s
+}
+
+// Lint looks for query anti-patterns behind most slow or surprising
+// filters: regexes with a leading or trailing .*, which force a scan from
+// every position, regexes that are plain literals, where *= is much
+// cheaper, and ordering comparisons that fall back to comparing strings.
+// The latter are found from the value in the query and, when sample
+// entries are given, from the values the field actually holds.
+func Lint(chain *FilterChain, sample ...*parser.LogEntry) []Warning {
+	var warnings []Warning
+	var walk func(c *FilterChain)
+	walk = func(c *FilterChain) {
+		if c == nil {
+			return
+		}
+		for i := range c.Conditions {
+			warnings = append(warnings, lintCondition(&c.Conditions[i], sample)...)
+		}
+		for _, sub := range c.SubChains {
+			walk(sub)
+		}
+	}
+	walk(chain)
+	return warnings
+}
+
+func lintCondition(c *Condition, sample []*parser.LogEntry) []Warning {
+	warn := func(msg, suggestion string) Warning {
+		return Warning{Condition: c.String(), Message: msg, Suggestion: suggestion}
+	}
+	var warnings []Warning
+	switch c.Operator {
+	case OpRegex:
+		pattern := regexSource(c.Value)
+		if trimmed, ok := trimWildcards(pattern); ok {
+			w := warn("leading or trailing .* is redundant in an unanchored regex and slows matching", "")
+			if trimmed != "" {
+				w.Suggestion = (&Condition{Field: c.Field, Operator: OpRegex, Value: trimmed}).String()
+			}
+			warnings = append(warnings, w)
+			pattern = trimmed
+		}
+		if re, err := regexp.Compile(pattern); err == nil && pattern != "" {
+			if lit, complete := re.LiteralPrefix(); complete && !hasNamedGroup(re) {
+				warnings = append(warnings, warn("regex is a plain literal; a substring match is much faster",
+					(&Condition{Field: c.Field, Operator: OpContains, Value: lit}).String()))
+			}
+		}
+
+	case OpGt, OpLt, OpGte, OpLte, OpRange:
+		if DefaultLevels.orders(c) {
+			break // Compared by severity
+		}
+		values := []any{c.Value}
+		if c.Operator == OpRange {
+			values, _ = c.Value.([]any)
+		}
+		for _, v := range values {
+			if s, ok := v.(string); ok && !isNumeric(s) && !isTime(s) {
+				warnings = append(warnings, warn(fmt.Sprintf("%q is not a number or time, so values are compared as strings (\"10\" < \"9\")", s), ""))
+				return warnings
+			}
+		}
+		if example, ok := nonNumericSample(c.Field, sample); ok {
+			warnings = append(warnings, warn(
+				fmt.Sprintf("%s holds non-numeric values such as %q, which are compared as strings", c.Field, example),
+				fmt.Sprintf(`%s~="(?P<n>-?[0-9.]+)",n%s`, c.Field, strings.TrimPrefix(c.String(), c.Field))))
+		}
+	}
+	return warnings
+}
+
+// regexSource returns the pattern of a regex condition value.
+func regexSource(v any) string {
+	if re, ok := v.(*regexp.Regexp); ok {
+		return re.String()
+	}
+	return ToString(v)
+}
+
+// trimWildcards strips a leading ".*" (with a "^" before it) and a
+// trailing ".*" (with a "$" after it) and reports whether there was
+// anything to strip. Flags such as (?i) are kept.
+func trimWildcards(pattern string) (string, bool) {
+	flags := ""
+	if strings.HasPrefix(pattern, "(?") {
+		if end := strings.IndexByte(pattern, ')'); end > 0 && !strings.Contains(pattern[:end], ":") {
+			flags, pattern = pattern[:end+1], pattern[end+1:]
+		}
+	}
+
+	changed := false
+	body, start := strings.CutPrefix(pattern, "^")
+	if rest, ok := strings.CutPrefix(body, ".*"); ok {
+		body, start, changed = rest, false, true
+	}
+	end := strings.HasSuffix(body, "$") && !strings.HasSuffix(body, `\$`)
+	if end {
+		body = strings.TrimSuffix(body, "$")
+	}
+	if rest, ok := strings.CutSuffix(body, ".*"); ok && !strings.HasSuffix(body, `\.*`) {
+		body, end, changed = rest, false, true
+	}
+	if !changed {
+		return "", false
+	}
+	if body == "" {
+		return "", true
+	}
+	if start {
+		body = "^" + body
+	}
+	if end {
+		body += "$"
+	}
+	return flags + body, true
+}
+
+func hasNamedGroup(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumeric(s string) bool {
+	_, ok := ToFloat(s)
+	return ok
+}
+
+func isTime(s string) bool {
+	_, ok := parser.ParseTime(s)
+	return ok
+}
+
+// nonNumericSample returns a sample value of field that is neither a
+// number nor a time, if every sampled value is such.
+func nonNumericSample(field string, sample []*parser.LogEntry) (string, bool) {
+	example, seen := "", false
+	for _, e := range sample {
+		v, ok := lookup(e, field)
+		if !ok || v == nil {
+			continue
+		}
+		if _, ok := ToFloat(v); ok {
+			return "", false
+		}
+		if _, ok := parser.ParseTime(v); ok {
+			return "", false
+		}
+		if !seen {
+			example, seen = ToString(v), true
+		}
+	}
+	return example, seen
+}
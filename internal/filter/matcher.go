@@ -0,0 +1,453 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// FieldMatcher is the default Matcher. It evaluates conditions against an
+// entry's flattened fields with short-circuit AND/OR evaluation.
+//
+// With Highlight set, the fields behind a match are listed in the entry's
+// Matched. Conditions inside failed or negated chains are not listed, and
+// the regex captures, MatchedBranchField and MatchedPatternsField they set
+// are discarded.
+// Missing selects how conditions on absent fields evaluate. Levels orders
+// level fields by severity; nil selects DefaultLevels.
+type FieldMatcher struct {
+	Highlight bool
+	Missing   MissingMode
+	Levels    *Levels
+
+	indexes  sync.Map // *FilterChain → *chainIndex, built on first use
+	captures sync.Map // *FilterChain → []string, the fields it may set (see captureNames)
+	noIndex  bool     // Scan every condition, for comparing with the index
+}
+
+// NewMatcher creates a new FieldMatcher.
+func NewMatcher() *FieldMatcher {
+	return &FieldMatcher{}
+}
+
+// Match checks if a log entry satisfies the filter chain. A nil or empty
+// chain matches everything.
+func (m *FieldMatcher) Match(entry *parser.LogEntry, chain *FilterChain) bool {
+	if chain == nil {
+		return true
+	}
+	mark := len(entry.Matched)
+	var saved []savedField
+	names := m.captureNames(chain)
+	if len(names) > 0 {
+		saved = saveFields(entry, names)
+	}
+	ok := m.matchChain(entry, chain) != chain.Negate
+	if !ok || chain.Negate {
+		if m.Highlight {
+			entry.Matched = entry.Matched[:mark]
+		}
+		if len(names) > 0 {
+			restoreFields(entry, saved)
+		}
+	}
+	return ok
+}
+
+func (m *FieldMatcher) matchChain(entry *parser.LogEntry, chain *FilterChain) bool {
+	or := chain.Logic == LogicOr
+	if len(chain.Conditions) == 0 && len(chain.SubChains) == 0 {
+		return true
+	}
+
+	if idx := m.index(chain); idx != nil {
+		if i, ok := m.matchIndexed(entry, chain, idx); ok {
+			c := &chain.Conditions[i]
+			if v, ok := lookup(entry, c.Field); ok && m.Highlight {
+				m.record(entry, c, v)
+			}
+			recordBranch(entry, chain, c.Name, c.String)
+			return true
+		}
+	} else {
+		for i := range chain.Conditions {
+			c := &chain.Conditions[i]
+			if m.matchCondition(entry, c) == or {
+				if or {
+					recordBranch(entry, chain, c.Name, c.String)
+				}
+				return or
+			}
+		}
+	}
+	for _, sub := range chain.SubChains {
+		if m.Match(entry, sub) == or {
+			if or {
+				recordBranch(entry, chain, sub.Name, sub.String)
+			}
+			return or
+		}
+	}
+	return !or
+}
+
+// index returns the bulk index for an OR chain, or nil. Indexes skip
+// absent fields, so they are not used when those compare as null.
+func (m *FieldMatcher) index(chain *FilterChain) *chainIndex {
+	if chain.Logic != LogicOr || len(chain.Conditions) < min(minIndexedEq, minIndexedContains) || m.Missing == MissingNull || m.noIndex {
+		return nil
+	}
+	if v, ok := m.indexes.Load(chain); ok {
+		return v.(*chainIndex)
+	}
+	v, _ := m.indexes.LoadOrStore(chain, buildChainIndex(chain))
+	return v.(*chainIndex)
+}
+
+// matchIndexed evaluates the conditions of an indexed OR chain: a hash
+// lookup per equality set and an automaton pass per substring set, then a
+// scan of the remaining conditions.
+// It returns the first matching condition in chain order.
+func (m *FieldMatcher) matchIndexed(entry *parser.LogEntry, chain *FilterChain, idx *chainIndex) (int, bool) {
+	hit := idx.lookup(entry)
+	for _, i := range idx.scan {
+		if hit >= 0 && i > hit {
+			break
+		}
+		if m.matchCondition(entry, &chain.Conditions[i]) {
+			return i, true
+		}
+	}
+	return hit, hit >= 0
+}
+
+// MatchedBranchField is the synthetic field naming the OR branch that
+// matched: its "as" label, or its query text when unnamed. Conditions are
+// evaluated before parenthesized groups, so when several branches match,
+// the first matching condition is reported.
+const MatchedBranchField = "_matched_branch"
+
+// recordBranch stores the matching branch of a multi-branch OR chain.
+// Outer chains are evaluated last, so the outermost OR wins.
+func recordBranch(entry *parser.LogEntry, chain *FilterChain, name string, text func() string) {
+	if len(chain.Conditions)+len(chain.SubChains) < 2 {
+		return
+	}
+	if name == "" {
+		name = text()
+	}
+	entry.Fields[MatchedBranchField] = name
+}
+
+// matchCondition evaluates a single condition. Missing fields are handled
+// as m.Missing says, except by OpExists, which reports their absence.
+func (m *FieldMatcher) matchCondition(entry *parser.LogEntry, c *Condition) bool {
+	if base, rest, ok := strings.Cut(c.Field, "[]"); ok {
+		return m.matchElements(entry, arrayElements(entry, base, rest), c)
+	}
+
+	actual, ok := lookup(entry, c.Field)
+	if c.Operator == OpExists {
+		if ok {
+			m.record(entry, c, nil)
+		}
+		return ok
+	}
+	if !ok {
+		return m.matchMissing(c)
+	}
+	if !m.matchValue(entry, actual, c) {
+		return false
+	}
+	m.record(entry, c, actual)
+	return true
+}
+
+// record adds the field of a satisfied condition, and the text of actual
+// that matched, to entry.Matched when highlighting.
+func (m *FieldMatcher) record(entry *parser.LogEntry, c *Condition, actual any) {
+	if !m.Highlight {
+		return
+	}
+	var text string
+	switch c.Operator {
+	case OpExists, OpNe, OpNotIn:
+	case OpContains:
+		text = ToString(c.Value)
+	case OpRegex:
+		if re, ok := c.Value.(*regexp.Regexp); ok {
+			text = re.FindString(ToString(actual))
+		}
+	default:
+		// A function's result need not appear in the line.
+		if compiledExpr(c.Field) == nil {
+			text = ToString(actual)
+		}
+	}
+	fm := parser.FieldMatch{Field: c.Field, Text: text}
+	for _, seen := range entry.Matched {
+		if seen == fm {
+			return
+		}
+	}
+	entry.Matched = append(entry.Matched, fm)
+}
+
+// RawField is a synthetic field that resolves to the entry's original line.
+const RawField = "_raw"
+
+// lookup returns the value of field in entry, resolving TimestampField,
+// RawField and function calls such as len(field).
+func lookup(entry *parser.LogEntry, field string) (any, bool) {
+	if e := compiledExpr(field); e != nil {
+		return e.Eval(entry)
+	}
+	if field == RawField {
+		return entry.Raw, true
+	}
+	if field == TimestampField {
+		if !entry.Timestamp.IsZero() {
+			return entry.Timestamp, true
+		}
+		name, ok := parser.DetectTimeField(entry.Fields)
+		if !ok {
+			return nil, false
+		}
+		field = name
+	}
+	v, ok := entry.Fields[field]
+	return v, ok
+}
+
+// matchElements applies c to array elements: any element must satisfy it,
+// except for OpNe and OpNotIn, which require that no element equals the
+// value or is in the set.
+func (m *FieldMatcher) matchElements(entry *parser.LogEntry, elems []any, c *Condition) bool {
+	switch c.Operator {
+	case OpExists, OpNe, OpNotIn:
+		if len(elems) == 0 {
+			return false
+		}
+		for _, e := range elems {
+			if (c.Operator == OpNe && m.equal(e, c.Value)) || (c.Operator == OpNotIn && m.member(e, c.Value)) {
+				return false
+			}
+		}
+		m.record(entry, c, nil)
+		return true
+	}
+	for _, e := range elems {
+		if m.matchValue(entry, e, c) {
+			m.record(entry, c, e)
+			return true
+		}
+	}
+	return false
+}
+
+// matchValue applies a comparison operator to a present field value.
+func (m *FieldMatcher) matchValue(entry *parser.LogEntry, actual any, c *Condition) bool {
+	switch c.Operator {
+	case OpEq:
+		return m.equal(actual, c.Value)
+	case OpNe:
+		return !m.equal(actual, c.Value)
+	case OpIn:
+		return m.member(actual, c.Value)
+	case OpNotIn:
+		return !m.member(actual, c.Value)
+	case OpRange:
+		return m.inRange(c.Field, actual, c.Value)
+	case OpGt, OpLt, OpGte, OpLte:
+		cmp, ok := m.order(c.Field, actual, c.Value)
+		if !ok {
+			return false
+		}
+		switch c.Operator {
+		case OpGt:
+			return cmp > 0
+		case OpLt:
+			return cmp < 0
+		case OpGte:
+			return cmp >= 0
+		default:
+			return cmp <= 0
+		}
+	case OpRegex:
+		return matchRegex(entry, ToString(actual), c.Value)
+	case OpContains:
+		return strings.Contains(ToString(actual), ToString(c.Value))
+	}
+	return false
+}
+
+// equal compares numerically when both sides are numeric, and as strings
+// otherwise, so status:500 matches both 500 and "500".
+func (m *FieldMatcher) equal(actual, expected any) bool {
+	if t, ok := expected.(time.Time); ok {
+		cmp, ok := compareTime(actual, t)
+		return ok && cmp == 0
+	}
+	if t, ok := actual.(time.Time); ok {
+		cmp, ok := compareTime(expected, t)
+		return ok && cmp == 0
+	}
+	if a, ok := ToFloat(actual); ok {
+		if e, ok := ToFloat(expected); ok {
+			return a == e
+		}
+	}
+	return ToString(actual) == ToString(expected)
+}
+
+// member reports whether actual equals any value of the set, comparing
+// each member as equal does.
+func (m *FieldMatcher) member(actual, set any) bool {
+	members, _ := set.([]any)
+	for _, v := range members {
+		if m.equal(actual, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// inRange reports whether actual lies within the inclusive [low, high]
+// bounds, comparing each bound as order does.
+func (m *FieldMatcher) inRange(field string, actual, bounds any) bool {
+	b, _ := bounds.([]any)
+	if len(b) != 2 {
+		return false
+	}
+	lo, ok := m.order(field, actual, b[0])
+	if !ok || lo < 0 {
+		return false
+	}
+	hi, ok := m.order(field, actual, b[1])
+	return ok && hi <= 0
+}
+
+// order compares actual against expected by severity when field is a
+// level field and both are known levels, and as compare does otherwise.
+func (m *FieldMatcher) order(field string, actual, expected any) (int, bool) {
+	levels := m.Levels
+	if levels == nil {
+		levels = DefaultLevels
+	}
+	if cmp, ok, applies := levels.compare(field, actual, expected); applies {
+		return cmp, ok
+	}
+	return m.compare(actual, expected)
+}
+
+// compare orders actual against expected, as timestamps when either side
+// is a time.Time and numerically when possible. The boolean is false when the
+// values are not comparable.
+func (m *FieldMatcher) compare(actual, expected any) (int, bool) {
+	if t, ok := expected.(time.Time); ok {
+		return compareTime(actual, t)
+	}
+	if t, ok := actual.(time.Time); ok {
+		cmp, ok := compareTime(expected, t)
+		return -cmp, ok
+	}
+	if a, ok := ToFloat(actual); ok {
+		if e, ok := ToFloat(expected); ok {
+			switch {
+			case a < e:
+				return -1, true
+			case a > e:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if actual == nil || expected == nil {
+		return 0, false
+	}
+	return strings.Compare(ToString(actual), ToString(expected)), true
+}
+
+// matchRegex matches s against pattern. Named groups of a successful
+// match are stored as fields on the entry, so message~="user (?P<uid>\d+)"
+// makes uid available to later conditions, output and later stages.
+// Match takes them back out of chains that fail or are negated.
+func matchRegex(entry *parser.LogEntry, s string, pattern any) bool {
+	switch p := pattern.(type) {
+	case *regexp.Regexp:
+		if p.NumSubexp() == 0 || !hasNamedGroups(p) {
+			return p.MatchString(s)
+		}
+		m := p.FindStringSubmatch(s)
+		if m == nil {
+			return false
+		}
+		for i, name := range p.SubexpNames() {
+			if name != "" && i < len(m) {
+				entry.Fields[name] = m[i]
+			}
+		}
+		return true
+	case string:
+		ok, err := regexp.MatchString(p, s)
+		return err == nil && ok
+	}
+	return false
+}
+
+func hasNamedGroups(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ToString renders a field value the way it appears in queries.
+func ToString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case int:
+		return strconv.Itoa(t)
+	case bool:
+		return strconv.FormatBool(t)
+	case []string:
+		return strings.Join(t, ",")
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// ToFloat converts numeric values and numeric strings to float64.
+func ToFloat(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int64:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case string:
+		t = strings.TrimSpace(t)
+		if !parser.IsNumeric(t) {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,118 @@
+package filter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestMatchOperators(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fields := map[string]any{
+		"level":   "warn",
+		"status":  int64(503),
+		"code":    "500",
+		"ms":      12.5,
+		"ok":      false,
+		"msg":     "connection timed out after 30s",
+		"user.id": "u-42",
+		"tags":    []string{"db", "slow"},
+		"ratio":   "nan",
+		"count":   "1_000",
+		"at":      ts,
+		"nothing": nil,
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		// Numbers compare numerically, whatever their type in the entry.
+		{`status:503`, true},
+		{`status:"503"`, true},
+		{`code:500`, true},
+		{`code:500.0`, true},
+		{`status!=503`, false},
+		{`status>500`, true},
+		{`status<500`, false},
+		{`ms>=12.5`, true},
+		{`ms<=12`, false},
+		{`ok:false`, true},
+		// Values that parse as special floats stay strings.
+		{`ratio:nan`, true},
+		{`count:1000`, false},
+		{`count:1_000`, true},
+		// Strings, substrings and patterns.
+		{`level:warn`, true},
+		{`level:WARN`, false},
+		{`msg*=timed out`, true},
+		{`msg~="after \d+s$"`, true},
+		{`msg~="^timed"`, false},
+		{`user.id:u-42`, true},
+		// Levels order by severity, not alphabetically.
+		{`level>=info`, true},
+		{`level>error`, false},
+		{`level><info..error`, true},
+		{`level:info..error`, false}, // Not numbers or times: literal
+		// Sets and ranges.
+		{`status in (500, 503)`, true},
+		{`status not in (500, 503)`, false},
+		{`level:[debug,info]`, false},
+		{`status:500..599`, true},
+		{`ms><13..20`, false},
+		{`at>2024-02-29T00:00:00Z`, true},
+		{`at:2024-03-01T00:00:00Z..2024-03-01T23:59:59Z`, true},
+		// Presence, arrays and nulls.
+		{`user.id?`, true},
+		{`host?`, false},
+		{`nothing?`, true},
+		{`tags[]:slow`, true},
+		{`tags[]!=db`, false},
+		{`len(tags)=2`, true},
+		{`lower(level):warn`, true},
+	}
+	m := NewMatcher()
+	for _, tt := range tests {
+		chain, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if got := m.Match(&parser.LogEntry{Fields: clone(fields)}, chain); got != tt.want {
+			t.Errorf("%s on %v = %v, want %v", tt.query, fields, got, tt.want)
+		}
+	}
+}
+
+func TestMatchNilChain(t *testing.T) {
+	e := &parser.LogEntry{Fields: map[string]any{"a": 1.0}}
+	if !NewMatcher().Match(e, nil) || !NewMatcher().Match(e, &FilterChain{}) {
+		t.Error("nil or empty chain did not match")
+	}
+}
+
+func TestToFloat(t *testing.T) {
+	tests := []struct {
+		v    any
+		want float64
+		ok   bool
+	}{
+		{int64(3), 3, true},
+		{2.5, 2.5, true},
+		{"42", 42, true},
+		{" 7 ", 7, true},
+		{"1e3", 1000, true},
+		{"nan", 0, false},
+		{"Inf", 0, false},
+		{"1_000", 0, false},
+		{true, 0, false},
+		{nil, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ToFloat(tt.v)
+		if ok != tt.ok || (ok && got != tt.want) || math.IsNaN(got) {
+			t.Errorf("ToFloat(%#v) = %v, %v; want %v, %v", tt.v, got, ok, tt.want, tt.ok)
+		}
+	}
+}
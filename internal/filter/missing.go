@@ -0,0 +1,72 @@
+package filter
+
+import "fmt"
+
+// MissingMode selects how a condition evaluates when its field is absent
+// (--missing-as). Field existence (field?) and array conditions (tags[])
+// are not affected.
+type MissingMode int
+
+const (
+	// MissingFail fails every condition on an absent field, including
+	// status!=200 and status not in (...).
+	MissingFail MissingMode = iota
+
+	// MissingPassNegative passes != and "not in" on an absent field, which
+	// after all does not equal the value, and fails every other operator.
+	MissingPassNegative
+
+	// MissingNull evaluates an absent field as null: it equals only null
+	// (x:null), differs from everything else (x!=200 passes), is a member
+	// of a set only if the set lists null, and fails ordering, range,
+	// substring and regex operators.
+	MissingNull
+)
+
+var missingModeNames = []string{"fail", "pass-for-negative-ops", "treat-as-null"}
+
+// ParseMissingMode parses a --missing-as value.
+func ParseMissingMode(s string) (MissingMode, error) {
+	for i, name := range missingModeNames {
+		if s == name {
+			return MissingMode(i), nil
+		}
+	}
+	return MissingFail, fmt.Errorf("query: missing-as %q: want fail, pass-for-negative-ops or treat-as-null", s)
+}
+
+// String returns the --missing-as name of the mode.
+func (m MissingMode) String() string {
+	if int(m) < len(missingModeNames) {
+		return missingModeNames[m]
+	}
+	return fmt.Sprintf("MissingMode(%d)", int(m))
+}
+
+// matchMissing evaluates c for an entry without its field.
+func (m *FieldMatcher) matchMissing(c *Condition) bool {
+	switch m.Missing {
+	case MissingPassNegative:
+		return c.Operator == OpNe || c.Operator == OpNotIn
+	case MissingNull:
+		switch c.Operator {
+		case OpEq:
+			return isNull(c.Value)
+		case OpNe:
+			return !isNull(c.Value)
+		case OpIn, OpNotIn:
+			listed := false
+			set, _ := c.Value.([]any)
+			for _, v := range set {
+				listed = listed || isNull(v)
+			}
+			return listed == (c.Operator == OpIn)
+		}
+	}
+	return false
+}
+
+// isNull reports whether a query value is null.
+func isNull(v any) bool {
+	return v == nil || v == "null"
+}
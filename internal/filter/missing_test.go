@@ -0,0 +1,45 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// TestMissingModes documents how each --missing-as mode evaluates
+// conditions on a field the entry does not have.
+func TestMissingModes(t *testing.T) {
+	tests := []struct {
+		query          string
+		fail, neg, nul bool
+	}{
+		{`status=200`, false, false, false},
+		{`status:null`, false, false, true},
+		{`status!=200`, false, true, true},
+		{`status!=null`, false, true, false},
+		{`status in (200, 404)`, false, false, false},
+		{`status in (200, null)`, false, false, true},
+		{`status not in (200, 404)`, false, true, true},
+		{`status not in (200, null)`, false, true, false},
+		{`status>200`, false, false, false},
+		{`status~="^2"`, false, false, false},
+		{`status?`, false, false, false},
+		{`!status?`, true, true, true},
+		{`!(status=200)`, true, true, true},
+		{`!(status!=200)`, true, false, false},
+		{`level:info,status!=200`, false, true, true},
+	}
+	entry := &parser.LogEntry{Fields: map[string]any{"level": "info"}}
+	for _, tt := range tests {
+		chain, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		for mode, want := range map[MissingMode]bool{MissingFail: tt.fail, MissingPassNegative: tt.neg, MissingNull: tt.nul} {
+			m := &FieldMatcher{Missing: mode}
+			if got := m.Match(entry, chain); got != want {
+				t.Errorf("%s with %v: got %v, want %v", tt.query, mode, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package filter
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// ParallelFilter parses and filters chunks of lines on a worker pool
+// (fan-out/fan-in).
+type ParallelFilter struct {
+	Workers   int           // Default: runtime.NumCPU()
+	ChunkSize int           // Lines per chunk (default: 1000)
+	Ordered   bool          // Emit matches in input order
+	Parser    parser.Parser // Line parser shared by all workers
+	Matcher   Matcher       // Condition evaluator shared by all workers
+
+	// OnParseError, if set, is called with each line that fails to parse,
+	// from the worker goroutines.
+	OnParseError func(rec Record, err error)
+
+	totalLines  atomic.Int64
+	parseErrors atomic.Int64
+	filtered    atomic.Int64
+}
+
+// NewParallelFilter creates a ParallelFilter with default sizing.
+func NewParallelFilter(p parser.Parser, m Matcher) *ParallelFilter {
+	return &ParallelFilter{
+		Workers:   runtime.NumCPU(),
+		ChunkSize: 1000,
+		Parser:    p,
+		Matcher:   m,
+	}
+}
+
+// chunkResult carries the output of one chunk for ordered merging.
+type chunkResult[T any] struct {
+	seq   int
+	items []T
+}
+
+// Filter spawns the workers and returns a channel of matching entries,
+// closed once input is drained. With Ordered set, matches are emitted in
+// input order at the cost of buffering out-of-order chunks.
+func (p *ParallelFilter) Filter(input <-chan parser.Chunk, chain *FilterChain) <-chan *parser.LogEntry {
+	plan := p.plan(chain)
+	return run(p, input, p.Ordered, func(chunk parser.Chunk) []*parser.LogEntry {
+		return p.filterChunk(chunk, chain, plan)
+	})
+}
+
+// Record is one input line as seen by FilterAll.
+type Record struct {
+	Line    string           // Raw line (or multiline record)
+	LineNum int              // Line number in the source
+	Source  string           // Input file, if known
+	Entry   *parser.LogEntry // Parsed entry when the line matched, else nil
+}
+
+// FilterAll is like Filter but emits every line, matched or not, always in
+// input order, for stages that need the lines around matches (-A/-B/-C).
+func (p *ParallelFilter) FilterAll(input <-chan parser.Chunk, chain *FilterChain) <-chan Record {
+	plan := p.plan(chain)
+	return run(p, input, true, func(chunk parser.Chunk) []Record {
+		recs := make([]Record, len(chunk.Lines))
+		for i, line := range chunk.Lines {
+			recs[i] = Record{Line: line, LineNum: chunk.LineNum(i), Source: chunk.Source}
+		}
+		matches := p.filterChunk(chunk, chain, plan)
+		for i, j := 0, 0; i < len(recs) && j < len(matches); i++ {
+			if recs[i].LineNum == matches[j].LineNum {
+				recs[i].Entry = matches[j]
+				j++
+			}
+		}
+		return recs
+	})
+}
+
+// run processes chunks with process on p.Workers goroutines and returns a
+// channel of the results, closed once input is drained. With ordered set,
+// results are emitted in input order.
+func run[T any](p *ParallelFilter, input <-chan parser.Chunk, ordered bool, process func(parser.Chunk) []T) <-chan T {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	out := make(chan T, 1024)
+	results := make(chan chunkResult[T], workers)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range input {
+				items := process(chunk)
+				if ordered {
+					results <- chunkResult[T]{seq: chunk.Seq, items: items}
+					continue
+				}
+				for _, e := range items {
+					out <- e
+				}
+			}
+		}()
+	}
+
+	if !ordered {
+		go func() {
+			wg.Wait()
+			close(out)
+		}()
+		return out
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	go func() {
+		defer close(out)
+		pending := make(map[int][]T)
+		next := 0
+		for r := range results {
+			pending[r.seq] = r.items
+			for {
+				items, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				for _, e := range items {
+					out <- e
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// FilterChunk parses and filters one chunk on the calling goroutine, for
+// callers that schedule the work themselves.
+func (p *ParallelFilter) FilterChunk(chunk parser.Chunk, chain *FilterChain) []*parser.LogEntry {
+	return p.filterChunk(chunk, chain, p.plan(chain))
+}
+
+// filterChunk parses every line of a chunk and returns the matches.
+//
+// Lines lacking the literals the chain requires are dropped unparsed (see
+// screen). JSON lines are then matched against only the fields the chain
+// reads, extracted without decoding the rest of the line (see
+// parser.ExtractJSON); the full parse, and a second match that records
+// captures and highlights, happen only for lines that pass. Malformed
+// lines dropped early are therefore not always counted as parse errors.
+// A chunk with Columns is parsed with them (see parser.ForColumns).
+func (p *ParallelFilter) filterChunk(chunk parser.Chunk, chain *FilterChain, plan chunkPlan) []*parser.LogEntry {
+	var matches []*parser.LogEntry
+	lineParser := p.Parser
+	if chunk.Columns != nil {
+		lineParser = parser.ForColumns(lineParser, chunk.Columns)
+	}
+	for i, line := range chunk.Lines {
+		if plan.screen != nil && chunk.Context == nil && !plan.screen.pass(line) {
+			p.filtered.Add(1)
+			continue
+		}
+		if plan.lazy && parser.LazyJSON(lineParser, line) {
+			if partial, ok := parser.ExtractJSON(line, plan.keys); ok {
+				chunk.ApplyContext(i, partial)
+				if !p.Matcher.Match(partial, chain) {
+					p.filtered.Add(1)
+					continue
+				}
+			}
+		}
+		entry, err := lineParser.Parse(line)
+		if errors.Is(err, parser.ErrHeaderLine) {
+			p.filtered.Add(1)
+			continue
+		}
+		if err != nil {
+			p.parseErrors.Add(1)
+			if p.OnParseError != nil {
+				p.OnParseError(Record{Line: line, LineNum: chunk.LineNum(i), Source: chunk.Source}, err)
+			}
+			continue
+		}
+		entry.LineNum = chunk.LineNum(i)
+		entry.Source = chunk.Source
+		entry.ID = chunk.EntryID(i)
+		chunk.ApplyContext(i, entry)
+		if p.Matcher.Match(entry, chain) {
+			matches = append(matches, entry)
+		} else {
+			p.filtered.Add(1)
+		}
+	}
+	p.totalLines.Add(int64(len(chunk.Lines)))
+	return matches
+}
+
+// chunkPlan holds what filterChunk derives from the chain once.
+type chunkPlan struct {
+	screen screen   // Literals a line must contain, or nil
+	keys   []string // Fields to extract for the JSON pre-match
+	lazy   bool     // Pre-match JSON lines on keys
+}
+
+// plan prepares the shortcuts that are safe for chain. None are taken
+// when the chain matches everything or a custom Matcher might read any
+// field; the screen also needs the built-in parsers, which copy values
+// from the line, and is off when absent fields compare as null.
+func (p *ParallelFilter) plan(chain *FilterChain) chunkPlan {
+	m, ok := p.Matcher.(*FieldMatcher)
+	if !ok || chain == nil || len(chain.Conditions)+len(chain.SubChains) == 0 {
+		return chunkPlan{}
+	}
+	var plan chunkPlan
+	if m.Missing != MissingNull && parser.Verbatim(p.Parser) {
+		plan.screen = buildScreen(chain)
+	}
+	plan.keys, plan.lazy = chain.Fields()
+	return plan
+}
+
+// TotalLines returns the number of lines processed so far.
+func (p *ParallelFilter) TotalLines() int64 {
+	return p.totalLines.Load()
+}
+
+// ParseErrors returns the number of lines that failed to parse so far.
+func (p *ParallelFilter) ParseErrors() int64 {
+	return p.parseErrors.Load()
+}
+
+// Filtered returns the number of lines dropped so far for not matching,
+// including header lines and lines dropped before parsing (see
+// filterChunk). Every line processed is a match, a parse error or
+// filtered.
+func (p *ParallelFilter) Filtered() int64 {
+	return p.filtered.Load()
+}
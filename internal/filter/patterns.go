@@ -0,0 +1,164 @@
+package filter
+
+import (
+	"slices"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// minIndexedContains is the number of substring conditions on one field in
+// an OR chain from which they are compiled into a PatternSet instead of
+// calling strings.Contains for each.
+const minIndexedContains = 4
+
+// MatchedPatternsField is the synthetic field listing the substrings of a
+// compiled *= set that occur in the entry, in query order. It is set when
+// a wide OR of substring conditions is evaluated, e.g. a watchlist.
+const MatchedPatternsField = "_matched_patterns"
+
+// PatternSet finds which of many substrings occur in a string with a
+// single pass (Aho–Corasick). Matching is byte-wise and case-sensitive,
+// like strings.Contains. A PatternSet is safe for concurrent use.
+type PatternSet struct {
+	patterns []string
+	classes  [256]uint16 // Byte → column in next; 0 for bytes in no pattern
+	width    int         // Columns per state
+	next     []int32     // State transitions: next[state*width+class]
+	out      [][]int32   // Patterns ending at each state, including via failure links
+}
+
+// NewPatternSet compiles patterns. Empty patterns never match.
+func NewPatternSet(patterns []string) *PatternSet {
+	p := &PatternSet{patterns: patterns, width: 1}
+	for _, s := range patterns {
+		for i := 0; i < len(s); i++ {
+			if p.classes[s[i]] == 0 {
+				p.classes[s[i]] = uint16(p.width)
+				p.width++
+			}
+		}
+	}
+
+	// Build the trie; -1 marks a missing edge until failure links fill it.
+	p.addState()
+	for id, s := range patterns {
+		if s == "" {
+			continue
+		}
+		state := int32(0)
+		for i := 0; i < len(s); i++ {
+			slot := int(state)*p.width + int(p.classes[s[i]])
+			if p.next[slot] < 0 {
+				p.next[slot] = p.addState()
+			}
+			state = p.next[slot]
+		}
+		p.out[state] = append(p.out[state], int32(id))
+	}
+
+	// Breadth-first, turn the trie into a DFA: missing edges follow the
+	// failure link, and each state inherits the outputs of its fallback.
+	fail := make([]int32, len(p.out))
+	var queue []int32
+	for c := 0; c < p.width; c++ {
+		if child := p.next[c]; child > 0 {
+			queue = append(queue, child)
+		} else {
+			p.next[c] = 0
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		row := int(state) * p.width
+		fallback := int(fail[state]) * p.width
+		for c := 0; c < p.width; c++ {
+			child := p.next[row+c]
+			if child < 0 {
+				p.next[row+c] = p.next[fallback+c]
+				continue
+			}
+			fail[child] = p.next[fallback+c]
+			p.out[child] = append(p.out[child], p.out[fail[child]]...)
+			queue = append(queue, child)
+		}
+	}
+	return p
+}
+
+func (p *PatternSet) addState() int32 {
+	for range p.width {
+		p.next = append(p.next, -1)
+	}
+	p.out = append(p.out, nil)
+	return int32(len(p.out) - 1)
+}
+
+// Patterns returns the compiled patterns, indexed as reported by Matches.
+func (p *PatternSet) Patterns() []string {
+	return p.patterns
+}
+
+// MatchString reports whether any pattern occurs in s.
+func (p *PatternSet) MatchString(s string) bool {
+	state := int32(0)
+	for i := 0; i < len(s); i++ {
+		state = p.next[int(state)*p.width+int(p.classes[s[i]])]
+		if len(p.out[state]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns the indexes of the patterns that occur in s, ascending
+// and without duplicates.
+func (p *PatternSet) Matches(s string) []int {
+	var hits []int
+	state := int32(0)
+	for i := 0; i < len(s); i++ {
+		state = p.next[int(state)*p.width+int(p.classes[s[i]])]
+		for _, id := range p.out[state] {
+			hits = append(hits, int(id))
+		}
+	}
+	slices.Sort(hits)
+	return slices.Compact(hits)
+}
+
+// containsSet evaluates the substring conditions on one field of an OR
+// chain with a single PatternSet.
+type containsSet struct {
+	field string
+	set   *PatternSet
+	conds []int // Pattern index → index into chain.Conditions
+}
+
+func newContainsSet(chain *FilterChain, field string, conds []int) containsSet {
+	patterns := make([]string, len(conds))
+	for i, c := range conds {
+		patterns[i] = ToString(chain.Conditions[c].Value)
+	}
+	return containsSet{field: field, set: NewPatternSet(patterns), conds: conds}
+}
+
+// lookup returns the lowest index of a condition in the set the entry
+// satisfies, or -1, and records the hits in MatchedPatternsField.
+func (cs *containsSet) lookup(entry *parser.LogEntry) int {
+	v, ok := lookup(entry, cs.field)
+	if !ok {
+		return -1
+	}
+	hits := cs.set.Matches(ToString(v))
+	if len(hits) == 0 {
+		return -1
+	}
+	names, _ := entry.Fields[MatchedPatternsField].([]string)
+	for _, h := range hits {
+		if !slices.Contains(names, cs.set.patterns[h]) {
+			names = append(names, cs.set.patterns[h])
+		}
+	}
+	entry.Fields[MatchedPatternsField] = names
+	return cs.conds[hits[0]]
+}
@@ -0,0 +1,655 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// QueryParser parses filter expressions into FilterChains.
+//
+// Grammar (NOT binds tightest, then AND, then OR):
+//
+//	query     → or
+//	or        → and ("|" and)*
+//	and       → unary ("," unary)*
+//	unary     → ("!" unary | "(" or ")" | condition) ["as" name]
+//	condition → field operator value | field "?" | field ["not"] "in" set
+//	          | field (":" | "=" | "!=") list | field ("><" | ":" | "=") range
+//	field     → path | path "[]" | func "(" expr ("," expr)* ")"
+//	expr      → path | func "(" expr ("," expr)* ")"
+//	set       → "(" value ("," value)* ")"
+//	list      → "[" value ("," value)* "]"
+//	range     → value ".." value
+//
+// Values containing separators or parentheses must be double-quoted. The
+// optional "as name" labels an OR branch for the _matched_branch field; it
+// follows a quoted value, a set or a group, since a bare value such as
+// message:treat it as admin would be ambiguous and is rejected.
+// A range is inclusive and compares numerically or as timestamps; with ":"
+// or "=" both bounds must be numbers or times, otherwise the value is
+// matched literally.
+// A "tags[]" field matches when any array element satisfies the operator
+// (for != when none equals the value); len(tags) is the element count.
+// Functions transform field values before comparison: len (array or
+// string length), lower, upper, abs, and coalesce (the first present,
+// non-null argument).
+type QueryParser struct {
+	input string
+	pos   int
+}
+
+// NewQueryParser creates a new QueryParser.
+func NewQueryParser() *QueryParser {
+	return &QueryParser{}
+}
+
+// ParseQuery is a convenience wrapper around NewQueryParser().Parse.
+func ParseQuery(query string) (*FilterChain, error) {
+	return NewQueryParser().Parse(query)
+}
+
+// Parse converts a query string into a FilterChain. An empty query yields
+// an empty chain, which matches every entry.
+func (p *QueryParser) Parse(query string) (*FilterChain, error) {
+	p.input = query
+	p.pos = 0
+
+	p.skipSpace()
+	if p.eof() {
+		return &FilterChain{Logic: LogicAnd}, nil
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+	return n.chain(), nil
+}
+
+// node is an intermediate parse result: either a single condition or a chain.
+type node struct {
+	cond  *Condition
+	group *FilterChain
+}
+
+// chain returns the node as a FilterChain, wrapping a bare condition.
+func (n node) chain() *FilterChain {
+	if n.group != nil {
+		return n.group
+	}
+	return &FilterChain{Conditions: []Condition{*n.cond}, Logic: LogicAnd}
+}
+
+// combine builds a chain of the given logic from operands, merging operands
+// that are non-negated chains of the same logic.
+func combine(logic Logic, operands []node) node {
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	chain := &FilterChain{Logic: logic}
+	for _, op := range operands {
+		switch {
+		case op.cond != nil:
+			chain.Conditions = append(chain.Conditions, *op.cond)
+		case op.group.Logic == logic && !op.group.Negate && op.group.Name == "":
+			chain.Conditions = append(chain.Conditions, op.group.Conditions...)
+			chain.SubChains = append(chain.SubChains, op.group.SubChains...)
+		default:
+			chain.SubChains = append(chain.SubChains, op.group)
+		}
+	}
+	return node{group: chain}
+}
+
+func (p *QueryParser) parseOr() (node, error) {
+	operands, err := p.parseList('|', p.parseAnd)
+	if err != nil {
+		return node{}, err
+	}
+	return combine(LogicOr, operands), nil
+}
+
+func (p *QueryParser) parseAnd() (node, error) {
+	operands, err := p.parseList(',', p.parseUnary)
+	if err != nil {
+		return node{}, err
+	}
+	return combine(LogicAnd, operands), nil
+}
+
+// parseList parses one or more operands separated by sep.
+func (p *QueryParser) parseList(sep byte, operand func() (node, error)) ([]node, error) {
+	var operands []node
+	for {
+		n, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, n)
+
+		p.skipSpace()
+		if p.eof() || p.input[p.pos] != sep {
+			return operands, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *QueryParser) parseUnary() (node, error) {
+	n, err := p.parseOperand()
+	if err != nil {
+		return node{}, err
+	}
+
+	name := p.parseAlias()
+	switch {
+	case name == "":
+	case n.cond != nil && n.cond.Name == "":
+		n.cond.Name = name
+	default:
+		chain := *n.chain()
+		chain.Name = name
+		n = node{group: &chain}
+	}
+	return n, nil
+}
+
+// parseAlias consumes an optional `as name` suffix.
+func (p *QueryParser) parseAlias() string {
+	p.skipSpace()
+	m := aliasPrefix.FindStringSubmatch(p.input[p.pos:])
+	if m == nil {
+		return ""
+	}
+	p.pos += len(m[0])
+	return m[1]
+}
+
+// aliasPrefix matches an "as name" suffix at the parser position.
+var aliasPrefix = regexp.MustCompile(`^as\s+([A-Za-z_][\w-]*)`)
+
+// aliasSuffix matches a bare value ending in what reads as an "as name"
+// label, which is ambiguous: labels follow quoted values and groups.
+var aliasSuffix = regexp.MustCompile(`^(.*?)\s+as\s+([A-Za-z_][\w-]*)$`)
+
+func (p *QueryParser) parseOperand() (node, error) {
+	p.skipSpace()
+	if p.eof() {
+		return node{}, p.errorf("unexpected end of query")
+	}
+
+	switch p.input[p.pos] {
+	case '!':
+		p.pos++
+		n, err := p.parseOperand()
+		if err != nil {
+			return node{}, err
+		}
+		chain := n.chain()
+		if n.cond == nil {
+			// Copy so a merged parent never aliases a negated child.
+			c := *chain
+			chain = &c
+		}
+		chain.Negate = !chain.Negate
+		return node{group: chain}, nil
+
+	case '(':
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return node{}, err
+		}
+		p.skipSpace()
+		if p.eof() || p.input[p.pos] != ')' {
+			return node{}, p.errorf("expected ')'")
+		}
+		p.pos++
+		return n, nil
+	}
+
+	cond, err := p.parseCondition()
+	if err != nil {
+		return node{}, err
+	}
+	return node{cond: cond}, nil
+}
+
+func (p *QueryParser) parseCondition() (*Condition, error) {
+	start := p.pos
+	for !p.eof() && isFieldByte(p.input[p.pos]) {
+		p.pos++
+	}
+	field := strings.TrimSpace(p.input[start:p.pos])
+	if field == "" {
+		return nil, p.errorf("expected field name")
+	}
+	if IsFunc(field) && !p.eof() && p.input[p.pos] == '(' {
+		end := closingParen(p.input[p.pos:])
+		if end < 0 {
+			return nil, p.errorf("expected ')'")
+		}
+		e, rest, err := parseExpr(field + p.input[p.pos:p.pos+end+1])
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if rest != "" {
+			return nil, p.errorf("unexpected %q", rest)
+		}
+		field = e.String()
+		p.pos += end + 1
+	}
+
+	if m := inSuffix.FindStringSubmatch(field); m != nil && !p.eof() && p.input[p.pos] == '(' {
+		op := OpIn
+		if m[2] != "" {
+			op = OpNotIn
+		}
+		return p.parseSet(m[1], op, ')')
+	}
+
+	opStart := p.pos
+	op, err := p.parseOperator()
+	if err != nil {
+		return nil, err
+	}
+	if op == OpExists {
+		return &Condition{Field: field, Operator: OpExists}, nil
+	}
+	p.skipSpace()
+	if (op == OpEq || op == OpNe) && !p.eof() && p.input[p.pos] == '[' {
+		if op == OpEq {
+			return p.parseSet(field, OpIn, ']')
+		}
+		return p.parseSet(field, OpNotIn, ']')
+	}
+
+	valueStart := p.pos
+	quoted := !p.eof() && p.peekQuote()
+	raw, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	cond := &Condition{Field: field, Operator: op}
+	if m := aliasSuffix.FindStringSubmatch(raw); m != nil && !quoted {
+		// message:treat it as admin could be either; make the user say.
+		lhs := field + strings.TrimSpace(p.input[opStart:valueStart])
+		return nil, fmt.Errorf("query: value %q for %q ends in \"as %s\": quote the value, as in %s\"%s\" as %s to label the branch or %s%q to match it",
+			raw, field, m[2], lhs, m[1], m[2], lhs, raw)
+	}
+	if op == OpEq && !quoted {
+		if bounds, ok := rangeBounds(raw, false); ok {
+			cond.Operator, cond.Value = OpRange, bounds
+			return cond, nil
+		}
+	}
+	switch op {
+	case OpRange:
+		bounds, ok := rangeBounds(raw, true)
+		if !ok {
+			return nil, fmt.Errorf("query: invalid range %q for %q", raw, field)
+		}
+		cond.Value = bounds
+	case OpRegex:
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("query: invalid regex for %q: %w", field, err)
+		}
+		cond.Value = re
+	case OpContains:
+		cond.Value = raw
+	default:
+		cond.Value = typedValue(raw)
+	}
+	return cond, nil
+}
+
+// rangeBounds splits a low..high value into typed bounds. Numbers and
+// timestamps are recognized; other bounds are accepted as strings only when
+// loose is set.
+func rangeBounds(raw string, loose bool) ([]any, bool) {
+	lo, hi, ok := strings.Cut(raw, "..")
+	lo, hi = strings.TrimSpace(lo), strings.TrimSpace(hi)
+	if !ok || lo == "" || hi == "" {
+		return nil, false
+	}
+	low, lok := rangeBound(lo)
+	high, hok := rangeBound(hi)
+	if !loose && (!lok || !hok) {
+		return nil, false
+	}
+	return []any{low, high}, true
+}
+
+// rangeBound types a range bound as a number or a time, falling back to the
+// raw string.
+func rangeBound(s string) (any, bool) {
+	if f, ok := ToFloat(s); ok {
+		return f, true
+	}
+	if t, ok := parser.ParseTime(s); ok {
+		return t, true
+	}
+	return s, false
+}
+
+// inSuffix splits an "in" or "not in" keyword off the text before a set.
+var inSuffix = regexp.MustCompile(`^(.*?)\s+(not\s+)?in$`)
+
+// parseSet reads the members of a set opening at the parser position and
+// closed by end, typing each like a single value.
+func (p *QueryParser) parseSet(field string, op Operator, end byte) (*Condition, error) {
+	p.pos++ // opening bracket
+	var members []any
+	for {
+		p.skipSpace()
+		quoted := p.peekQuote()
+		raw, err := p.parseMember(end)
+		if err != nil {
+			return nil, err
+		}
+		if raw == "" && !quoted {
+			return nil, p.errorf("empty set member")
+		}
+		members = append(members, typedValue(raw))
+
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("expected %q", end)
+		}
+		switch p.input[p.pos] {
+		case ',':
+			p.pos++
+		case end:
+			p.pos++
+			return &Condition{Field: field, Operator: op, Value: members}, nil
+		default:
+			return nil, p.errorf("unexpected %q in set", p.input[p.pos])
+		}
+	}
+}
+
+// parseMember reads a quoted set member, or a bare one up to ',' or end.
+func (p *QueryParser) parseMember(end byte) (string, error) {
+	if p.peekQuote() {
+		return p.parseQuoted()
+	}
+	start := p.pos
+	for !p.eof() && p.input[p.pos] != ',' && p.input[p.pos] != end {
+		p.pos++
+	}
+	return strings.TrimSpace(p.input[start:p.pos]), nil
+}
+
+// operators lists operator tokens, longest first so ">=" wins over ">".
+var operators = []struct {
+	token string
+	op    Operator
+}{
+	{"!=", OpNe},
+	{"><", OpRange},
+	{">=", OpGte},
+	{"<=", OpLte},
+	{"~=", OpRegex},
+	{"*=", OpContains},
+	{":", OpEq},
+	{"=", OpEq},
+	{">", OpGt},
+	{"<", OpLt},
+	{"?", OpExists},
+}
+
+// Operators returns the operator tokens of the query language, including
+// the "in" and "not in" set keywords.
+func Operators() []string {
+	tokens := make([]string, 0, len(operators)+2)
+	for _, o := range operators {
+		tokens = append(tokens, o.token)
+	}
+	return append(tokens, "in", "not in")
+}
+
+func (p *QueryParser) parseOperator() (Operator, error) {
+	rest := p.input[p.pos:]
+	for _, o := range operators {
+		if strings.HasPrefix(rest, o.token) {
+			p.pos += len(o.token)
+			return o.op, nil
+		}
+	}
+	if p.eof() {
+		return 0, p.errorf("expected operator")
+	}
+	return 0, p.errorf("unknown operator at %q", rest)
+}
+
+// parseValue reads a bare value up to the next separator or ')', or a
+// double-quoted value with backslash escapes.
+func (p *QueryParser) parseValue() (string, error) {
+	p.skipSpace()
+	if !p.eof() && p.input[p.pos] == '"' {
+		return p.parseQuoted()
+	}
+
+	start := p.pos
+	for !p.eof() {
+		switch p.input[p.pos] {
+		case ',', '|', ')':
+			return strings.TrimSpace(p.input[start:p.pos]), nil
+		}
+		p.pos++
+	}
+	return strings.TrimSpace(p.input[start:]), nil
+}
+
+func (p *QueryParser) parseQuoted() (string, error) {
+	start := p.pos
+	p.pos++ // opening quote
+
+	var b strings.Builder
+	for !p.eof() {
+		c := p.input[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.input) && (p.input[p.pos+1] == '"' || p.input[p.pos+1] == '\\'):
+			b.WriteByte(p.input[p.pos+1])
+			p.pos += 2
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated quoted value")
+}
+
+// typedValue converts a raw query value into a number, boolean or string.
+func typedValue(raw string) any {
+	if f, ok := ToFloat(raw); ok {
+		return f
+	}
+	switch raw {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return raw
+}
+
+// closingParen returns the index of the parenthesis closing the one that
+// starts s, or -1.
+func closingParen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isFieldByte(c byte) bool {
+	switch c {
+	case ':', '=', '!', '>', '<', '~', '*', '?', ',', '|', '(', ')', '"':
+		return false
+	}
+	return true
+}
+
+func (p *QueryParser) skipSpace() {
+	for !p.eof() && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// peekQuote reports whether the next non-space byte opens a quoted value.
+func (p *QueryParser) peekQuote() bool {
+	rest := strings.TrimLeft(p.input[p.pos:], " \t")
+	return rest != "" && rest[0] == '"'
+}
+
+func (p *QueryParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *QueryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("query: %s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+// String renders the condition in query syntax.
+func (c Condition) String() string {
+	var b strings.Builder
+	b.WriteString(c.Field)
+	switch c.Operator {
+	case OpIn, OpNotIn:
+		if c.Operator == OpNotIn {
+			b.WriteString(" not")
+		}
+		b.WriteString(" in (")
+		members, _ := c.Value.([]any)
+		for i, v := range members {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(formatValue(v))
+		}
+		b.WriteByte(')')
+		if c.Name != "" {
+			b.WriteString(" as " + c.Name)
+		}
+		return b.String()
+	}
+	for _, o := range operators {
+		if o.op == c.Operator {
+			b.WriteString(o.token)
+			break
+		}
+	}
+	switch c.Operator {
+	case OpExists:
+	case OpRange:
+		bounds, _ := c.Value.([]any)
+		if len(bounds) == 2 {
+			b.WriteString(formatValue(bounds[0]) + ".." + formatValue(bounds[1]))
+		}
+		if c.Name != "" {
+			// A label may not follow a bare value.
+			return "(" + b.String() + ") as " + c.Name
+		}
+	default:
+		if c.Name != "" {
+			b.WriteString(quoteValue(c.Value) + " as " + c.Name)
+			return b.String()
+		}
+		b.WriteString(formatValue(c.Value))
+	}
+	if c.Name != "" {
+		b.WriteString(" as " + c.Name)
+	}
+	return b.String()
+}
+
+// String renders the chain in query syntax.
+func (c *FilterChain) String() string {
+	s := c.body()
+	if c.Negate {
+		s = "!(" + s + ")"
+	}
+	if c.Name != "" {
+		s += " as " + c.Name
+	}
+	return s
+}
+
+// operand renders the chain as a parenthesized operand of its parent.
+func (c *FilterChain) operand() string {
+	s := "(" + c.body() + ")"
+	if c.Negate {
+		s = "!" + s
+	}
+	if c.Name != "" {
+		s += " as " + c.Name
+	}
+	return s
+}
+
+// body renders the chain's conditions and sub-chains joined by its logic.
+func (c *FilterChain) body() string {
+	sep := ","
+	if c.Logic == LogicOr {
+		sep = "|"
+	}
+
+	parts := make([]string, 0, len(c.Conditions)+len(c.SubChains))
+	for _, cond := range c.Conditions {
+		parts = append(parts, cond.String())
+	}
+	for _, sub := range c.SubChains {
+		parts = append(parts, sub.operand())
+	}
+	return strings.Join(parts, sep)
+}
+
+// formatValue renders a condition value, quoting it when it contains
+// characters the query grammar treats specially.
+func formatValue(v any) string {
+	var s string
+	switch t := v.(type) {
+	case *regexp.Regexp:
+		s = t.String()
+	case nil:
+		return ""
+	default:
+		s = ToString(v)
+	}
+	if strings.ContainsAny(s, ",|()\" ") || strings.HasPrefix(s, "[") || strings.Contains(s, "..") || aliasSuffix.MatchString(s) {
+		return quoteValue(v)
+	}
+	return s
+}
+
+// quoteValue renders a condition value double-quoted.
+func quoteValue(v any) string {
+	s := ToString(v)
+	if re, ok := v.(*regexp.Regexp); ok {
+		s = re.String()
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,194 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestBranchLabels(t *testing.T) {
+	tests := []struct {
+		query  string
+		fields map[string]any
+		branch any // Expected _matched_branch; nil for no match
+	}{
+		{`level:"error" as err|level:"warn" as wrn`, map[string]any{"level": "warn"}, "wrn"},
+		{`(level:error) as err|level:warn`, map[string]any{"level": "error"}, "err"},
+		{`(level:warn,status>=500) as slow|level:error`, map[string]any{"level": "warn", "status": int64(503)}, "slow"},
+		{`a in (1, 2) as small|a>"100" as big`, map[string]any{"a": int64(2)}, "small"},
+		{`a? as has_a|b?`, map[string]any{"a": "x"}, "has_a"},
+		{`(a:1..5) as low|a:9`, map[string]any{"a": int64(3)}, "low"},
+		{`a:"x as y"|b:1`, map[string]any{"a": "x as y"}, `a:"x as y"`},
+		{`message:"treat it as admin"|b:1`, map[string]any{"message": "treat it as admin"}, `message:"treat it as admin"`},
+		{`message:"treat it" as admin|b:1`, map[string]any{"message": "treat it as admin"}, nil},
+	}
+	m := NewMatcher()
+	for _, tt := range tests {
+		chain, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		for _, c := range []*FilterChain{chain, reparse(t, chain)} {
+			e := &parser.LogEntry{Fields: clone(tt.fields)}
+			ok := m.Match(e, c)
+			if got := e.Fields[MatchedBranchField]; ok != (tt.branch != nil) || got != tt.branch {
+				t.Errorf("%s (as %s): matched %v, branch %v; want %v", tt.query, c, ok, got, tt.branch)
+			}
+		}
+	}
+}
+
+// TestBranchDiscarded checks that failed and negated chains leave no
+// _matched_branch behind, and that a branch logged under that name is kept.
+func TestBranchDiscarded(t *testing.T) {
+	tests := []struct {
+		query  string
+		fields map[string]any
+		match  bool
+		branch any // Expected _matched_branch; nil for none
+	}{
+		{`(level:"error" as err|level:warn),status>=500`, map[string]any{"level": "error", "status": int64(200)}, false, nil},
+		{`!(level:"error" as err|level:warn)`, map[string]any{"level": "debug"}, true, nil},
+		{`!(level:"error" as err|level:warn),status:200`, map[string]any{"level": "error", "status": int64(200)}, false, nil},
+		{`((level:"error" as err|level:warn),status>=500)|(status:200) as ok`, map[string]any{"level": "error", "status": int64(200)}, true, "ok"},
+		{`(level:"error" as err|level:warn),status>=500`, map[string]any{"level": "error", "status": int64(200), MatchedBranchField: "logged"}, false, "logged"},
+	}
+	for _, tt := range tests {
+		chain, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		for _, m := range []*FieldMatcher{{}, {noIndex: true}} {
+			e := &parser.LogEntry{Fields: clone(tt.fields)}
+			ok := m.Match(e, chain)
+			if got := e.Fields[MatchedBranchField]; ok != tt.match || got != tt.branch {
+				t.Errorf("%s: matched %v, branch %v; want %v, %v", tt.query, ok, got, tt.match, tt.branch)
+			}
+		}
+	}
+}
+
+// reparse parses the rendering of chain, which must be valid query syntax.
+func reparse(t *testing.T, chain *FilterChain) *FilterChain {
+	t.Helper()
+	c, err := ParseQuery(chain.String())
+	if err != nil {
+		t.Fatalf("ParseQuery(%q) of rendered chain: %v", chain.String(), err)
+	}
+	return c
+}
+
+func TestBareValueEndingInAsIsRejected(t *testing.T) {
+	for _, q := range []string{
+		`message:treat it as admin`,
+		`a:x as y`,
+		`level:error as err|level:warn`,
+		`a:1..5 as low`,
+	} {
+		_, err := ParseQuery(q)
+		if err == nil || !strings.Contains(err.Error(), "quote the value") {
+			t.Errorf("ParseQuery(%q): err = %v, want a request to quote", q, err)
+		}
+	}
+}
+
+// TestQueryPrecedence checks that NOT binds tighter than AND and AND
+// tighter than OR, and that groups override both.
+func TestQueryPrecedence(t *testing.T) {
+	entries := []map[string]any{
+		{"a": int64(1), "b": int64(1), "c": int64(0)},
+		{"a": int64(1), "b": int64(0), "c": int64(1)},
+		{"a": int64(0), "b": int64(1), "c": int64(1)},
+		{"a": int64(0), "b": int64(0), "c": int64(0)},
+	}
+	tests := []struct {
+		query string
+		want  []bool // Match of each entry
+	}{
+		{`a:1,b:1|c:1`, []bool{true, true, true, false}},
+		{`a:1,(b:1|c:1)`, []bool{true, true, false, false}},
+		{`c:1|a:1,b:1`, []bool{true, true, true, false}},
+		{`!a:1,b:1`, []bool{false, false, true, false}},
+		{`!(a:1,b:1)`, []bool{false, true, true, true}},
+		{`!(a:1|b:1),c:0`, []bool{false, false, false, true}},
+		{`!!a:1`, []bool{true, true, false, false}},
+		{`((a:1)),((b:0|c:0))`, []bool{true, true, false, false}},
+		{` a : 1 , b : 1 `, []bool{true, false, false, false}},
+		{``, []bool{true, true, true, true}},
+	}
+	m := NewMatcher()
+	for _, tt := range tests {
+		chain, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		for _, c := range []*FilterChain{chain, reparse(t, chain)} {
+			for i, fields := range entries {
+				if got := m.Match(&parser.LogEntry{Fields: clone(fields)}, c); got != tt.want[i] {
+					t.Errorf("%s (as %s) on %v = %v, want %v", tt.query, c, fields, got, tt.want[i])
+				}
+			}
+		}
+	}
+}
+
+func TestParseQueryConditions(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Condition
+	}{
+		{`level:error`, Condition{Field: "level", Operator: OpEq, Value: "error"}},
+		{`level=error`, Condition{Field: "level", Operator: OpEq, Value: "error"}},
+		{`status!=200`, Condition{Field: "status", Operator: OpNe, Value: 200.0}},
+		{`status>=500`, Condition{Field: "status", Operator: OpGte, Value: 500.0}},
+		{`status<=499`, Condition{Field: "status", Operator: OpLte, Value: 499.0}},
+		{`ok:true`, Condition{Field: "ok", Operator: OpEq, Value: true}},
+		{`user.id?`, Condition{Field: "user.id", Operator: OpExists}},
+		{`msg*=time out`, Condition{Field: "msg", Operator: OpContains, Value: "time out"}},
+		{`msg:"a,b|c"`, Condition{Field: "msg", Operator: OpEq, Value: "a,b|c"}},
+		{`msg:"say \"hi\""`, Condition{Field: "msg", Operator: OpEq, Value: `say "hi"`}},
+		{`id:"42"`, Condition{Field: "id", Operator: OpEq, Value: 42.0}},
+		{`level in (warn, error)`, Condition{Field: "level", Operator: OpIn, Value: []any{"warn", "error"}}},
+		{`level not in (debug)`, Condition{Field: "level", Operator: OpNotIn, Value: []any{"debug"}}},
+		{`level:[warn,error]`, Condition{Field: "level", Operator: OpIn, Value: []any{"warn", "error"}}},
+		{`ms:10..20`, Condition{Field: "ms", Operator: OpRange, Value: []any{10.0, 20.0}}},
+		{`ms><1..2`, Condition{Field: "ms", Operator: OpRange, Value: []any{1.0, 2.0}}},
+	}
+	for _, tt := range tests {
+		chain, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if len(chain.Conditions) != 1 || len(chain.SubChains) != 0 {
+			t.Errorf("ParseQuery(%q) = %s, want one condition", tt.query, chain)
+			continue
+		}
+		if got := chain.Conditions[0]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseQuery(%q) = %#v, want %#v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, q := range []string{
+		`level:error)`,
+		`(level:error`,
+		`level:error,`,
+		`|level:error`,
+		`:error`,
+		`level`,
+		`level^error`,
+		`msg:"open`,
+		`msg~=(`,
+		`ms><10`,
+		`level in (warn`,
+		`!`,
+	} {
+		if chain, err := ParseQuery(q); err == nil {
+			t.Errorf("ParseQuery(%q) = %s, want an error", q, chain)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// screen lists literal substrings a raw line must contain for a chain to
+// possibly match: at least one of each group. It lets ParallelFilter drop
+// most lines of a selective query before any parser runs, as grep would.
+type screen [][]string
+
+// pass reports whether line may match. Lines with backslash escapes are
+// always passed, since an escaped value need not appear literally.
+func (s screen) pass(line string) bool {
+	if strings.IndexByte(line, '\\') >= 0 {
+		return true
+	}
+	for _, group := range s {
+		found := false
+		for _, lit := range group {
+			if strings.Contains(line, lit) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// buildScreen derives the screen of a chain from the string values its
+// equality, membership, substring and regex conditions require. All
+// conditions of an AND chain must hold, so each adds a group; an OR chain
+// yields one group covering all its branches, or nothing when some branch
+// has no literal. Negated chains and other operators add nothing.
+func buildScreen(chain *FilterChain) screen {
+	if chain == nil || chain.Negate {
+		return nil
+	}
+	var groups screen
+	or := chain.Logic == LogicOr
+	var alts []string // OR: union of one group per branch
+	for i := range chain.Conditions {
+		lits := conditionLiterals(&chain.Conditions[i])
+		switch {
+		case !or && lits != nil:
+			groups = append(groups, lits)
+		case or && lits == nil:
+			return nil
+		case or:
+			alts = append(alts, lits...)
+		}
+	}
+	for _, sub := range chain.SubChains {
+		subGroups := buildScreen(sub)
+		switch {
+		case !or:
+			groups = append(groups, subGroups...)
+		case len(subGroups) == 0:
+			return nil
+		default:
+			alts = append(alts, subGroups[0]...)
+		}
+	}
+	if or && len(alts) > 0 {
+		return screen{alts}
+	}
+	return groups
+}
+
+// conditionLiterals returns the substrings of which the raw line must
+// contain one for c to hold, or nil when there are none to rely on:
+// values that may be compared as numbers or rendered differently from the
+// line, and fields that are computed rather than parsed.
+func conditionLiterals(c *Condition) []string {
+	if compiledExpr(c.Field) != nil || strings.HasPrefix(c.Field, "_") && c.Field != RawField {
+		return nil
+	}
+	switch c.Operator {
+	case OpEq:
+		if s, ok := c.Value.(string); ok && screenable(s) && !isNumeric(s) {
+			return []string{s}
+		}
+	case OpIn:
+		members, _ := c.Value.([]any)
+		var lits []string
+		for _, m := range members {
+			s, ok := m.(string)
+			if !ok || !screenable(s) || isNumeric(s) {
+				return nil
+			}
+			lits = append(lits, s)
+		}
+		return lits
+	case OpContains:
+		if s := ToString(c.Value); screenable(s) {
+			return []string{s}
+		}
+	case OpRegex:
+		if re, ok := c.Value.(*regexp.Regexp); ok {
+			if prefix, _ := re.LiteralPrefix(); screenable(prefix) {
+				return []string{prefix}
+			}
+		}
+	}
+	return nil
+}
+
+// screenable reports whether a literal could not be part of how a number
+// renders (ToString gives digits, signs, points, +Inf and NaN), so a field
+// holding it must be a string copied from the line.
+func screenable(s string) bool {
+	return strings.Trim(s, "0123456789.+-InfNa") != ""
+}
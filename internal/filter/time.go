@@ -0,0 +1,65 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// TimestampField is a synthetic field name that resolves to the entry's
+// normalized Timestamp, or to its detected timestamp field when none was
+// set (see parser.DetectTimeField).
+const TimestampField = "_timestamp"
+
+// ParseTimeBound parses a --since/--until value. It accepts anything
+// parser.ParseTime understands (RFC3339, common log formats, epoch) or a
+// relative duration such as "90s", "2h" or "7d", meaning that long before now.
+func ParseTimeBound(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if d, ok := parseRelative(s); ok {
+		return now.Add(-d), nil
+	}
+	if t, ok := parser.ParseTime(s); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("filter: invalid time %q", s)
+}
+
+// parseRelative parses a Go duration, extended with a "d" (day) suffix.
+func parseRelative(s string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(n * float64(24*time.Hour)), true
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+// TimeRange returns conditions restricting field to the inclusive range
+// [since, until]. Zero bounds are left open. Use TimestampField to match
+// against each entry's detected timestamp.
+func TimeRange(field string, since, until time.Time) []Condition {
+	var conds []Condition
+	if !since.IsZero() {
+		conds = append(conds, Condition{Field: field, Operator: OpGte, Value: since})
+	}
+	if !until.IsZero() {
+		conds = append(conds, Condition{Field: field, Operator: OpLte, Value: until})
+	}
+	return conds
+}
+
+// compareTime orders actual against t when actual parses as a timestamp.
+func compareTime(actual any, t time.Time) (int, bool) {
+	at, ok := parser.ParseTime(actual)
+	if !ok {
+		return 0, false
+	}
+	return at.Compare(t), true
+}
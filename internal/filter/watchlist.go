@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadWatchlist reads indicators (IPs, hashes, usernames, ...) from a
+// --watchlist file, one per line. Surrounding whitespace is trimmed; blank
+// lines, lines starting with # and repeated values are skipped.
+func LoadWatchlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var values []string
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		v := strings.TrimSpace(sc.Text())
+		if v == "" || strings.HasPrefix(v, "#") || seen[v] {
+			continue
+		}
+		seen[v] = true
+		values = append(values, v)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("watchlist: %s: %w", path, err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("watchlist: %s: no values", path)
+	}
+	return values, nil
+}
+
+// WatchlistChain returns an OR chain matching entries whose field
+// (--watch-field, RawField for the whole line) contains any of values.
+// Wide lists are evaluated in one pass by a PatternSet, and the values
+// found are listed in MatchedPatternsField.
+func WatchlistChain(field string, values []string) *FilterChain {
+	chain := &FilterChain{Logic: LogicOr, Conditions: make([]Condition, len(values))}
+	for i, v := range values {
+		chain.Conditions[i] = Condition{Field: field, Operator: OpContains, Value: v}
+	}
+	return chain
+}
+
+// And combines chains so an entry must match all of them. Nil chains are
+// ignored; with a single remaining chain it is returned as is.
+func And(chains ...*FilterChain) *FilterChain {
+	var subs []*FilterChain
+	for _, c := range chains {
+		if c != nil {
+			subs = append(subs, c)
+		}
+	}
+	switch len(subs) {
+	case 0:
+		return nil
+	case 1:
+		return subs[0]
+	}
+	return &FilterChain{Logic: LogicAnd, SubChains: subs}
+}
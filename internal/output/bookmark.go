@@ -0,0 +1,130 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Bookmark is an entry marked during an interactive session, with an
+// optional triage note.
+type Bookmark struct {
+	ID     string    `json:"id"`
+	Source string    `json:"source,omitempty"`
+	Line   int       `json:"line"`
+	Raw    string    `json:"raw"`
+	Note   string    `json:"note,omitempty"`
+	Added  time.Time `json:"added"`
+}
+
+// Bookmarks collects the entries bookmarked in an interactive session and
+// exports them at the end as JSON or Markdown, so others can re-locate
+// each one with flog show. Entries are keyed by their EntryID; bookmarking
+// an entry again updates its note.
+type Bookmarks struct {
+	Filter string // Filter of the session, recorded in exports
+
+	items []*Bookmark
+	index map[string]int
+}
+
+// NewBookmarks creates an empty bookmark set for a session filtering with
+// filter.
+func NewBookmarks(filter string) *Bookmarks {
+	return &Bookmarks{Filter: filter, index: make(map[string]int)}
+}
+
+// Add bookmarks entry with note, or replaces the note of an existing
+// bookmark. Entries without an ID cannot be bookmarked.
+func (b *Bookmarks) Add(entry *parser.LogEntry, note string) error {
+	if entry.ID.File == "" {
+		return fmt.Errorf("output: entry at line %d has no ID to bookmark", entry.LineNum)
+	}
+	id := entry.ID.String()
+	if i, ok := b.index[id]; ok {
+		b.items[i].Note = note
+		return nil
+	}
+	b.index[id] = len(b.items)
+	b.items = append(b.items, &Bookmark{
+		ID:     id,
+		Source: entry.Source,
+		Line:   entry.LineNum,
+		Raw:    entry.Raw,
+		Note:   note,
+		Added:  time.Now().UTC(),
+	})
+	return nil
+}
+
+// Remove drops the bookmark with the given ID and reports whether it
+// existed.
+func (b *Bookmarks) Remove(id string) bool {
+	i, ok := b.index[id]
+	if !ok {
+		return false
+	}
+	b.items = append(b.items[:i], b.items[i+1:]...)
+	delete(b.index, id)
+	for j := i; j < len(b.items); j++ {
+		b.index[b.items[j].ID] = j
+	}
+	return true
+}
+
+// Has reports whether the entry with the given ID is bookmarked.
+func (b *Bookmarks) Has(id string) bool {
+	_, ok := b.index[id]
+	return ok
+}
+
+// List returns the bookmarks in the order they were added.
+func (b *Bookmarks) List() []*Bookmark {
+	return b.items
+}
+
+// WriteJSON exports the bookmarks as one JSON document.
+func (b *Bookmarks) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	items := b.items
+	if items == nil {
+		items = []*Bookmark{}
+	}
+	return enc.Encode(struct {
+		Filter    string      `json:"filter,omitempty"`
+		Bookmarks []*Bookmark `json:"bookmarks"`
+	}{b.Filter, items})
+}
+
+// WriteMarkdown exports the bookmarks as a Markdown list for pasting into
+// incident documents: location and ID, note, then the raw line as code.
+func (b *Bookmarks) WriteMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("# flog bookmarks\n\n")
+	if b.Filter != "" {
+		fmt.Fprintf(&sb, "Filter: `%s`\n\n", b.Filter)
+	}
+	for _, bm := range b.items {
+		source := bm.Source
+		if source == "" || source == "-" {
+			source = StdinName
+		}
+		fmt.Fprintf(&sb, "- **%s:%d** `%s`\n", source, bm.Line, bm.ID)
+		if bm.Note != "" {
+			fmt.Fprintf(&sb, "  %s\n", strings.ReplaceAll(bm.Note, "\n", "\n  "))
+		}
+		fence := "```"
+		for strings.Contains(bm.Raw, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&sb, "\n  %s\n  %s\n  %s\n\n", fence, strings.ReplaceAll(bm.Raw, "\n", "\n  "), fence)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
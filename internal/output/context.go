@@ -0,0 +1,97 @@
+package output
+
+import "github.com/ishk9/flog/internal/filter"
+
+// GroupSeparator is printed between non-adjacent clusters of matches and
+// their context, as in grep.
+const GroupSeparator = "--"
+
+// ContextKind tells a ContextBuffer's emit function what it is given.
+type ContextKind int
+
+const (
+	ContextMatch     ContextKind = iota // A matching line
+	ContextLine                         // A line before or after a match
+	ContextSeparator                    // A gap between clusters; the record is empty
+)
+
+// ContextBuffer adds lines of context around matches (-A, -B, -C). It is
+// fed every line in input order (see ParallelFilter.FilterAll) and keeps
+// the last Before non-matching lines in a ring buffer, releasing them when
+// a match follows. Clusters that are not adjacent, or that come from
+// different files, are separated by a ContextSeparator.
+type ContextBuffer struct {
+	Before int // Lines of leading context (-B)
+	After  int // Lines of trailing context (-A)
+
+	emit      func(rec filter.Record, kind ContextKind)
+	ring      []pendingLine
+	head      int // Index of the oldest line in ring
+	afterLeft int // Trailing context lines still to emit
+
+	src     string // Source of the lines being added
+	seq     int    // Position of the next line added
+	lastSeq int    // Position of the last line emitted
+	lastSrc string // Source of the last line emitted
+	emitted bool
+}
+
+// pendingLine is a buffered context line and its position.
+type pendingLine struct {
+	rec filter.Record
+	seq int
+}
+
+// NewContextBuffer creates a ContextBuffer passing the lines to output,
+// in order, to emit.
+func NewContextBuffer(before, after int, emit func(rec filter.Record, kind ContextKind)) *ContextBuffer {
+	return &ContextBuffer{
+		Before: before,
+		After:  after,
+		emit:   emit,
+		ring:   make([]pendingLine, 0, max(before, 0)),
+	}
+}
+
+// Add feeds the next input line. Records with an Entry are matches.
+func (c *ContextBuffer) Add(rec filter.Record) {
+	seq := c.seq
+	c.seq++
+	if rec.Source != c.src {
+		// Context never crosses files.
+		c.src, c.ring, c.head, c.afterLeft = rec.Source, c.ring[:0], 0, 0
+	}
+
+	switch {
+	case rec.Entry != nil:
+		for i := range c.ring {
+			p := c.ring[(c.head+i)%len(c.ring)]
+			c.write(p.rec, p.seq, ContextLine)
+		}
+		c.ring, c.head = c.ring[:0], 0
+		c.write(rec, seq, ContextMatch)
+		c.afterLeft = c.After
+
+	case c.afterLeft > 0:
+		c.write(rec, seq, ContextLine)
+		c.afterLeft--
+
+	case c.Before > 0:
+		if len(c.ring) < c.Before {
+			c.ring = append(c.ring, pendingLine{rec: rec, seq: seq})
+			return
+		}
+		c.ring[c.head] = pendingLine{rec: rec, seq: seq}
+		c.head = (c.head + 1) % len(c.ring)
+	}
+}
+
+// write emits rec, preceded by a separator when it does not directly
+// follow the last line emitted.
+func (c *ContextBuffer) write(rec filter.Record, seq int, kind ContextKind) {
+	if c.emitted && (seq != c.lastSeq+1 || rec.Source != c.lastSrc) {
+		c.emit(filter.Record{}, ContextSeparator)
+	}
+	c.emit(rec, kind)
+	c.emitted, c.lastSeq, c.lastSrc = true, seq, rec.Source
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// CSVWriter writes matches as RFC 4180 CSV (-o csv) or tab-separated
+// values (-o tsv), one column per field, quoting values that contain the
+// separator, quotes or newlines. With Fields (-F) rows are streamed;
+// without, entries are buffered until Flush so the columns can be the
+// union of all fields seen, in sorted order. Missing fields are empty.
+type CSVWriter struct {
+	Fields []string // Columns; empty for the union of fields seen
+	Header bool     // Write a header row (disabled by --no-header)
+
+	w           *csv.Writer
+	wroteHeader bool
+	buffered    []*parser.LogEntry
+}
+
+// NewCSVWriter creates a CSVWriter writing to w with the given separator:
+// ',' for CSV or '\t' for TSV.
+func NewCSVWriter(w io.Writer, fields []string, comma rune) *CSVWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return &CSVWriter{Fields: fields, Header: true, w: cw}
+}
+
+// Write outputs the entry as a row, or buffers it when the columns are
+// not known yet.
+func (c *CSVWriter) Write(entry *parser.LogEntry) error {
+	if len(c.Fields) == 0 {
+		c.buffered = append(c.buffered, entry)
+		return nil
+	}
+	return c.writeRow(entry)
+}
+
+// Flush writes any buffered rows and flushes the output.
+func (c *CSVWriter) Flush() error {
+	if len(c.Fields) == 0 && len(c.buffered) > 0 {
+		seen := make(map[string]bool)
+		for _, e := range c.buffered {
+			for k := range e.Fields {
+				if !seen[k] {
+					seen[k] = true
+					c.Fields = append(c.Fields, k)
+				}
+			}
+		}
+		sort.Strings(c.Fields)
+		for _, e := range c.buffered {
+			if err := c.writeRow(e); err != nil {
+				return err
+			}
+		}
+		c.buffered = nil
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *CSVWriter) writeRow(entry *parser.LogEntry) error {
+	if c.Header && !c.wroteHeader {
+		if err := c.w.Write(c.Fields); err != nil {
+			return err
+		}
+	}
+	c.wroteHeader = true
+
+	row := make([]string, len(c.Fields))
+	for i, f := range c.Fields {
+		if v, ok := entry.Fields[f]; ok && v != nil {
+			row[i] = filter.ToString(v)
+		}
+	}
+	return c.w.Write(row)
+}
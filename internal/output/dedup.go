@@ -0,0 +1,59 @@
+package output
+
+import (
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Deduper suppresses repeated entries (--dedup): identical raw lines, or
+// entries repeating a field value such as a request ID. Entries without
+// the field are never suppressed.
+//
+// With a Window, only the most recent Window distinct keys are remembered,
+// bounding memory on huge streams at the cost of letting a repeat through
+// once its key has been evicted (--dedup-window).
+type Deduper struct {
+	Field  string // Empty to compare raw lines
+	Window int    // Distinct keys remembered; 0 for unbounded
+
+	seen map[string]struct{}
+	ring []string // Keys in insertion order, when windowed
+	next int      // Ring slot to evict next
+
+	Suppressed int64 // Entries dropped as duplicates
+}
+
+// NewDeduper creates a Deduper keyed on field (raw lines when empty).
+func NewDeduper(field string, window int) *Deduper {
+	return &Deduper{Field: field, Window: window, seen: make(map[string]struct{})}
+}
+
+// Keep reports whether entry is the first with its key and should be
+// output. It is not safe for concurrent use; call it from the merger.
+func (d *Deduper) Keep(entry *parser.LogEntry) bool {
+	key := entry.Raw
+	if d.Field != "" {
+		v, ok := entry.Fields[d.Field]
+		if !ok {
+			return true
+		}
+		key = filter.ToString(v)
+	}
+
+	if _, dup := d.seen[key]; dup {
+		d.Suppressed++
+		return false
+	}
+	d.seen[key] = struct{}{}
+
+	if d.Window > 0 {
+		if len(d.ring) < d.Window {
+			d.ring = append(d.ring, key)
+		} else {
+			delete(d.seen, d.ring[d.next])
+			d.ring[d.next] = key
+			d.next = (d.next + 1) % d.Window
+		}
+	}
+	return true
+}
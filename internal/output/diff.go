@@ -0,0 +1,50 @@
+package output
+
+import (
+	"maps"
+	"reflect"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// FieldDiff finds the fields whose values changed since the previous entry
+// with the same value of Key (--diff-fields), or since the previous entry
+// when Key is empty. It keeps the fields of the last entry of every key
+// value seen.
+type FieldDiff struct {
+	Key string
+
+	last map[string]map[string]any // Key value → fields of its last entry
+}
+
+// NewFieldDiff creates a FieldDiff grouping entries by key.
+func NewFieldDiff(key string) *FieldDiff {
+	return &FieldDiff{Key: key, last: make(map[string]map[string]any)}
+}
+
+// Changed records entry and returns its fields that are new or hold
+// another value than in the previous entry of its group. It returns nil
+// for the first entry of a group and for entries without the key field.
+func (d *FieldDiff) Changed(entry *parser.LogEntry) map[string]bool {
+	var group string
+	if d.Key != "" {
+		v, ok := entry.Fields[d.Key]
+		if !ok {
+			return nil
+		}
+		group = filter.ToString(v)
+	}
+	prev := d.last[group]
+	d.last[group] = maps.Clone(entry.Fields)
+	if prev == nil {
+		return nil
+	}
+	changed := make(map[string]bool)
+	for k, v := range entry.Fields {
+		if old, ok := prev[k]; !ok || !reflect.DeepEqual(old, v) {
+			changed[k] = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,48 @@
+package output
+
+import "github.com/ishk9/flog/internal/parser"
+
+// StdinName is how entries read from stdin are attributed, as in grep.
+const StdinName = "(standard input)"
+
+// FilenameFormatter prefixes each formatted entry with its source file and
+// a colon (--with-filename, -H), so matches from many files can be told
+// apart. Entries without a Source are left unprefixed.
+type FilenameFormatter struct {
+	Formatter
+}
+
+// WithFilename wraps f to prefix entries with their source file.
+func WithFilename(f Formatter) *FilenameFormatter {
+	return &FilenameFormatter{Formatter: f}
+}
+
+// Format renders the entry with the wrapped formatter and prefixes it.
+func (f *FilenameFormatter) Format(entry *parser.LogEntry) string {
+	return string(f.AppendFormat(nil, entry))
+}
+
+// AppendFormat appends the prefixed entry to dst.
+func (f *FilenameFormatter) AppendFormat(dst []byte, entry *parser.LogEntry) []byte {
+	switch entry.Source {
+	case "":
+	case "-":
+		dst = append(append(dst, StdinName...), ':')
+	default:
+		dst = append(append(dst, entry.Source...), ':')
+	}
+	return AppendFormat(dst, f.Formatter, entry)
+}
+
+// ShowFilenames decides whether output lines get a filename prefix: forced
+// by --with-filename or suppressed by --no-filename, and otherwise shown
+// when more than one input is read.
+func ShowFilenames(withFilename, noFilename bool, inputs int) bool {
+	switch {
+	case noFilename:
+		return false
+	case withFilename:
+		return true
+	}
+	return inputs > 1
+}
@@ -0,0 +1,225 @@
+package output
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// ANSI styles used by Highlighter.
+const (
+	styleKey   = "\x1b[1;36m" // Bold cyan
+	styleValue = "\x1b[1;31m" // Bold red
+	styleReset = "\x1b[0m"
+)
+
+// Highlighter emphasizes the fields and values that caused a match
+// (--highlight), as recorded in LogEntry.Matched by a FieldMatcher with
+// Highlight set.
+type Highlighter struct {
+	Key   string // Style for matched field names
+	Value string // Style for matched values
+}
+
+// NewHighlighter creates a Highlighter with the default ANSI styles.
+func NewHighlighter() *Highlighter {
+	return &Highlighter{Key: styleKey, Value: styleValue}
+}
+
+// Raw returns the entry's raw line with each matched text emphasized once:
+// within the value of its field where the field's key appears in the line
+// (JSON, logfmt), else at its first occurrence as a whole word, else at
+// its first occurrence. Where matches overlap, the longer one wins.
+func (h *Highlighter) Raw(entry *parser.LogEntry) string {
+	var spans []span
+	for _, m := range entry.Matched {
+		if m.Text == "" {
+			continue
+		}
+		if i, ok := findMatch(entry.Raw, matchedField(m.Field), m.Text); ok {
+			spans = append(spans, span{i, i + len(m.Text)})
+		}
+	}
+	return markSpans(entry.Raw, spans, h.Value)
+}
+
+// findMatch locates the text a condition on field matched in raw.
+func findMatch(raw, field, text string) (int, bool) {
+	key := field[strings.LastIndexByte(field, '.')+1:]
+	if i := strings.IndexByte(key, '['); i >= 0 {
+		key = key[:i]
+	}
+	for from := 0; key != ""; {
+		i := strings.Index(raw[from:], key)
+		if i < 0 {
+			break
+		}
+		start := from + i
+		from = start + len(key)
+		if start > 0 && isWordByte(raw[start-1]) {
+			continue
+		}
+		j := from
+		if j < len(raw) && raw[j] == '"' {
+			j++
+		}
+		j = skipSpaces(raw, j)
+		if j >= len(raw) || raw[j] != ':' && raw[j] != '=' {
+			continue
+		}
+		j = skipSpaces(raw, j+1)
+		if k := strings.Index(raw[j:valueEnd(raw, j)], text); k >= 0 {
+			return j + k, true
+		}
+	}
+
+	first := -1
+	for from := 0; ; {
+		i := strings.Index(raw[from:], text)
+		if i < 0 {
+			break
+		}
+		start, end := from+i, from+i+len(text)
+		if first < 0 {
+			first = start
+		}
+		if (start == 0 || !isWordByte(raw[start-1])) && (end == len(raw) || !isWordByte(raw[end])) {
+			return start, true
+		}
+		from = start + 1
+	}
+	return first, first >= 0
+}
+
+// valueEnd returns the end of the value starting at raw[i]: a quoted
+// string, a bracketed array or object, or a bare word.
+func valueEnd(raw string, i int) int {
+	depth := 0
+	for j := i; j < len(raw); j++ {
+		switch c := raw[j]; {
+		case c == '"':
+			for j++; j < len(raw) && raw[j] != '"'; j++ {
+				if raw[j] == '\\' {
+					j++
+				}
+			}
+			if depth == 0 {
+				return min(j+1, len(raw))
+			}
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			if depth == 0 {
+				return j
+			}
+			if depth--; depth == 0 {
+				return j + 1
+			}
+		case depth == 0 && (c == ' ' || c == '\t' || c == ','):
+			return j
+		}
+	}
+	return len(raw)
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// KeyValue styles one field of a key/value rendering such as pretty
+// output: the key when the field matched, and the matched text within
+// value.
+func (h *Highlighter) KeyValue(entry *parser.LogEntry, key, value string) (string, string) {
+	var texts []string
+	matched := false
+	for _, m := range entry.Matched {
+		if f := matchedField(m.Field); key != f && !strings.HasPrefix(key, f+"[") {
+			continue
+		}
+		matched = true
+		if m.Text != "" {
+			texts = append(texts, m.Text)
+		}
+	}
+	if !matched {
+		return key, value
+	}
+	return h.Key + key + styleReset, mark(value, texts, h.Value)
+}
+
+// matchedField maps a condition field to the field it highlights: tags[]
+// and len(tags) both point at tags, whose flattened keys are tags[0], ...
+// A function call highlights its first field argument.
+func matchedField(field string) string {
+	if e, err := filter.ParseExpr(field); err == nil {
+		for e.Func != "" {
+			e = e.Args[0]
+		}
+		field = e.Field
+	}
+	return strings.Replace(field, "[]", "", 1)
+}
+
+// span is a byte range of a string to mark.
+type span struct{ start, end int }
+
+// mark wraps every occurrence of texts in s with style.
+func mark(s string, texts []string, style string) string {
+	var spans []span
+	for _, t := range texts {
+		for from := 0; ; {
+			i := strings.Index(s[from:], t)
+			if i < 0 {
+				break
+			}
+			spans = append(spans, span{from + i, from + i + len(t)})
+			from += i + len(t)
+		}
+	}
+	return markSpans(s, spans, style)
+}
+
+// markSpans wraps spans of s with style. Of overlapping spans, the longer
+// is marked.
+func markSpans(s string, spans []span, style string) string {
+	if len(spans) == 0 {
+		return s
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].end-spans[i].start > spans[j].end-spans[j].start
+	})
+
+	covered := make([]bool, len(s))
+	for _, sp := range spans {
+		free := true
+		for j := sp.start; j < sp.end; j++ {
+			free = free && !covered[j]
+		}
+		if free {
+			for j := sp.start; j < sp.end; j++ {
+				covered[j] = true
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if covered[i] && (i == 0 || !covered[i-1]) {
+			b.WriteString(style)
+		}
+		b.WriteByte(s[i])
+		if covered[i] && (i == len(s)-1 || !covered[i+1]) {
+			b.WriteString(styleReset)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestHighlightRaw(t *testing.T) {
+	h := &Highlighter{Value: "<"}
+	tests := []struct {
+		raw     string
+		matched []parser.FieldMatch
+		want    string
+	}{
+		// Only the value of the matched field, not the same text elsewhere.
+		{`{"msg":"error in error handler","level":"error"}`, []parser.FieldMatch{{Field: "level", Text: "error"}},
+			`{"msg":"error in error handler","level":"<error` + styleReset + `"}`},
+		{`msg="error in error handler" level=error`, []parser.FieldMatch{{Field: "level", Text: "error"}},
+			`msg="error in error handler" level=<error` + styleReset},
+		{`{"msg":"error in error handler","level":"error"}`, []parser.FieldMatch{{Field: "msg", Text: "error"}},
+			`{"msg":"<error` + styleReset + ` in error handler","level":"error"}`},
+		// Nested and array fields are found by their last key.
+		{`{"id":7,"user":{"id":42},"code":42}`, []parser.FieldMatch{{Field: "user.id", Text: "42"}},
+			`{"id":7,"user":{"id":<42` + styleReset + `},"code":42}`},
+		{`{"name":"a","tags":["b","a"]}`, []parser.FieldMatch{{Field: "tags[]", Text: "a"}},
+			`{"name":"a","tags":["b","<a` + styleReset + `"]}`},
+		// Without a key in the line, the first whole word, else the first
+		// occurrence.
+		{`10.0.0.5 - - "GET /5005 HTTP/1.1" 500 5005`, []parser.FieldMatch{{Field: "status", Text: "500"}},
+			`10.0.0.5 - - "GET /5005 HTTP/1.1" <500` + styleReset + ` 5005`},
+		{`timeout timed out`, []parser.FieldMatch{{Field: "message", Text: "time"}},
+			`<time` + styleReset + `out timed out`},
+	}
+	for _, tt := range tests {
+		got := h.Raw(&parser.LogEntry{Raw: tt.raw, Matched: tt.matched})
+		if got != tt.want {
+			t.Errorf("Raw(%s, %v)\n got %s\nwant %s", tt.raw, tt.matched, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package output
+
+import "github.com/ishk9/flog/internal/parser"
+
+// IDFormatter prefixes each formatted entry with its stable EntryID and a
+// colon (--with-id), for citing findings that flog show can re-display
+// later. Entries without an ID are left unprefixed.
+type IDFormatter struct {
+	Formatter
+}
+
+// WithID wraps f to prefix entries with their ID.
+func WithID(f Formatter) *IDFormatter {
+	return &IDFormatter{Formatter: f}
+}
+
+// Format renders the entry with the wrapped formatter and prefixes it.
+func (f *IDFormatter) Format(entry *parser.LogEntry) string {
+	return string(f.AppendFormat(nil, entry))
+}
+
+// AppendFormat appends the prefixed entry to dst.
+func (f *IDFormatter) AppendFormat(dst []byte, entry *parser.LogEntry) []byte {
+	if entry.ID.File != "" {
+		dst = append(append(dst, entry.ID.String()...), ':')
+	}
+	return AppendFormat(dst, f.Formatter, entry)
+}
@@ -0,0 +1,132 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// DefaultJUnitLines caps the offending lines listed per failed file.
+const DefaultJUnitLines = 100
+
+// JUnitReport turns an --expect-zero assertion into a JUnit XML result for
+// CI (flog assert -o junit): one test case per input file, passing when no
+// line matched the filter and failing with the offending lines otherwise.
+type JUnitReport struct {
+	Filter   string
+	MaxLines int // Offending lines listed per file; 0 for DefaultJUnitLines
+
+	files []string
+	cases map[string]*junitResult
+}
+
+type junitResult struct {
+	count int
+	lines []string
+}
+
+// NewJUnitReport creates a report asserting that nothing matches filter.
+func NewJUnitReport(filter string) *JUnitReport {
+	return &JUnitReport{Filter: filter, cases: make(map[string]*junitResult)}
+}
+
+// AddFile registers an input file, so it is reported even without matches.
+func (r *JUnitReport) AddFile(file string) {
+	if _, ok := r.cases[file]; !ok {
+		r.files = append(r.files, file)
+		r.cases[file] = &junitResult{}
+	}
+}
+
+// Add records a matching, and therefore offending, entry. Entries are
+// attributed to their Source.
+func (r *JUnitReport) Add(entry *parser.LogEntry) {
+	r.AddFile(entry.Source)
+	res := r.cases[entry.Source]
+	res.count++
+	limit := r.MaxLines
+	if limit <= 0 {
+		limit = DefaultJUnitLines
+	}
+	if len(res.lines) < limit {
+		res.lines = append(res.lines, fmt.Sprintf("%d: %s", entry.LineNum, entry.Raw))
+	}
+}
+
+// Failed reports whether any file had a match, for the exit status.
+func (r *JUnitReport) Failed() bool {
+	for _, res := range r.cases {
+		if res.count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// Write renders the report as JUnit XML.
+func (r *JUnitReport) Write(w io.Writer) error {
+	suite := junitSuite{Name: "flog assert", Tests: len(r.files)}
+	for _, file := range r.files {
+		name := file
+		if name == "" || name == "-" {
+			name = StdinName
+		}
+		res := r.cases[file]
+		tc := junitCase{Name: fmt.Sprintf("%s: no lines match %s", name, r.Filter), ClassName: "flog"}
+		if res.count > 0 {
+			suite.Failures++
+			body := strings.Join(res.lines, "\n")
+			if more := res.count - len(res.lines); more > 0 {
+				body += fmt.Sprintf("\n... and %d more", more)
+			}
+			noun := "lines match"
+			if res.count == 1 {
+				noun = "line matches"
+			}
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d %s %s", res.count, noun, r.Filter),
+				Type:    "ExpectZero",
+				Body:    body,
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
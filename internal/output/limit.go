@@ -0,0 +1,79 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ishk9/flog/internal/aggregate"
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Limiter caps matches per input file (--limit-per-file) and per value of
+// a field (--limit-per-group N by field), so broad filters yield a sample
+// spread across files and keys instead of the first noisy source. Entries
+// without the group field share the aggregate.MissingKey group.
+type Limiter struct {
+	PerFile  int    // Matches kept per Source; 0 for no limit
+	PerGroup int    // Matches kept per GroupBy value; 0 for no limit
+	GroupBy  string // Field grouping PerGroup
+
+	files  map[string]int
+	groups map[string]int
+
+	Suppressed int64 // Entries dropped by a limit
+}
+
+// NewLimiter creates a Limiter. A perGroup limit needs a groupBy field.
+func NewLimiter(perFile, perGroup int, groupBy string) *Limiter {
+	return &Limiter{
+		PerFile:  perFile,
+		PerGroup: perGroup,
+		GroupBy:  groupBy,
+		files:    make(map[string]int),
+		groups:   make(map[string]int),
+	}
+}
+
+// ParseGroupLimit parses a --limit-per-group value such as "3 by user.id".
+func ParseGroupLimit(s string) (n int, field string, err error) {
+	count, field, ok := strings.Cut(strings.TrimSpace(s), " by ")
+	field = strings.TrimSpace(field)
+	if !ok || field == "" {
+		return 0, "", fmt.Errorf("output: group limit %q: want \"N by field\"", s)
+	}
+	n, err = strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n < 1 {
+		return 0, "", fmt.Errorf("output: group limit %q: invalid count", s)
+	}
+	return n, field, nil
+}
+
+// Keep reports whether entry is within the limits and should be output.
+// It is not safe for concurrent use; call it from the merger.
+func (l *Limiter) Keep(entry *parser.LogEntry) bool {
+	var group string
+	if l.PerGroup > 0 {
+		group = aggregate.MissingKey
+		if v, ok := entry.Fields[l.GroupBy]; ok {
+			group = filter.ToString(v)
+		}
+		if l.groups[group] >= l.PerGroup {
+			l.Suppressed++
+			return false
+		}
+	}
+	if l.PerFile > 0 && l.files[entry.Source] >= l.PerFile {
+		l.Suppressed++
+		return false
+	}
+
+	if l.PerGroup > 0 {
+		l.groups[group]++
+	}
+	if l.PerFile > 0 {
+		l.files[entry.Source]++
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// MetaFormatter renders each match as one JSON object wrapping its fields
+// with flog metadata (--output jsonl-meta), so records filtered from many
+// files can be traced back to where they came from:
+//
+//	{"source":"app.log","line":12,"id":"3fa9c1e2b4d5a6f7@1048","filter":"level:error","timestamp":"...","fields":{...}}
+//
+// The id is the entry's EntryID, omitted when unknown. The source is the entry's Source, or the formatter's when the entry has
+// none. The timestamp is the entry's normalized Timestamp, or its detected
+// time field, and is omitted when neither is known.
+type MetaFormatter struct {
+	Source string // Input file for entries without a Source; "-" for stdin
+	Filter string // Filter expression the entries matched
+}
+
+// NewMetaFormatter creates a MetaFormatter for entries matching filter.
+func NewMetaFormatter(filter string) *MetaFormatter {
+	return &MetaFormatter{Filter: filter}
+}
+
+type metaRecord struct {
+	Source    string         `json:"source,omitempty"`
+	Line      int            `json:"line"`
+	ID        string         `json:"id,omitempty"`
+	Filter    string         `json:"filter"`
+	Timestamp *time.Time     `json:"timestamp,omitempty"`
+	Fields    map[string]any `json:"fields"`
+}
+
+// Format converts a log entry to a single-line JSON envelope.
+func (f *MetaFormatter) Format(entry *parser.LogEntry) string {
+	return string(f.AppendFormat(nil, entry))
+}
+
+// AppendFormat appends the entry's JSON envelope to dst.
+func (f *MetaFormatter) AppendFormat(dst []byte, entry *parser.LogEntry) []byte {
+	rec := metaRecord{
+		Source: entry.Source,
+		Line:   entry.LineNum,
+		Filter: f.Filter,
+		Fields: JSONFields(entry.Fields),
+	}
+	if rec.Source == "" {
+		rec.Source = f.Source
+	}
+	if entry.ID.File != "" {
+		rec.ID = entry.ID.String()
+	}
+	t := entry.Timestamp
+	if t.IsZero() {
+		probe := parser.LogEntry{Fields: entry.Fields}
+		if parser.NormalizeTime(&probe, "") {
+			t = probe.Timestamp
+		}
+	}
+	if !t.IsZero() {
+		rec.Timestamp = &t
+	}
+
+	b := bytes.NewBuffer(dst)
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(rec); err != nil {
+		b.Truncate(len(dst))
+		enc.Encode(map[string]string{"error": err.Error(), "raw": entry.Raw})
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
+
+// JSONFields returns fields with values JSON cannot encode, such as NaN
+// parsed from a "NaN" log value, rendered as strings. The map is copied
+// only when needed.
+func JSONFields(fields map[string]any) map[string]any {
+	out, copied := fields, false
+	for k, v := range fields {
+		f, ok := v.(float64)
+		if !ok || (!math.IsNaN(f) && !math.IsInf(f, 0)) {
+			continue
+		}
+		if !copied {
+			out = make(map[string]any, len(fields))
+			for k2, v2 := range fields {
+				out[k2] = v2
+			}
+			copied = true
+		}
+		out[k] = filter.ToString(f)
+	}
+	return out
+}
@@ -19,12 +19,37 @@ type Formatter interface {
 	Format(entry *parser.LogEntry) string
 }
 
+// AppendFormatter is a Formatter that can also render into a caller's
+// buffer, so hot output paths reuse one buffer instead of allocating a
+// string per entry. Formatters emitting several lines per entry, or
+// binary encodings, should implement it.
+type AppendFormatter interface {
+	Formatter
+	// AppendFormat appends the rendered entry to dst and returns the
+	// extended buffer.
+	AppendFormat(dst []byte, entry *parser.LogEntry) []byte
+}
+
+// AppendFormat appends entry as rendered by f to dst, without an
+// intermediate string when f is an AppendFormatter.
+func AppendFormat(dst []byte, f Formatter, entry *parser.LogEntry) []byte {
+	if af, ok := f.(AppendFormatter); ok {
+		return af.AppendFormat(dst, entry)
+	}
+	return append(dst, f.Format(entry)...)
+}
+
 // Stats holds statistics about the filtering operation.
 type Stats struct {
 	TotalLines   int64            // Total lines processed
 	MatchedLines int64            // Lines that matched filters
 	ParseErrors  int64            // Lines that failed to parse
 	FieldCounts  map[string]int64 // Field occurrence counts (for --stats)
+
+	Fields []string // Fields to profile (--stats-fields); empty for all
+	TopN   int      // Values listed per field; 0 for DefaultTopValues
+
+	fields map[string]*FieldStat
 }
 
 // NewStats creates a new Stats instance with initialized maps.
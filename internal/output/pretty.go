@@ -0,0 +1,314 @@
+package output
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// PrettyTimeFormat is the layout of timestamps in pretty output.
+const PrettyTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// PrettyFormatter renders entries as compact lines for people to read
+// (--output pretty): the timestamp, the level and the message, then the
+// other fields as key=value in name order, colored by Theme. With Symbols,
+// lines start with the symbol of their level, or a blank of its width.
+// With Diff, values that changed since the previous entry of the same key
+// are styled as TokenChanged, or marked with a '*' before their name when
+// there is no theme.
+// With a Width,
+// lines longer than Width columns are cut with an ellipsis or, with Wrap,
+// broken at spaces onto continuation lines indented to the message.
+type PrettyFormatter struct {
+	Theme   *Theme            // Nil for no color
+	Symbols map[string]string // Lower-cased level → symbol; nil for none
+	Diff    *FieldDiff        // Nil to not mark changed values
+	Width   int               // Columns per line; 0 for no limit
+	Wrap    bool              // Wrap long lines rather than cut them
+}
+
+// NewPrettyFormatter creates a PrettyFormatter with theme, which may be
+// nil.
+func NewPrettyFormatter(theme *Theme) *PrettyFormatter {
+	return &PrettyFormatter{Theme: theme}
+}
+
+// Format implements Formatter.
+func (f *PrettyFormatter) Format(entry *parser.LogEntry) string {
+	return string(f.AppendFormat(nil, entry))
+}
+
+// AppendFormat implements AppendFormatter.
+func (f *PrettyFormatter) AppendFormat(dst []byte, entry *parser.LogEntry) []byte {
+	head := make(map[string]bool, 3) // Fields shown before the others
+	start, indent := len(dst), 2
+	space := func() {
+		if len(dst) > start {
+			dst = append(dst, ' ')
+		}
+	}
+	if f.Symbols != nil {
+		dst = f.symbol(dst, entry)
+	}
+	if ts, field := prettyTime(entry); ts != "" {
+		space()
+		dst = f.styled(dst, ts, f.Theme.token(TokenTime, ""))
+		head[field] = true
+	}
+	if key, level := levelOf(entry.Fields); key != "" {
+		space()
+		dst = f.styled(dst, padRight(strings.ToUpper(level), 5), f.Theme.level(level))
+		head[key] = true
+	}
+	key := parser.MessageField(entry.Fields)
+	if msg, ok := entry.Fields[key].(string); ok {
+		space()
+		if len(dst) > start {
+			indent = visibleWidth(dst[start:])
+		}
+		dst = f.styled(dst, msg, f.Theme.token(TokenString, key))
+		head[key] = true
+	}
+
+	var changed map[string]bool
+	if f.Diff != nil {
+		changed = f.Diff.Changed(entry)
+	}
+	for _, k := range sortedFields(entry.Fields) {
+		if head[k] {
+			continue
+		}
+		space()
+		if changed[k] && f.Theme == nil {
+			dst = append(dst, '*')
+		}
+		dst = f.styled(dst, k, f.Theme.token(TokenKey, ""))
+		dst = f.styled(dst, "=", f.Theme.token(TokenPunct, ""))
+		text, class := prettyValue(entry.Fields[k])
+		style := f.Theme.token(class, k)
+		if changed[k] {
+			style = f.Theme.token(TokenChanged, "") + style
+		}
+		dst = f.styled(dst, text, style)
+	}
+	if f.Width > 0 {
+		dst = append(dst[:start], f.fit(dst[start:], indent)...)
+	}
+	return dst
+}
+
+// fit lays line out in f.Width columns, cutting or wrapping each of its
+// lines, and keeps the styles in effect across the breaks it adds.
+// Continuation lines are indented by indent columns, at most half the
+// width.
+func (f *PrettyFormatter) fit(line []byte, indent int) []byte {
+	indent = min(indent, f.Width/2)
+	out := make([]byte, 0, len(line)+len(line)/f.Width*(indent+16))
+	style := "" // Escape sequence in effect
+	col, cut := 0, false
+	sp, spCol, spStyle := -1, 0, "" // Last space of the output line
+	breakLine := func(style string) {
+		if style != "" {
+			out = append(out, styleReset...)
+		}
+		out = append(out, '\n')
+		for range indent {
+			out = append(out, ' ')
+		}
+		out = append(out, style...)
+		col, sp = indent, -1
+	}
+
+	for i := 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			style = string(line[i : i+n])
+			if style == styleReset {
+				style = ""
+			}
+			if !cut {
+				out = append(out, line[i:i+n]...)
+			}
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRune(line[i:])
+		switch {
+		case r == '\n':
+			out = append(out, '\n')
+			if cut {
+				out = append(out, style...)
+			}
+			col, cut, sp = 0, false, -1
+			i += size
+			continue
+		case cut:
+			i += size
+			continue
+		case !f.Wrap && col == f.Width-1 && visibleWidth(line[i:]) > 1:
+			out = append(out, "…"...)
+			if style != "" {
+				out = append(out, styleReset...)
+			}
+			cut = true
+			i += size
+			continue
+		case f.Wrap && col >= f.Width:
+			if r == ' ' {
+				breakLine(style)
+				i += size
+				continue
+			}
+			if sp >= 0 && spCol > indent {
+				tail := bytes.Clone(out[sp+1:])
+				out = out[:sp]
+				breakLine(spStyle)
+				out = append(out, tail...)
+				col += visibleWidth(tail)
+			} else {
+				breakLine(style)
+			}
+		}
+		if r == ' ' {
+			sp, spCol, spStyle = len(out), col, style
+		}
+		out = append(out, line[i:i+size]...)
+		col++
+		i += size
+	}
+	return out
+}
+
+// escapeLen returns the length of the ANSI escape sequence b starts with,
+// or 0.
+func escapeLen(b []byte) int {
+	if len(b) < 2 || b[0] != '\x1b' || b[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// visibleWidth returns the columns b takes up to its first newline,
+// counting a rune as one column and escape sequences as none.
+func visibleWidth(b []byte) int {
+	n := 0
+	for i := 0; i < len(b); {
+		if e := escapeLen(b[i:]); e > 0 {
+			i += e
+			continue
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		if r == '\n' {
+			break
+		}
+		n++
+		i += size
+	}
+	return n
+}
+
+// symbol appends the symbol of the entry's level in the level's style, or
+// a space when the level has none.
+func (f *PrettyFormatter) symbol(dst []byte, entry *parser.LogEntry) []byte {
+	_, level := levelOf(entry.Fields)
+	if s, ok := f.Symbols[strings.ToLower(level)]; ok {
+		return f.styled(dst, s, f.Theme.level(level))
+	}
+	return append(dst, ' ')
+}
+
+// styled appends s in style.
+func (f *PrettyFormatter) styled(dst []byte, s, style string) []byte {
+	if style == "" {
+		return append(dst, s...)
+	}
+	dst = append(dst, style...)
+	dst = append(dst, s...)
+	return append(dst, styleReset...)
+}
+
+// prettyTime returns the entry's time and the field it came from: the
+// normalized Timestamp, else the detected time field as written.
+func prettyTime(entry *parser.LogEntry) (ts, field string) {
+	field, ok := parser.DetectTimeField(entry.Fields)
+	if !entry.Timestamp.IsZero() {
+		return entry.Timestamp.Format(PrettyTimeFormat), field
+	}
+	if !ok {
+		return "", ""
+	}
+	if t, ok := entry.Fields[field].(time.Time); ok {
+		return t.Format(PrettyTimeFormat), field
+	}
+	return filter.ToString(entry.Fields[field]), field
+}
+
+// levelOf returns the level field of fields and its value.
+func levelOf(fields map[string]any) (key, level string) {
+	for _, k := range filter.DefaultLevelFields {
+		if v, ok := fields[k].(string); ok && v != "" {
+			return k, v
+		}
+	}
+	return "", ""
+}
+
+// prettyValue renders a field value and returns its token class. Strings
+// are quoted when they would not read back as one value.
+func prettyValue(v any) (string, string) {
+	switch t := v.(type) {
+	case nil:
+		return "null", TokenNull
+	case bool:
+		return strconv.FormatBool(t), TokenBool
+	case int, int64, float64:
+		return filter.ToString(t), TokenNumber
+	case string:
+		if needsQuotes(t) {
+			return strconv.Quote(t), TokenString
+		}
+		return t, TokenString
+	case []string:
+		return "[" + strings.Join(t, ",") + "]", TokenString
+	}
+	return filter.ToString(v), TokenString
+}
+
+func needsQuotes(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == '"' || r == '=' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func padRight(s string, n int) string {
+	if len(s) >= n {
+		return s
+	}
+	return s + strings.Repeat(" ", n-len(s))
+}
+
+func sortedFields(fields map[string]any) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,172 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestPrettyFormat(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 5, 250e6, time.UTC)
+	tests := []struct {
+		entry *parser.LogEntry
+		want  string
+	}{
+		{&parser.LogEntry{Timestamp: ts, Fields: map[string]any{
+			"time": "2024-03-01T12:00:05.25Z", "level": "warn", "msg": "slow request",
+			"status": 200.0, "path": "/api/users", "cached": false, "user": nil,
+		}}, `2024-03-01T12:00:05.250Z WARN  slow request cached=false path=/api/users status=200 user=null`},
+		// Without a time or level, and with values that need quoting.
+		{&parser.LogEntry{Fields: map[string]any{
+			"message": "started", "error": "no such file", "empty": "", "tags": []string{"a", "b"},
+		}}, `started empty="" error="no such file" tags=[a,b]`},
+	}
+	f := NewPrettyFormatter(nil)
+	for _, tt := range tests {
+		if got := f.Format(tt.entry); got != tt.want {
+			t.Errorf("Format(%v)\n got %s\nwant %s", tt.entry.Fields, got, tt.want)
+		}
+	}
+}
+
+func TestPrettyFormatTheme(t *testing.T) {
+	theme, err := ThemeSpec{
+		Tokens: map[string]string{TokenKey: "cyan", TokenNumber: "magenta", TokenPunct: ""},
+		Levels: map[string]string{"error": "red"},
+		Fields: map[string]string{"user": "green"},
+	}.Theme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	theme.Tokens[TokenString] = ""
+	entry := &parser.LogEntry{Fields: map[string]any{"level": "ERROR", "msg": "failed", "code": 7.0, "user": "ann"}}
+	want := "\x1b[31mERROR" + styleReset + " failed " +
+		"\x1b[36mcode" + styleReset + "=\x1b[35m7" + styleReset + " " +
+		"\x1b[36muser" + styleReset + "=\x1b[32mann" + styleReset
+	if got := NewPrettyFormatter(theme).Format(entry); got != want {
+		t.Errorf("Format\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestPrettyFormatWidth(t *testing.T) {
+	entry := &parser.LogEntry{Fields: map[string]any{"level": "info", "msg": "request done", "path": "/api/users", "status": 200.0}}
+	tests := []struct {
+		width int
+		wrap  bool
+		want  string
+	}{
+		{0, false, "INFO  request done path=/api/users status=200"},
+		{80, true, "INFO  request done path=/api/users status=200"},
+		{20, false, "INFO  request done …"},
+		// Continuation lines start under the message.
+		{24, true, "INFO  request done\n      path=/api/users\n      status=200"},
+		// Words longer than a line are broken anywhere.
+		{12, true, "INFO  reques\n      t done\n      path=/\n      api/us\n      ers\n      status\n      =200"},
+	}
+	for _, tt := range tests {
+		f := &PrettyFormatter{Width: tt.width, Wrap: tt.wrap}
+		if got := f.Format(entry); got != tt.want {
+			t.Errorf("Format width %d wrap %v\n got %q\nwant %q", tt.width, tt.wrap, got, tt.want)
+		}
+	}
+}
+
+func TestPrettyFormatWidthKeepsStyles(t *testing.T) {
+	theme := &Theme{Tokens: map[string]string{TokenString: "\x1b[32m"}}
+	entry := &parser.LogEntry{Fields: map[string]any{"msg": "aaa bbb ccc"}}
+	want := "\x1b[32maaa bbb" + styleReset + "\n  \x1b[32mccc" + styleReset
+	if got := (&PrettyFormatter{Theme: theme, Width: 8, Wrap: true}).Format(entry); got != want {
+		t.Errorf("wrapped\n got %q\nwant %q", got, want)
+	}
+	want = "\x1b[32maaa bb…" + styleReset
+	if got := (&PrettyFormatter{Theme: theme, Width: 7}).Format(entry); got != want {
+		t.Errorf("cut\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestPrettyFormatSymbols(t *testing.T) {
+	symbols, err := LevelSymbols(SymbolsASCII)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &PrettyFormatter{Symbols: symbols}
+	tests := []struct {
+		fields map[string]any
+		want   string
+	}{
+		{map[string]any{"level": "ERROR", "msg": "failed"}, "x ERROR failed"},
+		{map[string]any{"level": "warning", "msg": "slow"}, "! WARNING slow"},
+		{map[string]any{"level": "debug", "msg": "tick"}, "  DEBUG tick"},
+		{map[string]any{"msg": "started"}, "  started"},
+	}
+	for _, tt := range tests {
+		if got := f.Format(&parser.LogEntry{Fields: tt.fields}); got != tt.want {
+			t.Errorf("Format(%v) = %q, want %q", tt.fields, got, tt.want)
+		}
+	}
+}
+
+func TestLevelSymbols(t *testing.T) {
+	tests := []struct {
+		mode, lcAll, lang string
+		want              string // Symbol of error
+	}{
+		{SymbolsUnicode, "C", "", "✖"},
+		{SymbolsASCII, "", "en_US.UTF-8", "x"},
+		{SymbolsAuto, "", "en_US.UTF-8", "✖"},
+		{SymbolsAuto, "", "de_DE.utf8", "✖"},
+		{SymbolsAuto, "C", "en_US.UTF-8", "x"},
+		{SymbolsAuto, "", "", "x"},
+	}
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_CTYPE", "")
+		t.Setenv("LANG", tt.lang)
+		symbols, err := LevelSymbols(tt.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := symbols["error"]; got != tt.want {
+			t.Errorf("LevelSymbols(%s) with LC_ALL=%q LANG=%q: error is %q, want %q", tt.mode, tt.lcAll, tt.lang, got, tt.want)
+		}
+	}
+	if symbols, err := LevelSymbols(SymbolsNone); err != nil || symbols != nil {
+		t.Errorf("LevelSymbols(none) = %v, %v", symbols, err)
+	}
+	if _, err := LevelSymbols("emoji"); err == nil {
+		t.Error("LevelSymbols(emoji) succeeded")
+	}
+}
+
+func TestPrettyFormatDiff(t *testing.T) {
+	f := &PrettyFormatter{Diff: NewFieldDiff("id")}
+	lines := []map[string]any{
+		{"id": "a", "state": "pending", "n": 1.0},
+		{"id": "b", "state": "pending", "n": 1.0},
+		{"id": "a", "state": "running", "n": 1.0},
+		{"id": "a", "state": "running", "n": 1.0, "node": "x"},
+		{"state": "done"},
+	}
+	want := []string{
+		"id=a n=1 state=pending",
+		"id=b n=1 state=pending",
+		"id=a n=1 *state=running",
+		"id=a n=1 *node=x state=running",
+		"state=done",
+	}
+	for i, fields := range lines {
+		if got := f.Format(&parser.LogEntry{Fields: fields}); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i+1, got, want[i])
+		}
+	}
+
+	// Without a key, against the previous entry, styled by the theme.
+	theme := &Theme{Tokens: map[string]string{TokenChanged: "\x1b[7m"}}
+	f = &PrettyFormatter{Theme: theme, Diff: NewFieldDiff("")}
+	f.Format(&parser.LogEntry{Fields: map[string]any{"id": "a", "n": 1.0}})
+	want1 := "id=\x1b[7mb" + styleReset + " n=1"
+	if got := f.Format(&parser.LogEntry{Fields: map[string]any{"id": "b", "n": 1.0}}); got != want1 {
+		t.Errorf("themed = %q, want %q", got, want1)
+	}
+}
@@ -0,0 +1,78 @@
+package output
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// RedactMask replaces the values of redacted fields.
+const RedactMask = "[REDACTED]"
+
+// RedactFormatter masks the values of Fields before the wrapped formatter
+// sees an entry (--redact password,user.email), so matching still reads
+// them but no output shows them. Fields nested under a listed one are
+// masked too. The raw line has every occurrence of a masked value
+// replaced, which may mask more than the field itself but never less.
+type RedactFormatter struct {
+	Formatter
+	Fields []string
+}
+
+// WithRedact wraps f to mask fields.
+func WithRedact(f Formatter, fields []string) *RedactFormatter {
+	return &RedactFormatter{Formatter: f, Fields: fields}
+}
+
+// Format renders the redacted entry with the wrapped formatter.
+func (f *RedactFormatter) Format(entry *parser.LogEntry) string {
+	return string(f.AppendFormat(nil, entry))
+}
+
+// AppendFormat appends the redacted entry to dst.
+func (f *RedactFormatter) AppendFormat(dst []byte, entry *parser.LogEntry) []byte {
+	return AppendFormat(dst, f.Formatter, Redact(entry, f.Fields))
+}
+
+// Redact returns entry with the values of fields masked, as a copy when
+// anything is masked; entry itself is left unchanged.
+func Redact(entry *parser.LogEntry, fields []string) *parser.LogEntry {
+	var c *parser.LogEntry
+	var values []string
+	for k, v := range entry.Fields {
+		if !redacted(k, fields) {
+			continue
+		}
+		if c == nil {
+			c = entry.Clone()
+		}
+		c.Fields[k] = RedactMask
+		if s := filter.ToString(v); s != "" {
+			values = append(values, s)
+		}
+	}
+	if c == nil {
+		return entry
+	}
+	// Longest first, so a value containing another is masked whole.
+	slices.SortFunc(values, func(a, b string) int { return len(b) - len(a) })
+	for _, s := range values {
+		c.Raw = strings.ReplaceAll(c.Raw, s, RedactMask)
+	}
+	c.Matched = slices.DeleteFunc(c.Matched, func(m parser.FieldMatch) bool {
+		return redacted(m.Field, fields)
+	})
+	return c
+}
+
+// redacted reports whether field is one of fields or nested under one.
+func redacted(field string, fields []string) bool {
+	for _, f := range fields {
+		if field == f || strings.HasPrefix(field, f) && (field[len(f)] == '.' || field[len(f)] == '[') {
+			return true
+		}
+	}
+	return false
+}
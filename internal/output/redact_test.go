@@ -0,0 +1,37 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestRedact(t *testing.T) {
+	line := `{"user":{"email":"a@b.c","name":"ann"},"password":"hunter2","msg":"login by a@b.c"}`
+	entry, err := parser.NewJSONParser().Parse(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Matched = []parser.FieldMatch{{Field: "user.email", Text: "a@b.c"}, {Field: "msg", Text: "login"}}
+
+	got := Redact(entry, []string{"user.email", "password"})
+	want := `{"user":{"email":"[REDACTED]","name":"ann"},"password":"[REDACTED]","msg":"login by [REDACTED]"}`
+	if got.Raw != want {
+		t.Errorf("Raw = %s, want %s", got.Raw, want)
+	}
+	if got.Fields["user.email"] != RedactMask || got.Fields["password"] != RedactMask || got.Fields["user.name"] != "ann" {
+		t.Errorf("Fields = %v", got.Fields)
+	}
+	if len(got.Matched) != 1 || got.Matched[0].Field != "msg" {
+		t.Errorf("Matched = %v, want only msg", got.Matched)
+	}
+	if entry.Raw != line || entry.Fields["password"] != "hunter2" {
+		t.Error("Redact modified its argument")
+	}
+	if Redact(entry, []string{"user.emailx", "pass"}) != entry {
+		t.Error("Redact copied an entry with nothing to mask")
+	}
+	if got := Redact(entry, []string{"user"}); got.Fields["user.name"] != RedactMask {
+		t.Errorf("nested field not masked: %v", got.Fields)
+	}
+}
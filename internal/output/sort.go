@@ -0,0 +1,331 @@
+package output
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+	"github.com/ishk9/flog/internal/workdir"
+)
+
+// DefaultSortMemory is the approximate amount of entry data a Sorter keeps
+// in memory before spilling a sorted run to disk.
+const DefaultSortMemory = 256 << 20
+
+// entryOverhead approximates the per-entry and per-field memory beyond
+// the raw line, for spill accounting.
+const (
+	entryOverhead = 128
+	fieldOverhead = 64
+)
+
+func init() {
+	// Field value types beyond gob's builtins that parsers produce.
+	gob.Register([]string{})
+	gob.Register(time.Time{})
+}
+
+// SortKey is a --sort value: a field and a direction.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSortKey parses field[:asc|desc], e.g. "duration:desc".
+func ParseSortKey(s string) (SortKey, error) {
+	field, dir, _ := strings.Cut(strings.TrimSpace(s), ":")
+	if field == "" {
+		return SortKey{}, fmt.Errorf("sort: missing field in %q", s)
+	}
+	switch dir {
+	case "", "asc":
+		return SortKey{Field: field}, nil
+	case "desc":
+		return SortKey{Field: field, Desc: true}, nil
+	}
+	return SortKey{}, fmt.Errorf("sort: direction must be asc or desc, got %q", dir)
+}
+
+// Sorter buffers matched entries and emits them ordered by a field.
+// Numbers compare numerically and timestamps chronologically; other values
+// compare as strings, after numbers and times. Entries without the field
+// come last in either direction, and ties keep input order.
+//
+// When the buffered entries exceed MaxMemory they are sorted and spilled
+// to a temporary file, and Each merges the runs.
+type Sorter struct {
+	Key       SortKey
+	MaxMemory int64        // Spill threshold in bytes; 0 never spills
+	TempDir   string       // Directory for spill files; empty for os.TempDir
+	WorkDir   *workdir.Dir // When set, spill to its spill directory within its quota
+
+	buf  []*parser.LogEntry
+	mem  int64
+	runs []string // Spill files, in input order
+}
+
+// NewSorter creates a Sorter spilling above maxMemory bytes.
+func NewSorter(key SortKey, maxMemory int64) *Sorter {
+	return &Sorter{Key: key, MaxMemory: maxMemory}
+}
+
+// Add buffers an entry, spilling to disk when over the memory threshold.
+func (s *Sorter) Add(entry *parser.LogEntry) error {
+	s.buf = append(s.buf, entry)
+	s.mem += int64(entryOverhead + len(entry.Raw) + fieldOverhead*len(entry.Fields))
+	if s.MaxMemory > 0 && s.mem >= s.MaxMemory {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill writes the sorted buffer to a new run file.
+func (s *Sorter) spill() error {
+	s.sortBuffer()
+
+	dir := s.TempDir
+	if s.WorkDir != nil {
+		if err := s.WorkDir.Check(s.mem); err != nil {
+			return fmt.Errorf("sort: %w", err)
+		}
+		var err error
+		if dir, err = s.WorkDir.Path(workdir.Spill); err != nil {
+			return fmt.Errorf("sort: %w", err)
+		}
+	}
+	f, err := os.CreateTemp(dir, "flog-sort-*")
+	if err != nil {
+		return fmt.Errorf("sort: %w", err)
+	}
+	s.runs = append(s.runs, f.Name())
+
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	for _, e := range s.buf {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return fmt.Errorf("sort: spilling entry %d: %w", e.LineNum, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("sort: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("sort: %w", err)
+	}
+
+	s.buf = nil
+	s.mem = 0
+	return nil
+}
+
+func (s *Sorter) sortBuffer() {
+	keys := make([]sortValue, len(s.buf))
+	for i, e := range s.buf {
+		keys[i] = s.keyOf(e)
+	}
+	sort.Stable(byKey{entries: s.buf, keys: keys, desc: s.Key.Desc})
+}
+
+// Each calls fn with the entries in sorted order, stopping at the first
+// error. Spill files are removed afterwards.
+func (s *Sorter) Each(fn func(*parser.LogEntry) error) error {
+	defer s.Close()
+	s.sortBuffer()
+	if len(s.runs) == 0 {
+		for _, e := range s.buf {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	h := &mergeHeap{desc: s.Key.Desc}
+	for i, path := range s.runs {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("sort: %w", err)
+		}
+		defer f.Close()
+		dec := gob.NewDecoder(bufio.NewReader(f))
+		src := &mergeSource{order: i, next: func() (*parser.LogEntry, error) {
+			e := &parser.LogEntry{}
+			if err := dec.Decode(e); err != nil {
+				return nil, err
+			}
+			if e.Fields == nil {
+				e.Fields = make(map[string]any) // gob omits empty maps
+			}
+			return e, nil
+		}}
+		if err := h.push(s, src); err != nil {
+			return err
+		}
+	}
+	pos := 0
+	mem := &mergeSource{order: len(s.runs), next: func() (*parser.LogEntry, error) {
+		if pos == len(s.buf) {
+			return nil, io.EOF
+		}
+		pos++
+		return s.buf[pos-1], nil
+	}}
+	if err := h.push(s, mem); err != nil {
+		return err
+	}
+
+	for h.Len() > 0 {
+		src := h.sources[0]
+		if err := fn(src.entry); err != nil {
+			return err
+		}
+		heap.Pop(h)
+		if err := h.push(s, src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close removes any spill files. It is safe to call more than once.
+func (s *Sorter) Close() {
+	for _, path := range s.runs {
+		os.Remove(path)
+	}
+	s.runs = nil
+}
+
+// Value kinds, in ascending sort order.
+const (
+	kindNumber = iota
+	kindTime
+	kindString
+	kindMissing
+)
+
+// sortValue is an entry's precomputed sort key.
+type sortValue struct {
+	kind int
+	num  float64
+	at   time.Time
+	str  string
+}
+
+func (s *Sorter) keyOf(e *parser.LogEntry) sortValue {
+	var v any
+	var ok bool
+	if s.Key.Field == filter.TimestampField && !e.Timestamp.IsZero() {
+		v, ok = e.Timestamp, true
+	} else {
+		v, ok = e.Fields[s.Key.Field]
+	}
+	if !ok || v == nil {
+		return sortValue{kind: kindMissing}
+	}
+	if t, ok := v.(time.Time); ok {
+		return sortValue{kind: kindTime, at: t}
+	}
+	if f, ok := filter.ToFloat(v); ok {
+		return sortValue{kind: kindNumber, num: f}
+	}
+	if str, ok := v.(string); ok {
+		if t, ok := parser.ParseTime(str); ok {
+			return sortValue{kind: kindTime, at: t}
+		}
+	}
+	return sortValue{kind: kindString, str: filter.ToString(v)}
+}
+
+// compareValues orders a and b, reversing within a kind for descending
+// sorts so that missing values stay last.
+func compareValues(a, b sortValue, desc bool) int {
+	if a.kind != b.kind {
+		return a.kind - b.kind
+	}
+	var c int
+	switch a.kind {
+	case kindNumber:
+		switch {
+		case a.num < b.num:
+			c = -1
+		case a.num > b.num:
+			c = 1
+		}
+	case kindTime:
+		c = a.at.Compare(b.at)
+	case kindString:
+		c = strings.Compare(a.str, b.str)
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+type byKey struct {
+	entries []*parser.LogEntry
+	keys    []sortValue
+	desc    bool
+}
+
+func (b byKey) Len() int           { return len(b.entries) }
+func (b byKey) Less(i, j int) bool { return compareValues(b.keys[i], b.keys[j], b.desc) < 0 }
+func (b byKey) Swap(i, j int) {
+	b.entries[i], b.entries[j] = b.entries[j], b.entries[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// mergeSource is one sorted run being merged.
+type mergeSource struct {
+	order int // Run position in input; breaks ties to keep the sort stable
+	next  func() (*parser.LogEntry, error)
+	entry *parser.LogEntry
+	key   sortValue
+}
+
+type mergeHeap struct {
+	sources []*mergeSource
+	desc    bool
+}
+
+// push advances src to its next entry and adds it to the heap, or drops it
+// when the run is exhausted.
+func (h *mergeHeap) push(s *Sorter, src *mergeSource) error {
+	e, err := src.next()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("sort: reading spill: %w", err)
+	}
+	src.entry, src.key = e, s.keyOf(e)
+	heap.Push(h, src)
+	return nil
+}
+
+func (h *mergeHeap) Len() int { return len(h.sources) }
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.sources[i], h.sources[j]
+	if c := compareValues(a.key, b.key, h.desc); c != 0 {
+		return c < 0
+	}
+	return a.order < b.order
+}
+func (h *mergeHeap) Swap(i, j int) { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+func (h *mergeHeap) Push(x any)    { h.sources = append(h.sources, x.(*mergeSource)) }
+func (h *mergeHeap) Pop() any {
+	src := h.sources[len(h.sources)-1]
+	h.sources = h.sources[:len(h.sources)-1]
+	return src
+}
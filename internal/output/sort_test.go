@@ -0,0 +1,100 @@
+package output
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// sortEntries adds an entry per value, with Raw naming its position, and
+// returns the Raw of each entry in sorted order.
+func sortEntries(t *testing.T, s *Sorter, values []any) string {
+	t.Helper()
+	for i, v := range values {
+		e := &parser.LogEntry{Raw: string(rune('a' + i)), Fields: map[string]any{}}
+		if v != nil {
+			e.Fields["v"] = v
+		}
+		if err := s.Add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got strings.Builder
+	if err := s.Each(func(e *parser.LogEntry) error {
+		got.WriteString(e.Raw)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return got.String()
+}
+
+func TestSorter(t *testing.T) {
+	mixed := []any{"beta", int64(10), nil, "2024-01-02T00:00:00Z", 2.5, "alpha", nil, "2023-06-01T00:00:00Z", int64(-1)}
+	tests := []struct {
+		name   string
+		key    string
+		values []any
+		want   string
+	}{
+		{"numbers", "v", []any{int64(3), 1.5, "2", int64(-4)}, "dbca"},
+		// Numbers, then times, then strings; missing values last.
+		{"mixed kinds", "v", mixed, "iebhdfacg"},
+		// Descending reverses within each kind, missing values stay last.
+		{"mixed kinds desc", "v:desc", mixed, "beidhafcg"},
+		{"desc missing", "v:desc", []any{nil, int64(1), nil, int64(2)}, "dbac"},
+		// Ties keep input order in both directions.
+		{"stable", "v", []any{int64(1), int64(0), int64(1), int64(0), int64(1)}, "bdace"},
+		{"stable desc", "v:desc", []any{int64(1), int64(0), int64(1), int64(0), int64(1)}, "acebd"},
+	}
+	for _, tt := range tests {
+		key, err := ParseSortKey(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Spilling after every entry merges one run per entry.
+		for _, mem := range []int64{0, 1} {
+			dir := t.TempDir()
+			s := NewSorter(key, mem)
+			s.TempDir = dir
+			if got := sortEntries(t, s, tt.values); got != tt.want {
+				t.Errorf("%s (spill %v): order %s, want %s", tt.name, mem > 0, got, tt.want)
+			}
+			if files, _ := os.ReadDir(dir); len(files) != 0 {
+				t.Errorf("%s: %d spill files left", tt.name, len(files))
+			}
+		}
+	}
+}
+
+func TestSorterSpillKeepsFields(t *testing.T) {
+	key, _ := ParseSortKey("n:desc")
+	s := NewSorter(key, 300)
+	s.TempDir = t.TempDir()
+	for i := range 50 {
+		e := &parser.LogEntry{Raw: "x", LineNum: i + 1, Fields: map[string]any{"n": int64(i % 7), "tags": []string{"a"}}}
+		if err := s.Add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.runs) < 2 {
+		t.Fatalf("%d runs, want several", len(s.runs))
+	}
+	prev, last := int64(7), 0
+	err := s.Each(func(e *parser.LogEntry) error {
+		n := e.Fields["n"].(int64)
+		if n > prev || n == prev && e.LineNum < last {
+			t.Errorf("line %d (n=%d) after line %d (n=%d)", e.LineNum, n, last, prev)
+		}
+		if tags, _ := e.Fields["tags"].([]string); len(tags) != 1 {
+			t.Errorf("line %d: tags %v after spilling", e.LineNum, e.Fields["tags"])
+		}
+		prev, last = n, e.LineNum
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
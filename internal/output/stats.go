@@ -0,0 +1,221 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ishk9/flog/internal/aggregate"
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// DefaultTopValues is the number of most frequent values listed per field.
+const DefaultTopValues = 5
+
+// maxTrackedValues caps the exact value counts kept per field. Beyond it
+// the distinct count comes from a HyperLogLog sketch, and top values are
+// ranked among the values tracked so far.
+const maxTrackedValues = 10000
+
+// ValueCount is a field value and the number of entries that had it.
+type ValueCount struct {
+	Value string
+	Count int64
+}
+
+// FieldStat profiles the values of one field across matched entries.
+type FieldStat struct {
+	Field   string
+	Count   int64   // Entries containing the field
+	Numeric int64   // Values that were numeric
+	Min     float64 // Smallest numeric value
+	Max     float64 // Largest numeric value
+	Sum     float64 // Sum of numeric values
+
+	values map[string]int64
+	hll    *aggregate.HyperLogLog // Set once values overflows
+}
+
+func (f *FieldStat) add(v any) {
+	if values, ok := v.([]string); ok {
+		for _, s := range values {
+			f.addValue(s)
+		}
+		return
+	}
+	f.addValue(filter.ToString(v))
+
+	// Only native numbers count; numeric-looking strings such as IDs don't.
+	switch v.(type) {
+	case float64, int64, int:
+		n, _ := filter.ToFloat(v)
+		if f.Numeric == 0 || n < f.Min {
+			f.Min = n
+		}
+		if f.Numeric == 0 || n > f.Max {
+			f.Max = n
+		}
+		f.Sum += n
+		f.Numeric++
+	}
+}
+
+func (f *FieldStat) addValue(s string) {
+	if f.hll != nil {
+		f.hll.Add(s)
+	}
+	if _, ok := f.values[s]; ok || len(f.values) < maxTrackedValues {
+		f.values[s]++
+		return
+	}
+	if f.hll == nil {
+		f.hll = aggregate.NewHyperLogLog()
+		for v := range f.values {
+			f.hll.Add(v)
+		}
+		f.hll.Add(s)
+	}
+}
+
+// Distinct returns the number of distinct values, and whether it is a
+// HyperLogLog estimate.
+func (f *FieldStat) Distinct() (uint64, bool) {
+	if f.hll != nil {
+		return f.hll.Count(), true
+	}
+	return uint64(len(f.values)), false
+}
+
+// Avg returns the mean of the numeric values, or NaN when there were none.
+func (f *FieldStat) Avg() float64 {
+	if f.Numeric == 0 {
+		return math.NaN()
+	}
+	return f.Sum / float64(f.Numeric)
+}
+
+// Top returns the n most frequent values, by count (descending) then value.
+func (f *FieldStat) Top(n int) []ValueCount {
+	top := make([]ValueCount, 0, len(f.values))
+	for v, c := range f.values {
+		top = append(top, ValueCount{Value: v, Count: c})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Value < top[j].Value
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// ParseStatsFields parses a --stats-fields value such as "level,status".
+func ParseStatsFields(s string) []string {
+	var fields []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Observe records a matched entry in the field statistics. Only the
+// selected Fields are profiled, or every field when none are selected.
+// It is not safe for concurrent use; feed it from the pipeline's merger.
+func (s *Stats) Observe(entry *parser.LogEntry) {
+	s.MatchedLines++
+	if len(s.Fields) > 0 {
+		for _, name := range s.Fields {
+			if v, ok := entry.Fields[name]; ok {
+				s.observeField(name, v)
+			}
+		}
+		return
+	}
+	for name, v := range entry.Fields {
+		s.observeField(name, v)
+	}
+}
+
+func (s *Stats) observeField(name string, v any) {
+	if s.fields == nil {
+		s.fields = make(map[string]*FieldStat)
+	}
+	f, ok := s.fields[name]
+	if !ok {
+		f = &FieldStat{Field: name, values: make(map[string]int64)}
+		s.fields[name] = f
+	}
+	f.Count++
+	s.FieldCounts[name]++
+	f.add(v)
+}
+
+// FieldStats returns the profiled fields: in --stats-fields order when
+// fields were selected, otherwise by occurrence count (descending), then
+// name. Selected fields that never occurred are reported with zero counts.
+func (s *Stats) FieldStats() []*FieldStat {
+	if len(s.Fields) > 0 {
+		out := make([]*FieldStat, len(s.Fields))
+		for i, name := range s.Fields {
+			if out[i] = s.fields[name]; out[i] == nil {
+				out[i] = &FieldStat{Field: name}
+			}
+		}
+		return out
+	}
+	out := make([]*FieldStat, 0, len(s.fields))
+	for _, f := range s.fields {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Field < out[j].Field
+	})
+	return out
+}
+
+// Render writes the run totals followed by one row per field: occurrence
+// count, distinct values (prefixed with ~ when estimated), numeric
+// min/max/avg and the TopN most frequent values.
+func (s *Stats) Render(w io.Writer) error {
+	topN := s.TopN
+	if topN <= 0 {
+		topN = DefaultTopValues
+	}
+
+	if _, err := fmt.Fprintf(w, "lines: %d  matched: %d  parse errors: %d\n\n", s.TotalLines, s.MatchedLines, s.ParseErrors); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "field\tcount\tdistinct\tmin\tmax\tavg\ttop")
+	for _, f := range s.FieldStats() {
+		n, approx := f.Distinct()
+		distinct := fmt.Sprint(n)
+		if approx {
+			distinct = "~" + distinct
+		}
+		lo, hi := math.NaN(), math.NaN()
+		if f.Numeric > 0 {
+			lo, hi = f.Min, f.Max
+		}
+		var top []string
+		for _, v := range f.Top(topN) {
+			top = append(top, fmt.Sprintf("%s(%d)", v.Value, v.Count))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", f.Field, f.Count, distinct,
+			aggregate.FormatValue(lo), aggregate.FormatValue(hi), aggregate.FormatValue(f.Avg()),
+			strings.Join(top, " "))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Modes of level symbols in pretty output (--symbols).
+const (
+	SymbolsAuto    = "auto"    // Unicode when the locale is UTF-8, else ASCII
+	SymbolsUnicode = "unicode" // ✖ error, ⚠ warn, ℹ info
+	SymbolsASCII   = "ascii"   // x error, ! warn, i info
+	SymbolsNone    = "none"    // No symbols
+)
+
+// levelSymbols maps lower-cased levels to their Unicode and ASCII symbols.
+var levelSymbols = map[string][2]string{
+	"error": {"✖", "x"}, "err": {"✖", "x"}, "fatal": {"✖", "x"}, "critical": {"✖", "x"}, "panic": {"✖", "x"},
+	"warn": {"⚠", "!"}, "warning": {"⚠", "!"},
+	"info": {"ℹ", "i"},
+}
+
+// LevelSymbols returns the symbols of mode by lower-cased level, for
+// PrettyFormatter.Symbols; nil for SymbolsNone.
+func LevelSymbols(mode string) (map[string]string, error) {
+	ascii := false
+	switch mode {
+	case SymbolsNone:
+		return nil, nil
+	case SymbolsAuto:
+		ascii = !utf8Locale()
+	case SymbolsUnicode:
+	case SymbolsASCII:
+		ascii = true
+	default:
+		return nil, fmt.Errorf("output: unknown symbols %q (want auto, unicode, ascii or none)", mode)
+	}
+	symbols := make(map[string]string, len(levelSymbols))
+	for level, s := range levelSymbols {
+		if ascii {
+			symbols[level] = s[1]
+		} else {
+			symbols[level] = s[0]
+		}
+	}
+	return symbols, nil
+}
+
+// utf8Locale reports whether the locale set in the environment uses UTF-8,
+// taking the first of LC_ALL, LC_CTYPE and LANG that is set.
+func utf8Locale() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// TemplateFormatter renders each entry with a Go text/template
+// (--template), for full control over the line layout:
+//
+//	{{.timestamp}} [{{.level | upper}}] {{.message | trunc 80}}
+//
+// Fields are available by name, and dotted fields also nested, so
+// {{.user.id}} and {{index . "user.id"}} both work. The entry itself is
+// exposed as _raw, _line, _source and, when known, _timestamp. Helpers:
+// upper, lower, trunc N, date LAYOUT (of a time or a parseable time
+// string), default VALUE (for missing or empty values) and json.
+type TemplateFormatter struct {
+	tmpl *template.Template
+
+	Errors int64 // Entries the template failed on, output raw instead
+}
+
+// NewTemplateFormatter parses text as an output template.
+func NewTemplateFormatter(text string) (*TemplateFormatter, error) {
+	f := &TemplateFormatter{}
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("output: template: %w", err)
+	}
+	f.tmpl = tmpl
+	return f, nil
+}
+
+// Format renders the entry, or returns its raw line if the template fails.
+func (f *TemplateFormatter) Format(entry *parser.LogEntry) string {
+	return string(f.AppendFormat(nil, entry))
+}
+
+// AppendFormat appends the rendered entry, or its raw line if the
+// template fails, to dst.
+func (f *TemplateFormatter) AppendFormat(dst []byte, entry *parser.LogEntry) []byte {
+	b := bytes.NewBuffer(dst)
+	if err := f.tmpl.Execute(b, templateData(entry)); err != nil {
+		f.Errors++
+		return append(dst, entry.Raw...)
+	}
+	return b.Bytes()
+}
+
+// templateData builds the template's view of an entry.
+func templateData(entry *parser.LogEntry) map[string]any {
+	data := make(map[string]any, len(entry.Fields)+4)
+	for k, v := range entry.Fields {
+		data[k] = v
+	}
+	for k, v := range entry.Fields {
+		nest(data, k, v)
+	}
+	data["_raw"] = entry.Raw
+	data["_line"] = entry.LineNum
+	data["_source"] = entry.Source
+	if !entry.Timestamp.IsZero() {
+		data[filter.TimestampField] = entry.Timestamp
+	}
+	return data
+}
+
+// nest stores v under the dotted path key as nested maps, unless a
+// segment is already taken by a non-map value.
+func nest(data map[string]any, key string, v any) {
+	head, rest, ok := strings.Cut(key, ".")
+	if !ok || head == "" || rest == "" {
+		return
+	}
+	child, exists := data[head].(map[string]any)
+	if !exists {
+		if _, taken := data[head]; taken {
+			return
+		}
+		child = make(map[string]any)
+		data[head] = child
+	}
+	if _, taken := child[rest]; !taken {
+		child[rest] = v
+	}
+	nest(child, rest, v)
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": func(v any) string { return strings.ToUpper(templateString(v)) },
+	"lower": func(v any) string { return strings.ToLower(templateString(v)) },
+	"trunc": func(n int, v any) string {
+		s := templateString(v)
+		if n < 0 || utf8.RuneCountInString(s) <= n {
+			return s
+		}
+		return string([]rune(s)[:n])
+	},
+	"date": func(layout string, v any) string {
+		if t, ok := v.(time.Time); ok {
+			return t.Format(layout)
+		}
+		if t, ok := parser.ParseTime(v); ok {
+			return t.Format(layout)
+		}
+		return templateString(v)
+	},
+	"default": func(def, v any) any {
+		if v == nil || templateString(v) == "" {
+			return def
+		}
+		return v
+	},
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// templateString renders a value as text; nil is empty.
+func templateString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return filter.ToString(v)
+}
@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package output
+
+import "os"
+
+// ResizeSignals is empty: terminal size changes are not signaled on this
+// platform.
+var ResizeSignals []os.Signal
+
+// TerminalWidth is not supported on this platform and always reports that
+// f is not a terminal.
+func TerminalWidth(f *os.File) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package output
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ResizeSignals are the signals sent when the terminal changes size.
+var ResizeSignals = []os.Signal{syscall.SIGWINCH}
+
+// TerminalWidth returns the number of columns of the terminal f is, and
+// false when f is not a terminal.
+func TerminalWidth(f *os.File) (int, bool) {
+	var ws struct{ Row, Col, X, Y uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.Col == 0 {
+		return 0, false
+	}
+	return int(ws.Col), true
+}
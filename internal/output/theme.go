@@ -0,0 +1,233 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Token classes a Theme colors in pretty output.
+const (
+	TokenKey     = "key"     // Field names
+	TokenString  = "string"  // String values
+	TokenNumber  = "number"  // Numeric values
+	TokenBool    = "bool"    // true and false
+	TokenNull    = "null"    // Missing and null values
+	TokenTime    = "time"    // The entry's timestamp
+	TokenPunct   = "punct"   // = and brackets
+	TokenChanged = "changed" // Added to values that changed (see FieldDiff)
+)
+
+// tokenClasses lists the token classes, for validation.
+var tokenClasses = []string{TokenKey, TokenString, TokenNumber, TokenBool, TokenNull, TokenTime, TokenPunct, TokenChanged}
+
+// Theme holds the ANSI styles of pretty output (--theme): one per token
+// class, per level value and per field name. A field's style applies to
+// its value and wins over the token class; a level's applies to the level
+// of entries with that level. Empty styles leave text uncolored.
+type Theme struct {
+	Tokens map[string]string // Token class → escape sequence
+	Levels map[string]string // Lower-cased level → escape sequence
+	Fields map[string]string // Field name → escape sequence for its value
+}
+
+// ThemeSpec defines a theme in the config file, over a built-in base:
+//
+//	themes:
+//	  mine:
+//	    base: solarized
+//	    tokens: {key: "bold blue", number: "36"}
+//	    levels: {error: "bold white on red"}
+//	    fields: {user: magenta}
+//
+// Styles are color and attribute names (see ParseStyle) or SGR codes.
+type ThemeSpec struct {
+	Base   string            `yaml:"base"` // Built-in theme; default "dark"
+	Tokens map[string]string `yaml:"tokens"`
+	Levels map[string]string `yaml:"levels"`
+	Fields map[string]string `yaml:"fields"`
+}
+
+// builtinThemes are the themes selectable by name, as specs over no base.
+var builtinThemes = map[string]ThemeSpec{
+	"dark": {
+		Tokens: map[string]string{
+			TokenKey: "cyan", TokenString: "", TokenNumber: "bright-magenta", TokenBool: "yellow",
+			TokenNull: "bright-black", TokenTime: "bright-black", TokenPunct: "bright-black", TokenChanged: "reverse",
+		},
+		Levels: map[string]string{
+			"trace": "bright-black", "debug": "blue", "info": "green", "warn": "bold yellow",
+			"warning": "bold yellow", "error": "bold red", "err": "bold red", "fatal": "bold white on red",
+			"critical": "bold white on red", "panic": "bold white on red",
+		},
+	},
+	"light": {
+		Tokens: map[string]string{
+			TokenKey: "blue", TokenString: "", TokenNumber: "magenta", TokenBool: "yellow",
+			TokenNull: "black", TokenTime: "black", TokenPunct: "black", TokenChanged: "reverse",
+		},
+		Levels: map[string]string{
+			"trace": "black", "debug": "blue", "info": "green", "warn": "bold yellow",
+			"warning": "bold yellow", "error": "bold red", "err": "bold red", "fatal": "bold white on red",
+			"critical": "bold white on red", "panic": "bold white on red",
+		},
+	},
+	"solarized": {
+		Tokens: map[string]string{
+			TokenKey: "38;5;33", TokenString: "38;5;37", TokenNumber: "38;5;125", TokenBool: "38;5;136",
+			TokenNull: "38;5;244", TokenTime: "38;5;244", TokenPunct: "38;5;244", TokenChanged: "48;5;230",
+		},
+		Levels: map[string]string{
+			"trace": "38;5;244", "debug": "38;5;61", "info": "38;5;64", "warn": "1;38;5;136",
+			"warning": "1;38;5;136", "error": "1;38;5;160", "err": "1;38;5;160", "fatal": "1;38;5;230;48;5;160",
+			"critical": "1;38;5;230;48;5;160", "panic": "1;38;5;230;48;5;160",
+		},
+	},
+}
+
+// ThemeNames returns the names of the built-in themes, sorted.
+func ThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupTheme returns the built-in theme called name, or nil for "none".
+func LookupTheme(name string) (*Theme, error) {
+	if name == "none" {
+		return nil, nil
+	}
+	spec, ok := builtinThemes[name]
+	if !ok {
+		return nil, fmt.Errorf("output: unknown theme %q (want %s or none)", name, strings.Join(ThemeNames(), ", "))
+	}
+	return spec.build(nil)
+}
+
+// Theme builds the theme s defines.
+func (s ThemeSpec) Theme() (*Theme, error) {
+	base := s.Base
+	if base == "" {
+		base = "dark"
+	}
+	b, ok := builtinThemes[base]
+	if !ok {
+		return nil, fmt.Errorf("output: unknown base theme %q (want %s)", base, strings.Join(ThemeNames(), ", "))
+	}
+	t, err := b.build(nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.build(t)
+}
+
+// build parses the styles of s over those of t, or of no theme when t is
+// nil.
+func (s ThemeSpec) build(t *Theme) (*Theme, error) {
+	if t == nil {
+		t = &Theme{Tokens: make(map[string]string), Levels: make(map[string]string), Fields: make(map[string]string)}
+	}
+	for class, style := range s.Tokens {
+		if !slices.Contains(tokenClasses, class) {
+			return nil, fmt.Errorf("output: unknown token class %q (want %s)", class, strings.Join(tokenClasses, ", "))
+		}
+		esc, err := ParseStyle(style)
+		if err != nil {
+			return nil, err
+		}
+		t.Tokens[class] = esc
+	}
+	for level, style := range s.Levels {
+		esc, err := ParseStyle(style)
+		if err != nil {
+			return nil, err
+		}
+		t.Levels[strings.ToLower(level)] = esc
+	}
+	for field, style := range s.Fields {
+		esc, err := ParseStyle(style)
+		if err != nil {
+			return nil, err
+		}
+		t.Fields[field] = esc
+	}
+	return t, nil
+}
+
+// styleCodes maps attribute and foreground color names to SGR codes.
+// Background colors are the foreground code plus 10.
+var styleCodes = map[string]int{
+	"bold": 1, "dim": 2, "italic": 3, "underline": 4, "reverse": 7,
+	"black": 30, "red": 31, "green": 32, "yellow": 33, "blue": 34, "magenta": 35, "cyan": 36, "white": 37,
+	"bright-black": 90, "bright-red": 91, "bright-green": 92, "bright-yellow": 93,
+	"bright-blue": 94, "bright-magenta": 95, "bright-cyan": 96, "bright-white": 97,
+}
+
+// ParseStyle turns a style such as "bold red", "white on red" or an SGR
+// code list such as "1;38;5;160" into an ANSI escape sequence. An empty
+// style yields "".
+func ParseStyle(style string) (string, error) {
+	style = strings.TrimSpace(style)
+	if style == "" {
+		return "", nil
+	}
+	if isSGR(style) {
+		return "\x1b[" + style + "m", nil
+	}
+	var codes []string
+	background := false
+	for _, word := range strings.Fields(strings.ToLower(style)) {
+		if word == "on" {
+			background = true
+			continue
+		}
+		code, ok := styleCodes[word]
+		if !ok || (background && code < 30) {
+			return "", fmt.Errorf("output: style %q: unknown color %q", style, word)
+		}
+		if background {
+			code += 10
+			background = false
+		}
+		codes = append(codes, strconv.Itoa(code))
+	}
+	if background {
+		return "", fmt.Errorf("output: style %q: no color after \"on\"", style)
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m", nil
+}
+
+// isSGR reports whether s is a list of numeric SGR parameters.
+func isSGR(s string) bool {
+	for _, part := range strings.Split(s, ";") {
+		if _, err := strconv.ParseUint(part, 10, 8); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// token returns the style for a value of class, or of field when the
+// theme styles it.
+func (t *Theme) token(class, field string) string {
+	if t == nil {
+		return ""
+	}
+	if style, ok := t.Fields[field]; ok && field != "" {
+		return style
+	}
+	return t.Tokens[class]
+}
+
+// level returns the style for the level value v.
+func (t *Theme) level(v string) string {
+	if t == nil {
+		return ""
+	}
+	return t.Levels[strings.ToLower(v)]
+}
@@ -0,0 +1,75 @@
+package output
+
+import "testing"
+
+func TestParseStyle(t *testing.T) {
+	tests := []struct {
+		style, want string
+	}{
+		{"", ""},
+		{"red", "\x1b[31m"},
+		{"Bold Red", "\x1b[1;31m"},
+		{"bold white on red", "\x1b[1;37;41m"},
+		{"bright-black on bright-white", "\x1b[90;107m"},
+		{"1;38;5;160", "\x1b[1;38;5;160m"},
+	}
+	for _, tt := range tests {
+		got, err := ParseStyle(tt.style)
+		if err != nil {
+			t.Errorf("ParseStyle(%q): %v", tt.style, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStyle(%q) = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"mauve", "white on", "on bold", "1;x"} {
+		if _, err := ParseStyle(bad); err == nil {
+			t.Errorf("ParseStyle(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestThemeSpec(t *testing.T) {
+	theme, err := ThemeSpec{
+		Tokens: map[string]string{TokenKey: "bold"},
+		Levels: map[string]string{"ERROR": "red"},
+		Fields: map[string]string{"user": "green"},
+	}.Theme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := theme.token(TokenKey, ""); got != "\x1b[1m" {
+		t.Errorf("key style = %q", got)
+	}
+	if got := theme.token(TokenBool, ""); got != "\x1b[33m" {
+		t.Errorf("bool style = %q, want the dark base's", got)
+	}
+	if got := theme.token(TokenString, "user"); got != "\x1b[32m" {
+		t.Errorf("user value style = %q", got)
+	}
+	if got := theme.level("error"); got != "\x1b[31m" {
+		t.Errorf("error level style = %q", got)
+	}
+
+	if _, err := (ThemeSpec{Base: "neon"}).Theme(); err == nil {
+		t.Error("unknown base accepted")
+	}
+	if _, err := (ThemeSpec{Tokens: map[string]string{"keys": "red"}}).Theme(); err == nil {
+		t.Error("unknown token class accepted")
+	}
+}
+
+func TestLookupTheme(t *testing.T) {
+	for _, name := range ThemeNames() {
+		if theme, err := LookupTheme(name); err != nil || theme == nil {
+			t.Errorf("LookupTheme(%q) = %v, %v", name, theme, err)
+		}
+	}
+	if theme, err := LookupTheme("none"); err != nil || theme != nil {
+		t.Errorf("LookupTheme(none) = %v, %v, want no theme", theme, err)
+	}
+	if _, err := LookupTheme("neon"); err == nil {
+		t.Error("LookupTheme(neon) succeeded")
+	}
+}
@@ -0,0 +1,160 @@
+package output
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// writeBufferSize is the buffer used between formatters and the sink.
+const writeBufferSize = 256 * 1024
+
+// Writer buffers formatted results on their way to stdout or a file.
+//
+// Files are written atomically: output goes to a temporary file in the
+// same directory that replaces the target on Close, so readers never see
+// a half-written result. In append mode the target is extended in place.
+// Targets ending in .gz are gzip-compressed and targets ending in .zst
+// zstd-compressed; appending adds a new gzip member or zstd frame, which
+// readers concatenate transparently.
+type Writer struct {
+	buf  *bufio.Writer
+	enc  encoder // Compressor, if any
+	file *os.File
+	path string // Final path when writing through a temp file
+
+	scratch []byte // Reused by WriteEntry
+}
+
+// encoder is a compressing stream: gzip or zstd.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// NewWriter wraps an existing stream such as os.Stdout.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{buf: bufio.NewWriterSize(w, writeBufferSize)}
+}
+
+// CreateFile opens path for output (--output-file), appending when
+// appendMode is set (--append).
+func CreateFile(path string, appendMode bool) (*Writer, error) {
+	w := &Writer{}
+
+	var err error
+	if appendMode {
+		w.file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	} else {
+		w.file, err = os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+		w.path = path
+	}
+	if err != nil {
+		return nil, err
+	}
+	if w.path != "" {
+		// CreateTemp uses 0600; match what a plain create would give.
+		if err := w.file.Chmod(0o644); err != nil {
+			w.Abort()
+			return nil, err
+		}
+	}
+
+	var sink io.Writer = w.file
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		w.enc = gzip.NewWriter(w.file)
+		sink = w.enc
+	case strings.HasSuffix(path, ".zst"):
+		if w.enc, err = zstd.NewWriter(w.file); err != nil {
+			w.Abort()
+			return nil, err
+		}
+		sink = w.enc
+	}
+	w.buf = bufio.NewWriterSize(sink, writeBufferSize)
+	return w, nil
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// WriteLine writes s followed by a newline.
+func (w *Writer) WriteLine(s string) error {
+	if _, err := w.buf.WriteString(s); err != nil {
+		return err
+	}
+	return w.buf.WriteByte('\n')
+}
+
+// WriteEntry writes entry as rendered by f, followed by a newline. With an
+// AppendFormatter, the rendering goes through a reused buffer and costs
+// no allocation of its own.
+func (w *Writer) WriteEntry(f Formatter, entry *parser.LogEntry) error {
+	w.scratch = append(AppendFormat(w.scratch[:0], f, entry), '\n')
+	_, err := w.buf.Write(w.scratch)
+	return err
+}
+
+// Flush pushes buffered output to the underlying stream (not to the final
+// file location; that happens on Close).
+func (w *Writer) Flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.enc != nil {
+		return w.enc.Flush()
+	}
+	return nil
+}
+
+// Close flushes all output and, for atomic file writes, moves the
+// temporary file into place. On error the temporary file is removed.
+func (w *Writer) Close() error {
+	err := w.buf.Flush()
+	if w.enc != nil {
+		if cerr := w.enc.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if w.file == nil {
+		return err
+	}
+
+	if err == nil {
+		err = w.file.Sync()
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+
+	if w.path == "" {
+		return err
+	}
+	if err == nil {
+		err = os.Rename(w.file.Name(), w.path)
+	}
+	if err != nil {
+		os.Remove(w.file.Name())
+	}
+	return err
+}
+
+// Abort discards an atomic file write without touching the target. It is
+// a no-op for streams and append mode, where data may already be visible.
+func (w *Writer) Abort() {
+	if w.file == nil || w.path == "" {
+		return
+	}
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
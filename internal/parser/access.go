@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// accessLogPattern matches Common Log Format with the optional Combined
+// Log Format referer and user-agent suffix.
+var accessLogPattern = regexp.MustCompile(
+	`^(\S+) (\S+) (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}|-) (\d+|-)` +
+		`(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+// ErrNotAccessLog is returned when a line is not in Common/Combined format.
+var ErrNotAccessLog = errors.New("parser: not an access log line")
+
+// AccessLogParser parses Apache/Nginx access logs in Common and Combined
+// Log Format into remote_addr, remote_user, time, method, path, protocol,
+// status, bytes, referer and user_agent fields. Absent values ("-") are
+// omitted.
+type AccessLogParser struct{}
+
+// NewAccessLogParser creates a new AccessLogParser.
+func NewAccessLogParser() *AccessLogParser {
+	return &AccessLogParser{}
+}
+
+// CanParse checks if the line looks like a Common/Combined access log line.
+func (p *AccessLogParser) CanParse(line string) bool {
+	return accessLogPattern.MatchString(line)
+}
+
+// Parse converts an access log line into a LogEntry.
+func (p *AccessLogParser) Parse(line string) (*LogEntry, error) {
+	m := accessLogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, ErrNotAccessLog
+	}
+
+	entry := NewLogEntry(line, 0)
+	setString(entry, "remote_addr", m[1])
+	setString(entry, "remote_user", m[3])
+	setString(entry, "time", m[4])
+
+	request := unescapeQuoted(m[5])
+	if parts := strings.Fields(request); len(parts) == 3 {
+		entry.Fields["method"] = parts[0]
+		entry.Fields["path"] = parts[1]
+		entry.Fields["protocol"] = parts[2]
+	} else {
+		setString(entry, "request", request)
+	}
+
+	setInt(entry, "status", m[6])
+	setInt(entry, "bytes", m[7])
+	setString(entry, "referer", unescapeQuoted(m[8]))
+	setString(entry, "user_agent", unescapeQuoted(m[9]))
+
+	return entry, nil
+}
+
+// setString stores value unless it is empty or the "-" placeholder.
+func setString(entry *LogEntry, key, value string) {
+	if value != "" && value != "-" {
+		entry.Fields[key] = value
+	}
+}
+
+// setInt stores value as int64 unless it is absent or not a number.
+func setInt(entry *LogEntry, key, value string) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		entry.Fields[key] = n
+	}
+}
+
+// unescapeQuoted resolves the backslash escapes Apache and Nginx emit
+// inside quoted fields.
+func unescapeQuoted(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAccessLogParser(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]any
+	}{
+		// Common Log Format.
+		{`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`, map[string]any{
+			"remote_addr": "127.0.0.1", "remote_user": "frank", "time": "10/Oct/2000:13:55:36 -0700",
+			"method": "GET", "path": "/apache_pb.gif", "protocol": "HTTP/1.0", "status": int64(200), "bytes": int64(2326),
+		}},
+		// Combined Log Format; "-" values are left out.
+		{`10.0.0.5 - - [01/Mar/2024:12:00:05 +0000] "POST /api/login HTTP/1.1" 302 - "https://example.com/" "Mozilla/5.0 (X11)"`, map[string]any{
+			"remote_addr": "10.0.0.5", "time": "01/Mar/2024:12:00:05 +0000",
+			"method": "POST", "path": "/api/login", "protocol": "HTTP/1.1", "status": int64(302),
+			"referer": "https://example.com/", "user_agent": "Mozilla/5.0 (X11)",
+		}},
+		// Requests that are not "method path protocol", with escapes.
+		{`::1 - - [01/Mar/2024:12:00:05 +0000] "\x16\x03\"bad\"" 400 0 "-" "-"`, map[string]any{
+			"remote_addr": "::1", "time": "01/Mar/2024:12:00:05 +0000",
+			"request": `x16x03"bad"`, "status": int64(400), "bytes": int64(0),
+		}},
+		{`1.2.3.4 - - [01/Mar/2024:12:00:05 +0000] "-" - -`, map[string]any{
+			"remote_addr": "1.2.3.4", "time": "01/Mar/2024:12:00:05 +0000",
+		}},
+	}
+	p := NewAccessLogParser()
+	for _, tt := range tests {
+		if !p.CanParse(tt.line) {
+			t.Errorf("CanParse(%q) = false", tt.line)
+			continue
+		}
+		entry, err := p.Parse(tt.line)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(entry.Fields, tt.want) {
+			t.Errorf("Parse(%q)\n got %#v\nwant %#v", tt.line, entry.Fields, tt.want)
+		}
+	}
+}
+
+func TestAccessLogParserRejects(t *testing.T) {
+	p := NewAccessLogParser()
+	for _, line := range []string{
+		`level=info msg=started`,
+		`{"status":200}`,
+		`127.0.0.1 - - 10/Oct/2000:13:55:36 "GET / HTTP/1.0" 200 1`,
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" 2000 1`,
+	} {
+		if p.CanParse(line) {
+			t.Errorf("CanParse(%q) = true", line)
+		}
+		if _, err := p.Parse(line); err != ErrNotAccessLog {
+			t.Errorf("Parse(%q): err = %v, want ErrNotAccessLog", line, err)
+		}
+	}
+}
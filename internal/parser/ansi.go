@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// StripANSI removes ANSI escape sequences (CSI colors, OSC titles and
+// single-character escapes) and stray C0 control bytes from a line so that
+// TTY-captured logs can be detected and parsed like plain text. The 8-bit
+// CSI introducer 0x9b is honoured only in lines that are not valid UTF-8,
+// where it cannot be part of a character (as in ě, "\xc4\x9b").
+func StripANSI(line string) string {
+	if !hasControl(line) {
+		return line
+	}
+	c1 := !utf8.ValidString(line)
+
+	var b strings.Builder
+	b.Grow(len(line))
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == 0x1b:
+			i = skipEscape(line, i)
+		case c == 0x9b && c1: // 8-bit CSI
+			i = skipCSI(line, i+1)
+		case c < 0x20 && c != '\t':
+			// Drop other control bytes (BEL, backspace, CR, ...).
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// hasControl reports whether line contains any byte StripANSI would remove.
+func hasControl(line string) bool {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c < 0x20 && c != '\t' || c == 0x9b && !utf8.ValidString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipEscape returns the index of the last byte of the escape sequence
+// starting at line[i] (which must be ESC).
+func skipEscape(line string, i int) int {
+	if i+1 >= len(line) {
+		return i
+	}
+	switch line[i+1] {
+	case '[':
+		return skipCSI(line, i+2)
+	case ']', 'P', '_', '^':
+		return skipString(line, i+2)
+	default:
+		// Two-byte escape such as ESC c or ESC =.
+		return i + 1
+	}
+}
+
+// skipCSI skips CSI parameter and intermediate bytes up to and including the
+// final byte (0x40-0x7e).
+func skipCSI(line string, i int) int {
+	for ; i < len(line); i++ {
+		if c := line[i]; c >= 0x40 && c <= 0x7e {
+			return i
+		}
+	}
+	return len(line) - 1
+}
+
+// skipString skips an OSC/DCS/APC/PM payload terminated by BEL or ESC \.
+func skipString(line string, i int) int {
+	for ; i < len(line); i++ {
+		switch line[i] {
+		case 0x07:
+			return i
+		case 0x1b:
+			if i+1 < len(line) && line[i+1] == '\\' {
+				return i + 1
+			}
+		}
+	}
+	return len(line) - 1
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain line", "plain line"},
+		{"\x1b[1;31merror\x1b[0m done", "error done"},
+		{"\x1b]0;title\x07level=info", "level=info"},
+		{"\x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"a\tb\x07c\bd", "a\tbcd"},
+		// 0x9b inside UTF-8 characters is not a CSI.
+		{"name=ě level=info", "name=ě level=info"},
+		{"\x1b[32mname=ě\x1b[0m level=info", "name=ě level=info"},
+		{"日本語 ok", "日本語 ok"},
+		// In a line that is not UTF-8 (Latin-1 here), it is one.
+		{"caf\xe9 \x9b1mbold\x9b0m", "caf\xe9 bold"},
+	}
+	for _, tt := range tests {
+		if got := StripANSI(tt.in); got != tt.want {
+			t.Errorf("StripANSI(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReaderStripANSI(t *testing.T) {
+	input := "\x1b[33m{\"level\":\"warn\",\"who\":\"Zdeněk\"}\x1b[0m\n" +
+		"\x1b[31mERROR first\x1b[0m\n  \x1b[2mat main()\x1b[0m\n"
+	r := NewStreamReader()
+	r.SetStripANSI(true)
+	r.SetMultiline(nil)
+	var got []string
+	if err := r.ScanLines(strings.NewReader(input), func(line string) bool {
+		got = append(got, line)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`{"level":"warn","who":"Zdeněk"}`, "ERROR first\n  at main()"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
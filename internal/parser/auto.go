@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnknownFormat is returned when no registered parser accepts a line.
+var ErrUnknownFormat = errors.New("parser: unknown log format")
+
+// AutoParser detects the format of each line by trying its parsers in
+// order and using the first one whose CanParse accepts the line.
+type AutoParser struct {
+	parsers []Parser
+}
+
+// NewAutoParser creates an AutoParser. Without arguments it uses the
+// default order: JSON, access log, CEF, LEEF, then logfmt. CEF and LEEF
+// come before logfmt, whose key=value detection their extensions satisfy.
+func NewAutoParser(parsers ...Parser) *AutoParser {
+	if len(parsers) == 0 {
+		parsers = []Parser{
+			NewJSONParser(),
+			NewAccessLogParser(),
+			NewCEFParser(),
+			NewLEEFParser(),
+			NewLogfmtParser(),
+		}
+	}
+	return &AutoParser{parsers: parsers}
+}
+
+// Register appends a parser to the detection order.
+func (p *AutoParser) Register(parser Parser) {
+	p.parsers = append(p.parsers, parser)
+}
+
+// CanParse checks if any registered parser can handle the line.
+func (p *AutoParser) CanParse(line string) bool {
+	return p.detect(line) != nil
+}
+
+// Parse converts a line using the first parser that accepts it. For a
+// multiline record, the format is detected on the first line and the
+// continuation lines are appended to the entry's message; a record whose
+// first line is plain text becomes a single message field. A multiline
+// record holding a whole JSON object, such as one split by a record
+// separator, is parsed as a unit. A trailing carriage return, left by
+// callers that split CRLF input themselves, is ignored.
+func (p *AutoParser) Parse(line string) (*LogEntry, error) {
+	line = strings.TrimRight(line, "\r")
+	head, rest, multiline := strings.Cut(line, "\n")
+	if multiline && looksLikeJSON(line) {
+		if parser := p.detect(line); parser != nil {
+			if entry, err := parser.Parse(line); err == nil {
+				return entry, nil
+			}
+		}
+	}
+
+	parser := p.detect(head)
+	if parser == nil {
+		if !multiline {
+			return nil, ErrUnknownFormat
+		}
+		entry := NewLogEntry(line, 0)
+		entry.Fields["message"] = line
+		return entry, nil
+	}
+	entry, err := parser.Parse(head)
+	if err != nil || !multiline {
+		return entry, err
+	}
+
+	entry.Raw = line
+	key := MessageField(entry.Fields)
+	if msg, ok := entry.Fields[key].(string); ok && msg != "" {
+		entry.Fields[key] = msg + "\n" + rest
+	} else {
+		entry.Fields[key] = rest
+	}
+	return entry, nil
+}
+
+// MessageFields lists the field names treated as the entry's message.
+var MessageFields = []string{"message", "msg", "log"}
+
+// MessageField returns the name of the entry's message field, defaulting
+// to "message".
+func MessageField(fields map[string]any) string {
+	for _, name := range MessageFields {
+		if _, ok := fields[name]; ok {
+			return name
+		}
+	}
+	return "message"
+}
+
+func (p *AutoParser) detect(line string) Parser {
+	for _, parser := range p.parsers {
+		if parser.CanParse(line) {
+			return parser
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Object-store clients used for s3://bucket/key, gs://bucket/key and
+// az://account/container/blob inputs. They discover credentials the usual
+// way (environment variables, profiles, instance metadata), so flog needs
+// no configuration of its own.
+var (
+	AWSCommand    = "aws"
+	GCloudCommand = "gcloud"
+	AzureCommand  = "az"
+)
+
+// blobStore describes how to read and list one object store through its
+// command-line client. The bucket of an Azure URL is account/container.
+type blobStore struct {
+	command *string
+	cat     func(bucket, key string) []string
+	list    func(bucket, prefix string) []string
+	// object maps a line of list output to the object's key, if any.
+	object func(bucket, line string) (string, bool)
+}
+
+// s3ListLine matches `aws s3 ls --recursive` output: date, time, size, key.
+var s3ListLine = regexp.MustCompile(`^\S+ \S+\s+\d+ (.+)$`)
+
+var blobStores = map[string]blobStore{
+	"s3": {
+		command: &AWSCommand,
+		cat: func(bucket, key string) []string {
+			return []string{"s3", "cp", "--only-show-errors", "s3://" + bucket + "/" + key, "-"}
+		},
+		list: func(bucket, prefix string) []string {
+			return []string{"s3", "ls", "--recursive", "s3://" + bucket + "/" + prefix}
+		},
+		object: func(_, line string) (string, bool) {
+			m := s3ListLine.FindStringSubmatch(line)
+			if m == nil {
+				return "", false
+			}
+			return m[1], true
+		},
+	},
+	"gs": {
+		command: &GCloudCommand,
+		cat: func(bucket, key string) []string {
+			return []string{"storage", "cat", "gs://" + bucket + "/" + key}
+		},
+		list: func(bucket, prefix string) []string {
+			return []string{"storage", "ls", "gs://" + bucket + "/" + prefix + "**"}
+		},
+		object: func(bucket, line string) (string, bool) {
+			key, ok := strings.CutPrefix(line, "gs://"+bucket+"/")
+			return key, ok && key != "" && !strings.HasSuffix(key, "/")
+		},
+	},
+	"az": {
+		command: &AzureCommand,
+		cat: func(bucket, key string) []string {
+			account, container, _ := strings.Cut(bucket, "/")
+			return []string{"storage", "blob", "download", "--account-name", account,
+				"--container-name", container, "--name", key,
+				"--file", "/dev/stdout", "--no-progress", "--output", "none"}
+		},
+		list: func(bucket, prefix string) []string {
+			account, container, _ := strings.Cut(bucket, "/")
+			return []string{"storage", "blob", "list", "--account-name", account,
+				"--container-name", container, "--prefix", prefix,
+				"--num-results", "*", "--query", "[].name", "--output", "tsv"}
+		},
+		object: func(_, line string) (string, bool) {
+			return line, line != ""
+		},
+	},
+}
+
+// IsBlob reports whether path names an object in a cloud object store.
+func IsBlob(path string) bool {
+	scheme, _, ok := strings.Cut(path, "://")
+	_, known := blobStores[scheme]
+	return ok && known
+}
+
+// parseBlob splits an object URL into its store, bucket and key.
+func parseBlob(url string) (store blobStore, scheme, bucket, key string, err error) {
+	scheme, rest, _ := strings.Cut(url, "://")
+	store = blobStores[scheme]
+	parts := 2
+	if scheme == "az" {
+		parts = 3 // account/container/blob
+	}
+	segs := strings.SplitN(rest, "/", parts)
+	if len(segs) < parts || slices.Contains(segs[:parts-1], "") {
+		return store, "", "", "", fmt.Errorf("input %q: want %s", url, blobUsage(scheme))
+	}
+	return store, scheme, strings.Join(segs[:parts-1], "/"), segs[parts-1], nil
+}
+
+func blobUsage(scheme string) string {
+	if scheme == "az" {
+		return "az://account/container/blob"
+	}
+	return scheme + "://bucket/key"
+}
+
+// openBlob streams an object through its store's client.
+func openBlob(url string) (io.ReadCloser, error) {
+	store, scheme, bucket, key, err := parseBlob(url)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("input %q: want %s", url, blobUsage(scheme))
+	}
+	return startRemote(url, *store.command, store.cat(bucket, key))
+}
+
+// ListBlobs expands an object URL ending in "/" (every object under the
+// prefix; nested ones only when recursive) or containing glob patterns in
+// its key (matched as by path.Match, so "*" stays within one level) into
+// the sorted URLs of the matching objects.
+func ListBlobs(url string, recursive bool) ([]string, error) {
+	store, scheme, bucket, key, err := parseBlob(url)
+	if err != nil {
+		return nil, err
+	}
+	prefix, pattern := key, ""
+	if i := strings.IndexAny(key, "*?["); i >= 0 {
+		prefix, pattern = key[:i], key
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("input %q: %w", url, err)
+		}
+	}
+
+	out, err := exec.Command(*store.command, store.list(bucket, prefix)...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			err = errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("input %q: %w", url, err)
+	}
+
+	var urls []string
+	for _, line := range strings.Split(string(out), "\n") {
+		obj, ok := store.object(bucket, strings.TrimRight(line, "\r"))
+		if !ok || !strings.HasPrefix(obj, prefix) || strings.HasSuffix(obj, "/") {
+			continue
+		}
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, obj); !ok {
+				continue
+			}
+		} else if !recursive && strings.Contains(obj[len(prefix):], "/") {
+			continue
+		}
+		urls = append(urls, scheme+"://"+bucket+"/"+obj)
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
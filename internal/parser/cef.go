@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotCEF is returned when a line is not an ArcSight CEF event.
+var ErrNotCEF = errors.New("parser: not a CEF event")
+
+// cefHeaderFields names the pipe-separated header fields after "CEF:".
+var cefHeaderFields = []string{"cef_version", "vendor", "product", "product_version", "signature_id", "name", "severity"}
+
+// CEFParser parses ArcSight Common Event Format events:
+//
+//	CEF:0|Vendor|Product|1.0|100|Worm stopped|10|src=10.0.0.1 msg=Stopped worm
+//
+// Header fields become cef_version, vendor, product, product_version,
+// signature_id, name and severity; extension pairs keep their keys
+// (src, dst, spt, ...). Severity and extension values are type-inferred;
+// the other header fields stay strings. A syslog header before
+// "CEF:" is kept in syslog_header.
+type CEFParser struct{}
+
+// NewCEFParser creates a new CEFParser.
+func NewCEFParser() *CEFParser {
+	return &CEFParser{}
+}
+
+// CanParse checks if the line carries a CEF header.
+func (p *CEFParser) CanParse(line string) bool {
+	_, body, ok := cutSecurityPrefix(line, "CEF:")
+	return ok && len(splitHeader(body, len(cefHeaderFields))) == len(cefHeaderFields)+1
+}
+
+// Parse converts a CEF event into a LogEntry.
+func (p *CEFParser) Parse(line string) (*LogEntry, error) {
+	prefix, body, ok := cutSecurityPrefix(line, "CEF:")
+	if !ok {
+		return nil, ErrNotCEF
+	}
+	parts := splitHeader(body, len(cefHeaderFields))
+	if len(parts) != len(cefHeaderFields)+1 {
+		return nil, ErrNotCEF
+	}
+
+	entry := NewLogEntry(line, 0)
+	setString(entry, "syslog_header", prefix)
+	for i, name := range cefHeaderFields {
+		if v := unescapeHeader(parts[i]); v != "" {
+			entry.Fields[name] = v
+		}
+	}
+	if sev, ok := entry.Fields["severity"].(string); ok {
+		entry.Fields["severity"] = InferType(sev)
+	}
+	parseCEFExtension(parts[len(cefHeaderFields)], entry.Fields)
+	return entry, nil
+}
+
+// cutSecurityPrefix finds marker ("CEF:" or "LEEF:") at the start of line
+// or after a syslog header, and returns the trimmed header and the text
+// following the marker.
+func cutSecurityPrefix(line, marker string) (prefix, body string, ok bool) {
+	i := strings.Index(line, marker)
+	if i < 0 || (i > 0 && line[i-1] != ' ') {
+		return "", "", false
+	}
+	body = line[i+len(marker):]
+	if body == "" || body[0] < '0' || body[0] > '9' {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), body, true
+}
+
+// splitHeader splits s at its first n unescaped pipes. The remainder,
+// which may contain unescaped pipes, is the last element.
+func splitHeader(s string, n int) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s) && len(parts) < n; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '|':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if len(parts) < n {
+		return parts
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeHeader resolves \| and \\ in a header field.
+func unescapeHeader(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return unescapeQuoted(s)
+}
+
+// parseCEFExtension stores the key=value pairs of a CEF extension. Values
+// may contain spaces, so each runs until the word before the next
+// unescaped '='.
+func parseCEFExtension(s string, fields map[string]any) {
+	s = strings.TrimSpace(s)
+	key, valueStart := "", -1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '=':
+			keyStart := strings.LastIndexByte(s[:i], ' ') + 1
+			if valueStart >= 0 && keyStart <= valueStart {
+				continue // '=' inside the previous value
+			}
+			if valueStart >= 0 {
+				setExtension(fields, key, s[valueStart:keyStart])
+			}
+			key, valueStart = s[keyStart:i], i+1
+		}
+	}
+	if valueStart >= 0 {
+		setExtension(fields, key, s[valueStart:])
+	}
+}
+
+func setExtension(fields map[string]any, key, value string) {
+	if key == "" {
+		return
+	}
+	value = unescapeExtension(strings.TrimRight(value, " "))
+	if value != "" {
+		fields[key] = InferType(value)
+	}
+}
+
+// unescapeExtension resolves \=, \\, \n and \r in an extension value.
+func unescapeExtension(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+				continue
+			case 'r':
+				b.WriteByte('\r')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCEFParser(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]any
+	}{
+		{`CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232`, map[string]any{
+			"cef_version": "0", "vendor": "Security", "product": "threatmanager", "product_version": "1.0",
+			"signature_id": "100", "name": "worm successfully stopped", "severity": int64(10),
+			"src": "10.0.0.1", "dst": "2.1.2.2", "spt": int64(1232),
+		}},
+		// Values with spaces, escaped '=', '\' and newlines; escaped pipes
+		// in the header; a syslog header before the event.
+		{`Sep 19 08:26:10 host CEF:0|Ven\|dor|Prod|2|sig|name|High|msg=Detected a threat. No action needed act=blocked a\=b cs1=C:\\temp\nx`, map[string]any{
+			"syslog_header": "Sep 19 08:26:10 host", "cef_version": "0", "vendor": "Ven|dor", "product": "Prod",
+			"product_version": "2", "signature_id": "sig", "name": "name", "severity": "High",
+			"msg": "Detected a threat. No action needed", "act": `blocked a=b`, "cs1": "C:\\temp\nx",
+		}},
+		// An empty extension and empty header fields.
+		{`CEF:1|V|P||1|n|3|`, map[string]any{
+			"cef_version": "1", "vendor": "V", "product": "P", "signature_id": "1", "name": "n", "severity": int64(3),
+		}},
+	}
+	p := NewCEFParser()
+	for _, tt := range tests {
+		if !p.CanParse(tt.line) {
+			t.Errorf("CanParse(%q) = false", tt.line)
+			continue
+		}
+		entry, err := p.Parse(tt.line)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(entry.Fields, tt.want) {
+			t.Errorf("Parse(%q)\n got %#v\nwant %#v", tt.line, entry.Fields, tt.want)
+		}
+	}
+}
+
+func TestCEFParserRejects(t *testing.T) {
+	p := NewCEFParser()
+	for _, line := range []string{
+		`CEF:0|Security|threatmanager|1.0|100|worm stopped`,
+		`CEF:x|a|b|c|d|e|f|`,
+		`xCEF:0|a|b|c|d|e|f|`,
+		`msg="saw CEF: events"`,
+		`level=info`,
+	} {
+		if p.CanParse(line) {
+			t.Errorf("CanParse(%q) = true", line)
+		}
+		if _, err := p.Parse(line); err != ErrNotCEF {
+			t.Errorf("Parse(%q): err = %v, want ErrNotCEF", line, err)
+		}
+	}
+}
+
+func TestAutoParserPrefersCEF(t *testing.T) {
+	entry, err := NewAutoParser().Parse(`CEF:0|V|P|1|100|name|5|src=10.0.0.1 msg=two words`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Fields["vendor"] != "V" || entry.Fields["msg"] != "two words" {
+		t.Errorf("Fields = %v, want a CEF event", entry.Fields)
+	}
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compression identifies the codec of an input stream.
+type Compression int
+
+const (
+	CompressNone  Compression = iota // Plain text
+	CompressGzip                     // gzip (.gz)
+	CompressBzip2                    // bzip2 (.bz2)
+	CompressZstd                     // Zstandard (.zst)
+	CompressXz                       // xz (.xz)
+)
+
+var compressionNames = map[Compression]string{
+	CompressNone:  "none",
+	CompressGzip:  "gzip",
+	CompressBzip2: "bzip2",
+	CompressZstd:  "zstd",
+	CompressXz:    "xz",
+}
+
+func (c Compression) String() string {
+	return compressionNames[c]
+}
+
+// magics maps stream signatures to codecs. bzip2's "BZh" is followed by a
+// block size digit, checked separately.
+var magics = []struct {
+	magic []byte
+	c     Compression
+}{
+	{[]byte{0x1f, 0x8b}, CompressGzip},
+	{[]byte("BZh"), CompressBzip2},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, CompressZstd},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, CompressXz},
+}
+
+// maxMagic is the longest signature in magics.
+const maxMagic = 6
+
+// extensions maps file suffixes to the codec they promise.
+var extensions = map[string]Compression{
+	".gz":  CompressGzip,
+	".bz2": CompressBzip2,
+	".zst": CompressZstd,
+	".xz":  CompressXz,
+}
+
+// DetectCompression identifies the codec from the first bytes of a stream.
+func DetectCompression(header []byte) Compression {
+	for _, m := range magics {
+		if !bytes.HasPrefix(header, m.magic) {
+			continue
+		}
+		if m.c == CompressBzip2 && (len(header) < 4 || header[3] < '1' || header[3] > '9') {
+			continue
+		}
+		return m.c
+	}
+	return CompressNone
+}
+
+// decompress wraps r in a decoder chosen by its magic bytes, so rotated
+// logs are read transparently whatever tool compressed them and whatever
+// they are named. A file whose extension promises compression but whose
+// content is neither compressed nor empty is reported as corrupt rather
+// than read as text.
+func decompress(r io.Reader, name string) (io.Reader, func() error, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(maxMagic)
+
+	c := DetectCompression(header)
+	if want, ok := extensions[filepath.Ext(name)]; ok && c == CompressNone && len(header) > 0 {
+		return nil, nil, fmt.Errorf("%s: not %s data", name, want)
+	}
+
+	noop := func() error { return nil }
+	switch c {
+	case CompressGzip:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return gz, gz.Close, nil
+	case CompressBzip2:
+		return bzip2.NewReader(br), noop, nil
+	case CompressZstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return zr, func() error { zr.Close(); return nil }, nil
+	case CompressXz:
+		xr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return xr, noop, nil
+	}
+	return br, noop, nil
+}
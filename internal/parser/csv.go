@@ -0,0 +1,193 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrHeaderLine is returned by CSVParser.Parse for a header row; callers
+// should skip it rather than count a parse error.
+var ErrHeaderLine = errors.New("parser: csv header line")
+
+// CSVParser maps delimited rows into fields by column name. Cell values
+// are type-inferred and empty cells are omitted. Columns beyond the known
+// ones are named col<N> (1-based). A row that repeats the column names is
+// a header and returns ErrHeaderLine, so every input may start with one.
+//
+// Columns come from NewCSVParser (--csv-columns) or from the header row
+// of each input: a StreamReader set up with SetCSVHeader reads it, in
+// order, and hands it on with the input's chunks (see Chunk.Columns and
+// ForColumns). Records spanning several lines (quoted newlines) are not
+// supported.
+type CSVParser struct {
+	comma   rune
+	columns []string
+}
+
+// NewCSVParser creates a CSV parser. columns may be nil to take them from
+// the header row of each input.
+func NewCSVParser(columns []string) *CSVParser {
+	return &CSVParser{comma: ',', columns: columns}
+}
+
+// NewTSVParser creates a tab-separated parser. columns may be nil to take
+// them from the header row of each input.
+func NewTSVParser(columns []string) *CSVParser {
+	return &CSVParser{comma: '\t', columns: columns}
+}
+
+// CanParse checks if the line splits into more than one column.
+func (p *CSVParser) CanParse(line string) bool {
+	record, err := splitCSV(line, p.comma)
+	return err == nil && len(record) > 1
+}
+
+// Parse converts a row into a LogEntry keyed by column name.
+func (p *CSVParser) Parse(line string) (*LogEntry, error) {
+	record, err := splitCSV(line, p.comma)
+	if err != nil {
+		return nil, err
+	}
+	if p.columns != nil && slices.Equal(trimCells(record), p.columns) {
+		return nil, ErrHeaderLine
+	}
+
+	entry := NewLogEntry(line, 0)
+	for i, cell := range record {
+		if cell == "" {
+			continue
+		}
+		name := "col" + strconv.Itoa(i+1)
+		if i < len(p.columns) && p.columns[i] != "" {
+			name = p.columns[i]
+		}
+		entry.Fields[name] = InferType(cell)
+	}
+	return entry, nil
+}
+
+// DetectCSVHeader returns the column names of a delimited input whose
+// first line is a header, and false when it is a data row. A header has
+// a distinct, non-empty name in every cell, none of them a number or a
+// boolean as data rows usually have somewhere.
+func DetectCSVHeader(line string, comma rune) ([]string, bool) {
+	record, err := splitCSV(line, comma)
+	if err != nil || len(record) < 2 {
+		return nil, false
+	}
+	names := trimCells(record)
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" || seen[name] {
+			return nil, false
+		}
+		if _, ok := InferType(name).(string); !ok {
+			return nil, false
+		}
+		seen[name] = true
+	}
+	return names, true
+}
+
+// ForColumns returns p, or a copy of p and the parsers it wraps, with the
+// CSV parser inside using columns. p is returned unchanged when it holds
+// no CSV parser or that one has columns of its own.
+func ForColumns(p Parser, columns []string) Parser {
+	switch t := p.(type) {
+	case *CSVParser:
+		if t.columns != nil {
+			return p
+		}
+		return &CSVParser{comma: t.comma, columns: columns}
+	case Wrapper:
+		inner := t.Unwrap()
+		if c := ForColumns(inner, columns); c != inner {
+			return t.Rewrap(c)
+		}
+	}
+	return p
+}
+
+// CSVComma returns the separator of the CSV parser inside p, and whether
+// there is one that takes its columns from the input.
+func CSVComma(p Parser) (rune, bool) {
+	for {
+		switch t := p.(type) {
+		case *CSVParser:
+			return t.comma, t.columns == nil
+		case Wrapper:
+			p = t.Unwrap()
+		default:
+			return 0, false
+		}
+	}
+}
+
+// Wrapper is a Parser that wraps another, such as RenameParser, so
+// settings of one input can reach the parser inside (see ForColumns).
+type Wrapper interface {
+	Parser
+	Unwrap() Parser             // The wrapped parser
+	Rewrap(inner Parser) Parser // A copy wrapping inner instead
+}
+
+// SetCSVHeader makes the reader take the first line of every input, after
+// SetLimits' skipped lines, as the header of a delimited input when it
+// looks like one (see DetectCSVHeader), and set Chunk.Columns to it.
+func (r *StreamReader) SetCSVHeader(comma rune) {
+	r.csvComma = comma
+}
+
+// csvHeaderPeek is how much of an unmapped input is looked at for its
+// header line.
+const csvHeaderPeek = 64 * 1024
+
+// withColumns returns fn, setting Chunk.Columns to the header of the input
+// starting with prefix if the reader looks for one and there is one.
+func (r *StreamReader) withColumns(prefix []byte, fn func(Chunk) bool) func(Chunk) bool {
+	columns := r.csvColumns(prefix)
+	if columns == nil {
+		return fn
+	}
+	return func(c Chunk) bool {
+		c.Columns = columns
+		return fn(c)
+	}
+}
+
+// csvColumns returns the columns of the input starting with prefix, or
+// nil when the reader does not look for them or there is no header.
+func (r *StreamReader) csvColumns(prefix []byte) []string {
+	if r.csvComma == 0 {
+		return nil
+	}
+	var line []byte
+	for range r.skip + 1 {
+		tok, advance, ok := cutLine(prefix, false)
+		if !ok {
+			return nil // No complete line
+		}
+		line, prefix = tok, prefix[advance:]
+	}
+	columns, _ := DetectCSVHeader(string(line), r.csvComma)
+	return columns
+}
+
+func splitCSV(line string, comma rune) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(line))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r.Read()
+}
+
+func trimCells(record []string) []string {
+	out := make([]string, len(record))
+	for i, cell := range record {
+		out[i] = strings.TrimSpace(cell)
+	}
+	return out
+}
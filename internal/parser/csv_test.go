@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectCSVHeader(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"ts,level,msg", []string{"ts", "level", "msg"}},
+		{" ts , level ", []string{"ts", "level"}},
+		{`"user id",status`, []string{"user id", "status"}},
+		{"2024-01-02,error,disk full", []string{"2024-01-02", "error", "disk full"}}, // Only numbers and booleans tell data apart
+		{"alice,200,ok", nil},
+		{"alice,true", nil},
+		{"name,,msg", nil},
+		{"name,name", nil},
+		{"single", nil},
+	}
+	for _, tt := range tests {
+		got, ok := DetectCSVHeader(tt.line, ',')
+		if ok != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DetectCSVHeader(%q) = %q, %v, want %q", tt.line, got, ok, tt.want)
+		}
+	}
+}
+
+func TestCSVParserColumns(t *testing.T) {
+	p := NewCSVParser([]string{"user", "status"})
+	if _, err := p.Parse("user,status"); !errors.Is(err, ErrHeaderLine) {
+		t.Errorf("header row: err = %v, want ErrHeaderLine", err)
+	}
+	entry, err := p.Parse("alice,200,extra,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"user": "alice", "status": int64(200), "col3": "extra"}
+	if !reflect.DeepEqual(entry.Fields, want) {
+		t.Errorf("Fields = %v, want %v", entry.Fields, want)
+	}
+}
+
+func TestForColumns(t *testing.T) {
+	csv := NewCSVParser(nil)
+	p := NewRenameParser(csv, []Rename{{"lvl", "level"}})
+	got := ForColumns(p, []string{"ts", "lvl"})
+	entry, err := got.Parse("12:00,warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Fields["level"] != "warn" || entry.Fields["ts"] != "12:00" {
+		t.Errorf("Fields = %v", entry.Fields)
+	}
+	if csv.columns != nil {
+		t.Errorf("ForColumns changed the caller's parser")
+	}
+	if fixed := NewCSVParser([]string{"a", "b"}); ForColumns(fixed, []string{"x", "y"}) != Parser(fixed) {
+		t.Errorf("ForColumns replaced explicit columns")
+	}
+	if json := NewJSONParser(); ForColumns(json, []string{"x"}) != Parser(json) {
+		t.Errorf("ForColumns changed a parser without CSV")
+	}
+}
+
+// TestReaderCSVHeader checks that every input gets the columns of its own
+// header, or none, whether scanned or mapped.
+func TestReaderCSVHeader(t *testing.T) {
+	dir := t.TempDir()
+	inputs := map[string]string{
+		"a.csv": "user,status\nalice,200\nbob,500\n",
+		"b.csv": "status,user,ms\n404,carol,12\n",
+		"c.csv": "dave,503\n",
+		"d.csv": "# exported\nuser,status\nerin,201\n",
+	}
+	want := map[string][]string{
+		"a.csv": {"user", "status"},
+		"b.csv": {"status", "user", "ms"},
+		"c.csv": nil,
+	}
+	defer func(n int64) { MmapThreshold = n }(MmapThreshold)
+	for _, mmap := range []int64{0, 1} {
+		MmapThreshold = mmap
+		for name, data := range inputs {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			r := NewStreamReader()
+			r.SetCSVHeader(',')
+			if name == "d.csv" {
+				r.SetLimits(1, 0)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]string
+			err = r.ScanChunks(f, 1, func(c Chunk) bool {
+				got = append(got, c.Columns)
+				return true
+			})
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect, ok := want[name]
+			if !ok {
+				expect = []string{"user", "status"}
+			}
+			lines := strings.Count(data, "\n")
+			if name == "d.csv" {
+				lines--
+			}
+			if len(got) != lines {
+				t.Fatalf("%s mmap=%d: %d chunks, want %d", name, mmap, len(got), lines)
+			}
+			for i, columns := range got {
+				if !reflect.DeepEqual(columns, expect) {
+					t.Errorf("%s mmap=%d: chunk %d Columns = %q, want %q", name, mmap, i, columns, expect)
+				}
+			}
+		}
+	}
+}
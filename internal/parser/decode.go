@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxDecodedSize caps the bytes kept from a decoded field; longer results
+// are truncated and marked with "...".
+const MaxDecodedSize = 64 * 1024
+
+// fieldDecoders are the encodings --decode-field understands.
+var fieldDecoders = map[string]func(string) ([]byte, error){
+	"base64": decodeBase64String,
+	"url": func(s string) ([]byte, error) {
+		d, err := url.QueryUnescape(s)
+		return []byte(d), err
+	},
+	"hex": hex.DecodeString,
+}
+
+// DecodeParser wraps a Parser and decodes encoded field values in place
+// (--decode-field body=base64 --decode-field query=url) before filtering
+// and output. Decoded bytes that are not printable text are kept in
+// escaped form, as Go quotes them, so binary payloads cannot garble the
+// terminal; results are capped at MaxDecodedSize. Values that fail to
+// decode are left unchanged.
+type DecodeParser struct {
+	Parser
+	Fields map[string]string // Field name to encoding
+}
+
+// NewDecodeParser wraps p to decode fields, mapping field names to
+// encodings.
+func NewDecodeParser(p Parser, fields map[string]string) *DecodeParser {
+	return &DecodeParser{Parser: p, Fields: fields}
+}
+
+// ParseDecodeField parses a --decode-field value such as "body=base64".
+func ParseDecodeField(s string) (field, encoding string, err error) {
+	field, encoding, ok := strings.Cut(s, "=")
+	field, encoding = strings.TrimSpace(field), strings.TrimSpace(encoding)
+	if !ok || field == "" {
+		return "", "", fmt.Errorf("parser: decode field %q: want field=encoding", s)
+	}
+	if _, ok := fieldDecoders[encoding]; !ok {
+		return "", "", fmt.Errorf("parser: decode field %q: unknown encoding %q (want base64, url or hex)", s, encoding)
+	}
+	return field, encoding, nil
+}
+
+// Unwrap returns the wrapped parser.
+func (p *DecodeParser) Unwrap() Parser {
+	return p.Parser
+}
+
+// Rewrap returns a copy of p wrapping inner.
+func (p *DecodeParser) Rewrap(inner Parser) Parser {
+	c := *p
+	c.Parser = inner
+	return &c
+}
+
+// Parse parses line with the wrapped parser and decodes its fields.
+func (p *DecodeParser) Parse(line string) (*LogEntry, error) {
+	entry, err := p.Parser.Parse(line)
+	if err != nil {
+		return entry, err
+	}
+	for field, encoding := range p.Fields {
+		s, ok := entry.Fields[field].(string)
+		if !ok {
+			continue
+		}
+		b, err := fieldDecoders[encoding](s)
+		if err != nil {
+			continue
+		}
+		entry.Fields[field] = safeText(b)
+	}
+	return entry, nil
+}
+
+// safeText renders decoded bytes as a string, escaping binary content and
+// truncating it to MaxDecodedSize.
+func safeText(b []byte) string {
+	truncated := len(b) > MaxDecodedSize
+	if truncated {
+		b = b[:MaxDecodedSize]
+	}
+	s := string(b)
+	if !isPrintable(s) {
+		q := strconv.Quote(s)
+		s = q[1 : len(q)-1]
+	}
+	if truncated {
+		s += "..."
+	}
+	return s
+}
+
+// isPrintable reports whether s is valid UTF-8 without control characters
+// other than tab and newlines.
+func isPrintable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
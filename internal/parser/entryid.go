@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IDPrefixSize is how many leading bytes of an input identify it when it
+// is not a local file.
+const IDPrefixSize = 1024
+
+// FileID identifies an input. A local file (info not nil) is identified
+// by its device and inode and its first line, so appending to it or
+// renaming it (as logrotate does) keeps its identity while a rotated
+// successor starting with the same banner, or the file rewritten in
+// place, gets another. Other inputs, with a nil info, are identified by
+// their first IDPrefixSize bytes of (decompressed) content, which
+// survives compressing or copying them but not appending to one shorter
+// than that. prefix holds the first bytes of the input.
+func FileID(prefix []byte, info os.FileInfo) string {
+	h := sha256.New()
+	if key, ok := fileKey(info); ok {
+		h.Write([]byte(key))
+		if i := bytes.IndexByte(prefix, '\n'); i >= 0 {
+			prefix = prefix[:i+1]
+		}
+	}
+	h.Write(prefix[:min(len(prefix), IDPrefixSize)])
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// identify returns the FileID of the input rd reads, through br, and the
+// offset in the file at which rd is positioned, so that offsets count from
+// the start of the file when a caller has read part of it already. It
+// must be called before anything is read through br.
+func identify(rd io.Reader, br *bufio.Reader) (id string, base int64) {
+	var f *os.File
+	var prefix []byte
+	switch t := rd.(type) {
+	case *os.File:
+		f = t
+		if pos, err := t.Seek(0, io.SeekCurrent); err == nil && pos > 0 {
+			base = pos
+			prefix = make([]byte, IDPrefixSize)
+			n, _ := t.ReadAt(prefix, 0)
+			prefix = prefix[:n]
+		}
+	case *decodedFile:
+		f, _ = t.file.(*os.File)
+	}
+	if base == 0 {
+		prefix, _ = br.Peek(IDPrefixSize)
+	}
+	var info os.FileInfo
+	if f != nil && f != os.Stdin {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			info = fi
+		}
+	}
+	return FileID(prefix, info), base
+}
+
+// EntryID is a stable identifier for a log entry (--with-id): the identity
+// of its input and the byte offset of its first line, so that a finding
+// cited in an incident document can be re-located with flog show.
+type EntryID struct {
+	File   string // FileID of the input
+	Offset int64  // Byte offset in the decompressed input
+}
+
+// String renders the ID as file@offset.
+func (id EntryID) String() string {
+	return id.File + "@" + strconv.FormatInt(id.Offset, 10)
+}
+
+// ParseEntryID parses an ID rendered by EntryID.String.
+func ParseEntryID(s string) (EntryID, error) {
+	file, off, ok := strings.Cut(s, "@")
+	offset, err := strconv.ParseInt(off, 10, 64)
+	if !ok || err != nil || offset < 0 || len(file) != 16 {
+		return EntryID{}, fmt.Errorf("parser: invalid entry ID %q: want file@offset", s)
+	}
+	if _, err := hex.DecodeString(file); err != nil {
+		return EntryID{}, fmt.Errorf("parser: invalid entry ID %q: want file@offset", s)
+	}
+	return EntryID{File: file, Offset: offset}, nil
+}
+
+// Excerpt is an entry re-located by ID, with lines of context around it.
+type Excerpt struct {
+	Path  string   // Input the entry was found in
+	Start int      // Line number of Lines[0]
+	Lines []string // Context before, the entry's line, context after
+	Match int      // Index of the entry's line in Lines
+}
+
+// ErrEntryNotFound is returned by Locate when no input holds the entry.
+var ErrEntryNotFound = errors.New("parser: entry not found")
+
+// Locate finds the entry with the given ID among paths (flog show) and
+// returns its line with up to before and after lines of context. Inputs
+// are matched by FileID (see there for what keeps an identity) and every
+// matching input is tried in turn. r must split records as the reader
+// that produced the ID did, with the same multiline, record separator and
+// JSON document settings.
+func (r *StreamReader) Locate(paths []string, id EntryID, before, after int) (*Excerpt, error) {
+	var miss error
+	for _, path := range paths {
+		ex, err := r.locateIn(path, id, before, after)
+		switch {
+		case ex != nil:
+			return ex, nil
+		case errors.Is(err, ErrEntryNotFound):
+			miss = err
+		case err != nil:
+			return nil, err
+		}
+	}
+	if miss != nil {
+		return nil, miss
+	}
+	return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+}
+
+// Locate finds an entry with a default StreamReader, for IDs of inputs
+// read line by line (see StreamReader.Locate).
+func Locate(paths []string, id EntryID, before, after int) (*Excerpt, error) {
+	return NewStreamReader().Locate(paths, id, before, after)
+}
+
+// locateIn returns the excerpt for id in path, or nil and no error when
+// path is another input.
+func (r *StreamReader) locateIn(path string, id EntryID, before, after int) (*Excerpt, error) {
+	rc, err := openReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	br := bufio.NewReader(rc)
+	if fileID, _ := identify(rc, br); fileID != id.File {
+		return nil, nil
+	}
+
+	var ex *Excerpt
+	var ring []string // Lines before the entry, at most before
+	err = r.scanRecords(br, func(line string, pos position) bool {
+		switch {
+		case ex != nil:
+			ex.Lines = append(ex.Lines, line)
+			return len(ex.Lines)-ex.Match-1 < after
+		case pos.offset == id.Offset:
+			ex = &Excerpt{Path: path, Start: pos.line - len(ring), Lines: append(ring, line), Match: len(ring)}
+			return after > 0
+		case pos.offset > id.Offset:
+			return false
+		}
+		if before > 0 {
+			if len(ring) == before {
+				ring = ring[1:]
+			}
+			ring = append(ring, line)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if ex == nil {
+		return nil, fmt.Errorf("%w: no line starts at offset %d of %s", ErrEntryNotFound, id.Offset, path)
+	}
+	return ex, nil
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chunkIDs reads path as ReadChunks does and returns the ID of every line.
+func chunkIDs(t *testing.T, r *StreamReader, path string) map[string]EntryID {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ids := make(map[string]EntryID)
+	err = r.ScanChunks(f, 2, func(c Chunk) bool {
+		for i, line := range c.Lines {
+			ids[line] = c.EntryID(i)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLocateRoundTrip checks that every ID read from rotated files with
+// the same banner leads back to its own line, mapped or scanned.
+func TestLocateRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	banner := "# app started " + strings.Repeat("=", IDPrefixSize) + "\n"
+	paths := []string{filepath.Join(dir, "app.log.1"), filepath.Join(dir, "app.log")}
+	writeFile(t, paths[0], banner+"old one\nold two\n")
+	writeFile(t, paths[1], banner+"new one\nnew two\nnew three\n")
+
+	defer func(n int64) { MmapThreshold = n }(MmapThreshold)
+	for _, mmap := range []int64{0, 1} {
+		MmapThreshold = mmap
+		r := NewStreamReader()
+		for _, path := range paths {
+			for line, id := range chunkIDs(t, r, path) {
+				ex, err := r.Locate(paths, id, 1, 1)
+				if err != nil {
+					t.Fatalf("mmap=%d: Locate(%s) for %q: %v", mmap, id, line, err)
+				}
+				if ex.Path != path || ex.Lines[ex.Match] != line {
+					t.Errorf("mmap=%d: Locate(%s) = %s %q, want %s %q", mmap, id, ex.Path, ex.Lines[ex.Match], path, line)
+				}
+			}
+		}
+	}
+}
+
+// TestFileIDStable checks that a small file keeps its ID as it grows and
+// when renamed, and that offsets count from the start of the file when it
+// is read from the middle.
+func TestFileIDStable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeFile(t, path, "first\n")
+	r := NewStreamReader()
+	before := chunkIDs(t, r, path)["first"]
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("second\nthird\n")
+	f.Close()
+	rotated := filepath.Join(dir, "app.log.1")
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatal(err)
+	}
+	after := chunkIDs(t, r, rotated)
+	if after["first"] != before {
+		t.Errorf("ID changed from %s to %s", before, after["first"])
+	}
+
+	f, err = os.Open(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(int64(len("first\n")), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var got EntryID
+	r.ScanChunks(f, 10, func(c Chunk) bool {
+		got = c.EntryID(0)
+		return false
+	})
+	if got != after["second"] {
+		t.Errorf("ID read from the middle = %s, want %s", got, after["second"])
+	}
+}
+
+// TestLocateRecordSeparator checks that IDs of records split by a separator
+// are found with a reader splitting the same way.
+func TestLocateRecordSeparator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records")
+	writeFile(t, path, "one\x00two\x00three\x00")
+	sep, err := ParseRecordSeparator("nul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewStreamReader()
+	r.SetRecordSeparator(sep)
+	id := chunkIDs(t, r, path)["two"]
+	ex, err := r.Locate([]string{path}, id, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ex.Lines[ex.Match] != "two" {
+		t.Errorf("Locate = %q, want two", ex.Lines[ex.Match])
+	}
+	if _, err := NewStreamReader().Locate([]string{path}, EntryID{File: id.File, Offset: id.Offset + 1}, 0, 0); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("bad offset: err = %v, want ErrEntryNotFound", err)
+	}
+}
@@ -0,0 +1,86 @@
+package parser
+
+import "strings"
+
+// ExpandParser wraps a Parser and expands string fields whose value is
+// itself a JSON object or array (--expand-json-fields payload,message):
+// the value is parsed and flattened under the field's key, so
+// payload='{"order":{"id":7}}' becomes payload.order.id=7. Fields that are
+// not valid JSON are left as strings. The field "*" expands every string
+// field that looks like JSON.
+type ExpandParser struct {
+	Parser
+	Fields []string
+}
+
+// NewExpandParser wraps p to expand the named fields.
+func NewExpandParser(p Parser, fields []string) *ExpandParser {
+	return &ExpandParser{Parser: p, Fields: fields}
+}
+
+// ParseExpandFields splits a comma-separated --expand-json-fields value.
+func ParseExpandFields(s string) []string {
+	var fields []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Unwrap returns the wrapped parser.
+func (p *ExpandParser) Unwrap() Parser {
+	return p.Parser
+}
+
+// Rewrap returns a copy of p wrapping inner.
+func (p *ExpandParser) Rewrap(inner Parser) Parser {
+	c := *p
+	c.Parser = inner
+	return &c
+}
+
+// Parse parses line with the wrapped parser and expands its JSON fields.
+func (p *ExpandParser) Parse(line string) (*LogEntry, error) {
+	entry, err := p.Parser.Parse(line)
+	if err != nil {
+		return entry, err
+	}
+	for _, field := range p.Fields {
+		if field != "*" {
+			expandJSONField(entry.Fields, field)
+			continue
+		}
+		var keys []string
+		for k, v := range entry.Fields {
+			if s, ok := v.(string); ok && looksLikeJSON(s) {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			expandJSONField(entry.Fields, k)
+		}
+	}
+	return entry, nil
+}
+
+// expandJSONField replaces the string at key with its flattened JSON value.
+func expandJSONField(fields map[string]any, key string) {
+	s, ok := fields[key].(string)
+	if !ok || !looksLikeJSON(s) {
+		return
+	}
+	var v any
+	if err := decodeJSON(s, &v); err != nil {
+		return
+	}
+	delete(fields, key)
+	Flatten(key, v, fields)
+}
+
+// looksLikeJSON reports whether s is delimited like a JSON object or array.
+func looksLikeJSON(s string) bool {
+	s = strings.TrimSpace(s)
+	return len(s) >= 2 && (s[0] == '{' && s[len(s)-1] == '}' || s[0] == '[' && s[len(s)-1] == ']')
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package parser
+
+import "os"
+
+// fileKey is not supported on this platform; files are identified by
+// content alone.
+func fileKey(info os.FileInfo) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package parser
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// fileKey returns the device and inode of a local file.
+func fileKey(info os.FileInfo) (string, bool) {
+	if info == nil {
+		return "", false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false
+	}
+	return strconv.FormatUint(uint64(st.Dev), 10) + ":" + strconv.FormatUint(st.Ino, 10), true
+}
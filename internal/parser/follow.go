@@ -0,0 +1,363 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultFollowInterval is how often a Follower polls for new data and
+// new files.
+const DefaultFollowInterval = 250 * time.Millisecond
+
+// followReadSize is how much of a file a Follower reads at once.
+const followReadSize = 64 * 1024
+
+// FollowOptions configure a Follower.
+type FollowOptions struct {
+	Interval  time.Duration // Poll interval (default DefaultFollowInterval)
+	FromStart bool          // Read files present at start from the beginning, not the end
+	ChunkSize int           // Lines per chunk (default 1000)
+
+	// Reader splits the files into records as it does other inputs: its
+	// maximum line size and oversize mode, record separator and ANSI
+	// stripping apply (default NewStreamReader()). Multiline assembly,
+	// JSON documents, header context and SetLimits do not.
+	Reader *StreamReader
+}
+
+// Follower tails files (-t/--follow), including every file matching a
+// glob such as /var/log/app/*.log: files that appear later are read from
+// their beginning, files replaced by rotation are finished and the new
+// file read from its start, truncated files are reread, and deleted files
+// are finished and dropped. It polls, so it needs no OS notification
+// support. Files are read in bounded pieces and emitted in chunks of up to
+// ChunkSize lines as they are split, however much was appended.
+type Follower struct {
+	Patterns []string
+	Options  FollowOptions
+
+	files map[string]*followed
+	seq   int
+}
+
+// followed is the read state of one file.
+type followed struct {
+	path   string
+	f      *os.File
+	info   os.FileInfo // Identity of f
+	fileID string
+	offset int64  // Bytes of f read, buffered ones included
+	line   int    // Lines emitted so far
+	buf    []byte // Bytes read but not yet split into a record
+	lines  *lineSplitter
+}
+
+// NewFollower creates a Follower for paths and glob patterns.
+func NewFollower(patterns []string, opts FollowOptions) (*Follower, error) {
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("follow %q: %w", p, err)
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultFollowInterval
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 1000
+	}
+	if opts.Reader == nil {
+		opts.Reader = NewStreamReader()
+	}
+	return &Follower{Patterns: patterns, Options: opts, files: make(map[string]*followed)}, nil
+}
+
+// Run polls until ctx is done or fn returns false, calling fn with the
+// new lines of each file, in batches. The offsets are those of each line
+// in its file, and line numbers count from the start of the file. A line
+// longer than the maximum line size under OversizeFail ends Run with
+// ErrLineTooLong.
+func (fw *Follower) Run(ctx context.Context, fn func(c Chunk) bool) error {
+	defer fw.closeAll()
+	first := true
+	for {
+		if ok, err := fw.poll(first, fn); !ok || err != nil {
+			return err
+		}
+		first = false
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(fw.Options.Interval):
+		}
+	}
+}
+
+// ReadChunks runs the Follower and returns its chunks on a channel,
+// closed when ctx is done or on error; check Err afterwards.
+func (fw *Follower) ReadChunks(ctx context.Context) <-chan Chunk {
+	out := make(chan Chunk, 16)
+	go func() {
+		defer close(out)
+		r := fw.Options.Reader
+		r.setErr(fw.Run(ctx, func(c Chunk) bool {
+			select {
+			case out <- c:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}))
+	}()
+	return out
+}
+
+// Err returns the error that ended ReadChunks, if any.
+func (fw *Follower) Err() error {
+	return fw.Options.Reader.Err()
+}
+
+// poll picks up new and replaced files, drops deleted ones and emits the
+// data appended since the last poll. It reports false once fn does.
+func (fw *Follower) poll(first bool, fn func(Chunk) bool) (bool, error) {
+	paths := fw.match()
+	current := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		current[path] = true
+		if _, ok := fw.files[path]; ok {
+			continue
+		}
+		if st := fw.renamed(path); st != nil {
+			// Rotated to a name that still matches: keep reading it.
+			delete(fw.files, st.path)
+			st.path = path
+			fw.files[path] = st
+			continue
+		}
+		if st := fw.open(path, first && !fw.Options.FromStart); st != nil {
+			fw.files[path] = st
+		}
+	}
+
+	for _, path := range sortedKeys(fw.files) {
+		st := fw.files[path]
+		info, err := os.Stat(path)
+		switch {
+		case err != nil || !current[path]:
+			// Deleted (or renamed away): finish what was written.
+			if ok, err := fw.emit(st, true, fn); !ok || err != nil {
+				return ok, err
+			}
+			st.f.Close()
+			delete(fw.files, path)
+			continue
+		case !os.SameFile(info, st.info):
+			// Rotated: finish the old file, then start on the new one.
+			if ok, err := fw.emit(st, true, fn); !ok || err != nil {
+				return ok, err
+			}
+			st.f.Close()
+			delete(fw.files, path)
+			next := fw.open(path, false)
+			if next == nil {
+				continue
+			}
+			fw.files[path] = next
+			st = next
+		case info.Size() < st.offset:
+			// Truncated in place (copytruncate): read it again.
+			st.offset, st.line, st.buf = 0, 0, st.buf[:0]
+			st.lines = fw.newSplitter()
+			st.f.Seek(0, io.SeekStart)
+		}
+		if ok, err := fw.emit(st, false, fn); !ok || err != nil {
+			return ok, err
+		}
+	}
+	return true, nil
+}
+
+// match returns the regular files the patterns currently match.
+func (fw *Follower) match() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, p := range fw.Patterns {
+		matches, _ := filepath.Glob(p)
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() && !seen[m] {
+				seen[m] = true
+				paths = append(paths, m)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// renamed returns the followed file now found at path, if one was moved
+// there.
+func (fw *Follower) renamed(path string) *followed {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	for _, st := range fw.files {
+		if !os.SameFile(info, st.info) {
+			continue
+		}
+		if cur, err := os.Stat(st.path); err != nil || !os.SameFile(cur, st.info) {
+			return st
+		}
+	}
+	return nil
+}
+
+// open starts following path, at its end when atEnd is set.
+func (fw *Follower) open(path string, atEnd bool) *followed {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil
+	}
+	prefix := make([]byte, IDPrefixSize)
+	n, _ := f.ReadAt(prefix, 0)
+	st := &followed{path: path, f: f, info: info, fileID: FileID(prefix[:n], info), lines: fw.newSplitter()}
+	if atEnd {
+		st.line = countLines(f)
+		st.offset, _ = f.Seek(0, io.SeekEnd)
+	}
+	return st
+}
+
+// countLines counts the complete lines of f from its current position.
+func countLines(f *os.File) int {
+	buf := make([]byte, 256*1024)
+	n := 0
+	for {
+		k, err := f.Read(buf)
+		n += bytes.Count(buf[:k], []byte("\n"))
+		if err != nil {
+			return n
+		}
+	}
+}
+
+// newSplitter returns the record splitter for a file, as scanRecords
+// sets it up.
+func (fw *Follower) newSplitter() *lineSplitter {
+	r := fw.Options.Reader
+	split := scanLines
+	if r.recordSep != nil {
+		split = splitRecords(r.recordSep)
+	}
+	return &lineSplitter{
+		split: split,
+		max:   r.bufferSize,
+		mode:  r.oversize,
+		count: func() { r.oversized.Add(1) },
+	}
+}
+
+// emit reads what was appended to st, followReadSize bytes at a time, and
+// passes its complete records to fn in chunks; with final set, a trailing
+// partial record is emitted too. It reports false once fn does.
+func (fw *Follower) emit(st *followed, final bool, fn func(Chunk) bool) (bool, error) {
+	r := fw.Options.Reader
+	newChunk := func() Chunk {
+		c := Chunk{Seq: fw.seq, Source: st.path, FileID: st.fileID}
+		if r.oversize != OversizeFail {
+			c.LineNums = []int{}
+		}
+		return c
+	}
+	c := newChunk()
+	send := func() bool {
+		if len(c.Lines) == 0 {
+			return true
+		}
+		fw.seq++
+		ok := fn(c)
+		c = newChunk()
+		return ok
+	}
+
+	buf := make([]byte, followReadSize)
+	for {
+		n, err := st.f.Read(buf)
+		st.buf = append(st.buf, buf[:n]...)
+		st.offset += int64(n)
+		eof := err != nil || n == 0
+
+		used := 0
+		for {
+			advance, tok, err := st.lines.next(st.buf[used:], eof && final)
+			if err != nil {
+				if errors.Is(err, ErrLineTooLong) {
+					err = lineTooLong(st.line+1, st.lines.max)
+				}
+				return false, fmt.Errorf("%s: %w", st.path, err)
+			}
+			if advance == 0 && tok == nil {
+				break
+			}
+			start := st.offset - int64(len(st.buf)-used)
+			used += advance
+			if tok == nil || r.recordSep != nil && len(tok) == 0 {
+				st.line, st.lines.dropped = st.line+st.lines.dropped, 0
+				continue
+			}
+			if !st.lines.continued {
+				st.line++
+			}
+			st.line, st.lines.dropped = st.line+st.lines.dropped, 0
+
+			line := string(tok)
+			if r.stripANSI {
+				line = StripANSI(line)
+			}
+			if len(c.Lines) == 0 {
+				c.Start = st.line
+			}
+			c.Lines = append(c.Lines, line)
+			c.Offsets = append(c.Offsets, start)
+			if c.LineNums != nil {
+				c.LineNums = append(c.LineNums, st.line)
+			}
+			if len(c.Lines) == fw.Options.ChunkSize && !send() {
+				return false, nil
+			}
+		}
+		st.buf = st.buf[:copy(st.buf, st.buf[used:])]
+		if eof {
+			break
+		}
+	}
+	return send(), nil
+}
+
+func (fw *Follower) closeAll() {
+	for path, st := range fw.files {
+		st.f.Close()
+		delete(fw.files, path)
+	}
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pollLines runs one poll of fw and returns the lines and chunk sizes
+// emitted.
+func pollLines(t *testing.T, fw *Follower, first bool) (lines []string, sizes []int, err error) {
+	t.Helper()
+	_, err = fw.poll(first, func(c Chunk) bool {
+		for i, line := range c.Lines {
+			lines = append(lines, line)
+			if c.LineNum(i) != len(lines) {
+				t.Errorf("line %q numbered %d, want %d", line, c.LineNum(i), len(lines))
+			}
+		}
+		sizes = append(sizes, len(c.Lines))
+		return true
+	})
+	return lines, sizes, err
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFollowChunks checks that a large append is emitted in chunks of at
+// most ChunkSize lines, and that a partial last line waits for its end.
+func TestFollowChunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, strings.Repeat(strings.Repeat("x", 100)+"\n", 5000)+"partial")
+	fw, err := NewFollower([]string{path}, FollowOptions{FromStart: true, ChunkSize: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.closeAll()
+
+	lines, sizes, err := pollLines(t, fw, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 5000 {
+		t.Fatalf("%d lines, want 5000", len(lines))
+	}
+	for _, n := range sizes {
+		if n > 300 {
+			t.Errorf("chunk of %d lines, want at most 300", n)
+		}
+	}
+
+	appendFile(t, path, " line\n")
+	var got []string
+	fw.poll(false, func(c Chunk) bool {
+		got = append(got, c.Lines...)
+		if c.Offsets[0] != 5000*101 {
+			t.Errorf("offset %d, want %d", c.Offsets[0], 5000*101)
+		}
+		return true
+	})
+	if len(got) != 1 || got[0] != "partial line" {
+		t.Errorf("after the line end: %q, want [partial line]", got)
+	}
+}
+
+// TestFollowReaderSettings checks that followed files are split with the
+// reader's maximum line size, oversize mode and record separator.
+func TestFollowReaderSettings(t *testing.T) {
+	long := strings.Repeat("y", 200)
+	tests := []struct {
+		name  string
+		data  string
+		setup func(r *StreamReader)
+		want  []string
+		err   error
+	}{
+		{"skip", "a\n" + long + "\nb\n", func(r *StreamReader) { r.SetMaxLineSize(100, OversizeSkip) }, []string{"a", "b"}, nil},
+		{"truncate", long + "\n", func(r *StreamReader) { r.SetMaxLineSize(100, OversizeTruncate) }, []string{long[:100]}, nil},
+		{"fail", "a\n" + long + "\n", func(r *StreamReader) { r.SetMaxLineSize(100, OversizeFail) }, []string{"a"}, ErrLineTooLong},
+		{"nul", "one\x00two\ntwo\x00three", func(r *StreamReader) {
+			sep, _ := ParseRecordSeparator("nul")
+			r.SetRecordSeparator(sep)
+		}, []string{"one", "two\ntwo"}, nil},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "app.log")
+		appendFile(t, path, tt.data)
+		r := NewStreamReader()
+		tt.setup(r)
+		fw, err := NewFollower([]string{path}, FollowOptions{FromStart: true, ChunkSize: 1, Reader: r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		_, err = fw.poll(true, func(c Chunk) bool {
+			got = append(got, c.Lines...)
+			return true
+		})
+		fw.closeAll()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: lines %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
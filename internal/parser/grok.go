@@ -0,0 +1,262 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrNoGrokMatch is returned when a line does not match a grok pattern.
+var ErrNoGrokMatch = errors.New("parser: line does not match the grok pattern")
+
+// grokRef matches a pattern reference: %{NAME}, %{NAME:field} or
+// %{NAME:field:type}.
+var grokRef = regexp.MustCompile(`%\{(\w+)(?::([\w.@\[\]-]+))?(?::(int|float|string))?\}`)
+
+// grokName matches a pattern name in a patterns file.
+var grokName = regexp.MustCompile(`^\w+$`)
+
+// grokGroup prefixes the regexp group names of named references.
+const grokGroup = "grok_"
+
+// grokBase is the standard grok pattern library, as shipped with Logstash,
+// rewritten for Go's regexp syntax (no lookaround or atomic groups).
+var grokBase = map[string]string{
+	"USERNAME":       `[a-zA-Z0-9._-]+`,
+	"USER":           `%{USERNAME}`,
+	"EMAILLOCALPART": `[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+(?:\.[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+)*`,
+	"EMAILADDRESS":   `%{EMAILLOCALPART}@%{HOSTNAME}`,
+	"INT":            `[+-]?[0-9]+`,
+	"BASE10NUM":      `[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+)`,
+	"NUMBER":         `%{BASE10NUM}`,
+	"BASE16NUM":      `[+-]?(?:0x)?[0-9A-Fa-f]+`,
+	"POSINT":         `[1-9][0-9]*`,
+	"NONNEGINT":      `[0-9]+`,
+	"WORD":           `\b\w+\b`,
+	"NOTSPACE":       `\S+`,
+	"SPACE":          `\s*`,
+	"DATA":           `.*?`,
+	"GREEDYDATA":     `.*`,
+	"QUOTEDSTRING":   `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`(?:[^`\\\\]|\\\\.)*`",
+	"QS":             `%{QUOTEDSTRING}`,
+	"UUID":           `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"URN":            `urn:[0-9A-Za-z][0-9A-Za-z-]{0,31}:(?:%[0-9a-fA-F]{2}|[0-9A-Za-z()+,.:=@;$_!*'/?#-])+`,
+
+	"MAC":        `%{CISCOMAC}|%{WINDOWSMAC}|%{COMMONMAC}`,
+	"CISCOMAC":   `(?:[A-Fa-f0-9]{4}\.){2}[A-Fa-f0-9]{4}`,
+	"WINDOWSMAC": `(?:[A-Fa-f0-9]{2}-){5}[A-Fa-f0-9]{2}`,
+	"COMMONMAC":  `(?:[A-Fa-f0-9]{2}:){5}[A-Fa-f0-9]{2}`,
+	"IPV6":       `(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}|(?:[0-9A-Fa-f]{1,4}:){1,7}:|(?:[0-9A-Fa-f]{1,4}:){1,6}(?::[0-9A-Fa-f]{1,4}){1,1}|(?:[0-9A-Fa-f]{1,4}:){1,5}(?::[0-9A-Fa-f]{1,4}){1,2}|(?:[0-9A-Fa-f]{1,4}:){1,4}(?::[0-9A-Fa-f]{1,4}){1,3}|(?:[0-9A-Fa-f]{1,4}:){1,3}(?::[0-9A-Fa-f]{1,4}){1,4}|(?:[0-9A-Fa-f]{1,4}:){1,2}(?::[0-9A-Fa-f]{1,4}){1,5}|[0-9A-Fa-f]{1,4}:(?::[0-9A-Fa-f]{1,4}){1,6}|:(?:(?::[0-9A-Fa-f]{1,4}){1,7}|:)(?:%[0-9A-Za-z]+)?`,
+	"IPV4":       `(?:(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])`,
+	"IP":         `%{IPV6}|%{IPV4}`,
+	"HOSTNAME":   `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":   `%{IP}|%{HOSTNAME}`,
+	"HOSTPORT":   `%{IPORHOST}:%{POSINT}`,
+
+	"PATH":         `%{UNIXPATH}|%{WINPATH}`,
+	"UNIXPATH":     `(?:/[\w_%!$@:.,+~-]*)+`,
+	"TTY":          `/dev/(?:pts|tty(?:[pq])?)(?:\w+)?/?(?:[0-9]+)`,
+	"WINPATH":      `(?:[A-Za-z]+:|\\)(?:\\[^\\?*]*)+`,
+	"URIPROTO":     `[A-Za-z](?:[A-Za-z0-9+\-.]+)+`,
+	"URIHOST":      `%{IPORHOST}(?::%{POSINT})?`,
+	"URIPATH":      `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIQUERY":     `[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPARAM":     `\?%{URIQUERY}`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":          `%{URIPROTO}://(?:%{USER}(?::[^@]*)?@)?(?:%{URIHOST})?(?:%{URIPATH}(?:%{URIPARAM})?)?`,
+
+	"MONTH":             `\b(?:[Jj]an(?:uary|uar)?|[Ff]eb(?:ruary|ruar)?|[Mm](?:a|ä)?r(?:ch|z)?|[Aa]pr(?:il)?|[Mm]a(?:y|i)?|[Jj]un(?:e|i)?|[Jj]ul(?:y|i)?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo](?:c|k)?t(?:ober)?|[Nn]ov(?:ember)?|[Dd]e(?:c|z)(?:ember)?)\b`,
+	"MONTHNUM":          `0?[1-9]|1[0-2]`,
+	"MONTHNUM2":         `0[1-9]|1[0-2]`,
+	"MONTHDAY":          `(?:0[1-9])|(?:[12][0-9])|(?:3[01])|[1-9]`,
+	"DAY":               `(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"HOUR":              `2[0123]|[01]?[0-9]`,
+	"MINUTE":            `[0-5][0-9]`,
+	"SECOND":            `(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"DATE_US":           `%{MONTHNUM}[/-]%{MONTHDAY}[/-]%{YEAR}`,
+	"DATE_EU":           `%{MONTHDAY}[./-]%{MONTHNUM}[./-]%{YEAR}`,
+	"ISO8601_TIMEZONE":  `Z|[+-]%{HOUR}(?::?%{MINUTE})`,
+	"ISO8601_SECOND":    `%{SECOND}`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"DATE":              `%{DATE_US}|%{DATE_EU}`,
+	"DATESTAMP":         `%{DATE}[- ]%{TIME}`,
+	"TZ":                `[A-Z]{3}`,
+	"DATESTAMP_RFC822":  `%{DAY} %{MONTH} %{MONTHDAY} %{YEAR} %{TIME} %{TZ}`,
+	"DATESTAMP_OTHER":   `%{DAY} %{MONTH} %{MONTHDAY} %{TIME} %{TZ} %{YEAR}`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+
+	"SYSLOGTIMESTAMP": `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"PROG":            `[\x21-\x5a\x5c\x5e-\x7e]+`,
+	"SYSLOGPROG":      `%{PROG:program}(?:\[%{POSINT:pid}\])?`,
+	"SYSLOGHOST":      `%{IPORHOST}`,
+	"SYSLOGFACILITY":  `<%{NONNEGINT:facility}.%{NONNEGINT:priority}>`,
+	"SYSLOGBASE":      `%{SYSLOGTIMESTAMP:timestamp} (?:%{SYSLOGFACILITY} )?%{SYSLOGHOST:logsource} %{SYSLOGPROG}:`,
+	"LOGLEVEL":        `[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo(?:rmation)?|INFO(?:RMATION)?|[Ww]arn(?:ing)?|WARN(?:ING)?|[Ee]rr(?:or)?|ERR(?:OR)?|[Cc]rit(?:ical)?|CRIT(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|EMERG(?:ENCY)?|[Ee]merg(?:ency)?`,
+
+	"HTTPDUSER":         `%{EMAILADDRESS}|%{USER}`,
+	"HTTPDERROR_DATE":   `%{DAY} %{MONTH} %{MONTHDAY} %{TIME} %{YEAR}`,
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{HTTPDUSER:ident} %{HTTPDUSER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+}
+
+// Grok is a library of named grok patterns: the standard ones plus any
+// added with Add or LoadPatterns.
+type Grok struct {
+	patterns map[string]string
+}
+
+// NewGrok returns a Grok holding the standard pattern library.
+func NewGrok() *Grok {
+	g := &Grok{patterns: make(map[string]string, len(grokBase))}
+	for name, p := range grokBase {
+		g.patterns[name] = p
+	}
+	return g
+}
+
+// Add defines or replaces the pattern name.
+func (g *Grok) Add(name, pattern string) {
+	g.patterns[name] = pattern
+}
+
+// LoadPatterns adds the patterns of a Logstash patterns file: one
+// "NAME regex" definition per line, with blank lines and # comments
+// ignored (--patterns-file).
+func (g *Grok) LoadPatterns(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, pattern, ok := strings.Cut(line, " ")
+		if !ok || !grokName.MatchString(name) {
+			return fmt.Errorf("parser: grok patterns line %d: want NAME PATTERN", n)
+		}
+		g.Add(name, strings.TrimSpace(pattern))
+	}
+	return sc.Err()
+}
+
+// Compile builds a GrokParser for pattern, such as
+// "%{IP:client} %{WORD:method} %{URIPATH:path}".
+func (g *Grok) Compile(pattern string) (*GrokParser, error) {
+	p := &GrokParser{Pattern: pattern}
+	expr, err := g.expand(pattern, nil, p)
+	if err != nil {
+		return nil, err
+	}
+	// Oniguruma's (?<name>...) is Go's (?P<name>...).
+	expr = strings.ReplaceAll(expr, "(?<", "(?P<")
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parser: grok %q: %w", pattern, err)
+	}
+	p.re = re
+	for i, name := range re.SubexpNames() {
+		if n, ok := strings.CutPrefix(name, grokGroup); ok {
+			k, _ := strconv.Atoi(n)
+			p.captures[k].group = i
+		} else if name != "" {
+			p.captures = append(p.captures, grokCapture{group: i, field: name})
+		}
+	}
+	return p, nil
+}
+
+// expand replaces the pattern references in pattern with their
+// definitions, recursively. Named references become capture groups
+// recorded on p; stack holds the patterns being expanded, to reject
+// cycles.
+func (g *Grok) expand(pattern string, stack []string, p *GrokParser) (string, error) {
+	var err error
+	out := grokRef.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		m := grokRef.FindStringSubmatch(ref)
+		name, field, typ := m[1], m[2], m[3]
+		def, ok := g.patterns[name]
+		if !ok {
+			err = fmt.Errorf("parser: grok: unknown pattern %q", name)
+			return ""
+		}
+		for _, s := range stack {
+			if s == name {
+				err = fmt.Errorf("parser: grok: pattern %q refers to itself", name)
+				return ""
+			}
+		}
+		var body string
+		if body, err = g.expand(def, append(stack, name), p); err != nil {
+			return ""
+		}
+		if field == "" {
+			return "(?:" + body + ")"
+		}
+		group := grokGroup + strconv.Itoa(len(p.captures))
+		p.captures = append(p.captures, grokCapture{field: field, typ: typ})
+		return "(?P<" + group + ">" + body + ")"
+	})
+	return out, err
+}
+
+// GrokParser parses unstructured lines with a grok pattern (--pattern):
+// each named reference, %{IP:client}, becomes a field. Values are strings
+// unless the reference asks for :int or :float, as in Logstash; fields
+// that do not take part in the match are omitted. The pattern is not
+// anchored, so it may match anywhere in the line.
+type GrokParser struct {
+	Pattern string
+
+	re       *regexp.Regexp
+	captures []grokCapture // Named references in order, then (?<name>) groups
+}
+
+// grokCapture maps a capture group to a field.
+type grokCapture struct {
+	group int    // Index in the regexp
+	field string // Field name
+	typ   string // "int", "float" or "" for a string
+}
+
+// NewGrokParser compiles pattern against the standard pattern library.
+func NewGrokParser(pattern string) (*GrokParser, error) {
+	return NewGrok().Compile(pattern)
+}
+
+// CanParse reports whether the line matches the pattern.
+func (p *GrokParser) CanParse(line string) bool {
+	return p.re.MatchString(line)
+}
+
+// Parse extracts the pattern's fields from line.
+func (p *GrokParser) Parse(line string) (*LogEntry, error) {
+	m := p.re.FindStringSubmatchIndex(line)
+	if m == nil {
+		return nil, ErrNoGrokMatch
+	}
+	entry := NewLogEntry(line, 0)
+	for _, c := range p.captures {
+		start, end := m[2*c.group], m[2*c.group+1]
+		if start < 0 {
+			continue
+		}
+		v := line[start:end]
+		switch c.typ {
+		case "int", "float":
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				entry.Fields[c.field] = Number(f)
+				continue
+			}
+		}
+		entry.Fields[c.field] = v
+	}
+	return entry, nil
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGrokBaseCompiles(t *testing.T) {
+	for name := range grokBase {
+		if _, err := NewGrokParser("%{" + name + "}"); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestGrokParser(t *testing.T) {
+	tests := []struct {
+		pattern, line string
+		want          map[string]any
+	}{
+		{`%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} %{GREEDYDATA:msg}`,
+			`2024-03-01T12:00:05.250Z ERROR disk full on /dev/sda1`,
+			map[string]any{"ts": "2024-03-01T12:00:05.250Z", "level": "ERROR", "msg": "disk full on /dev/sda1"}},
+		// Types, and references to fields of nested names.
+		{`%{IP:client.ip} took %{NUMBER:ms:float}ms, %{INT:n:int} rows, code %{INT:code}`,
+			`10.0.0.1 took 12.5ms, 40 rows, code 007`,
+			map[string]any{"client.ip": "10.0.0.1", "ms": 12.5, "n": int64(40), "code": "007"}},
+		// Optional parts that do not match leave their fields out.
+		{`%{WORD:verb}(?: %{URIPATH:path})?`, `GET`, map[string]any{"verb": "GET"}},
+		// Oniguruma named groups.
+		{`user=(?<user>\w+)`, `login user=ann ok`, map[string]any{"user": "ann"}},
+		{`%{COMBINEDAPACHELOG}`,
+			`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "http://x/" "Mozilla/4.08"`,
+			map[string]any{
+				"clientip": "127.0.0.1", "ident": "-", "auth": "frank", "timestamp": "10/Oct/2000:13:55:36 -0700",
+				"verb": "GET", "request": "/a.gif", "httpversion": "1.0", "response": "200", "bytes": "2326",
+				"referrer": `"http://x/"`, "agent": `"Mozilla/4.08"`,
+			}},
+	}
+	for _, tt := range tests {
+		p, err := NewGrokParser(tt.pattern)
+		if err != nil {
+			t.Errorf("NewGrokParser(%q): %v", tt.pattern, err)
+			continue
+		}
+		entry, err := p.Parse(tt.line)
+		if err != nil {
+			t.Errorf("%s on %q: %v", tt.pattern, tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(entry.Fields, tt.want) {
+			t.Errorf("%s on %q\n got %#v\nwant %#v", tt.pattern, tt.line, entry.Fields, tt.want)
+		}
+	}
+
+	p, _ := NewGrokParser(`^%{INT:n}$`)
+	if p.CanParse("12a") {
+		t.Error("CanParse(12a) = true")
+	}
+	if _, err := p.Parse("12a"); err != ErrNoGrokMatch {
+		t.Errorf("Parse(12a): err = %v, want ErrNoGrokMatch", err)
+	}
+}
+
+func TestGrokPatterns(t *testing.T) {
+	g := NewGrok()
+	err := g.LoadPatterns(strings.NewReader(`
+# Application patterns
+APPID [A-Z]{3}-\d+
+REQ %{APPID:app} %{WORD:op}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := g.Compile(`%{REQ} by %{USERNAME:user}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := p.Parse("ABC-12 delete by ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"app": "ABC-12", "op": "delete", "user": "ann"}; !reflect.DeepEqual(entry.Fields, want) {
+		t.Errorf("Fields = %#v, want %#v", entry.Fields, want)
+	}
+
+	if err := g.LoadPatterns(strings.NewReader("NO-NAME x\n")); err == nil {
+		t.Error("LoadPatterns accepted an invalid name")
+	}
+	g.Add("LOOP", "a%{LOOP}")
+	for _, pattern := range []string{`%{LOOP}`, `%{NOPE:x}`, `%{WORD:w}(`} {
+		if _, err := g.Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) succeeded", pattern)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// headerPair matches key=value pairs, with optionally quoted values, in a
+// session header line.
+var headerPair = regexp.MustCompile(`([A-Za-z_][\w.-]*)=("(?:[^"\\]|\\.)*"|[^\s"]+)`)
+
+// SetHeaderContext enables header context: a line matching header, such
+// as "=== run id=abc config=prod ===", starts a section whose fields are
+// attached to every following entry of the same input until the next
+// header. The fields are header's named groups when it has any, otherwise
+// the key=value pairs of the line. See Chunk.ApplyContext.
+func (r *StreamReader) SetHeaderContext(header *regexp.Regexp) {
+	r.header = header
+}
+
+// headerFields extracts the context fields of a header line.
+func headerFields(header *regexp.Regexp, line string) map[string]any {
+	fields := make(map[string]any)
+	if m := header.FindStringSubmatch(line); m != nil {
+		for i, name := range header.SubexpNames() {
+			if name != "" && i < len(m) {
+				fields[name] = InferType(m[i])
+			}
+		}
+	}
+	if len(fields) > 0 {
+		return fields
+	}
+	for _, m := range headerPair.FindAllStringSubmatch(line, -1) {
+		v := m[2]
+		if s, err := strconv.Unquote(v); err == nil {
+			fields[m[1]] = s
+			continue
+		}
+		fields[m[1]] = InferType(v)
+	}
+	return fields
+}
+
+// ApplyContext adds the header fields in effect for Lines[i] to entry.
+// Fields the entry already has are kept.
+func (c *Chunk) ApplyContext(i int, entry *LogEntry) {
+	if i >= len(c.Context) {
+		return
+	}
+	for k, v := range c.Context[i] {
+		if _, ok := entry.Fields[k]; !ok {
+			entry.Fields[k] = v
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// binarySniffSize is how much of a file's decoded content is checked for
+// NUL bytes when deciding whether it is binary.
+const binarySniffSize = 8 * 1024
+
+// maxControlRatio is the share of control bytes above which content whose
+// records are separated by NULs is taken to be binary.
+const maxControlRatio = 0.1
+
+// ExpandOptions controls how directory and glob inputs are expanded.
+type ExpandOptions struct {
+	Recursive bool     // Descend into subdirectories (--recursive)
+	Include   []string // Base-name patterns a file must match, if any (--include)
+	Exclude   []string // Base-name patterns that drop a file (--exclude)
+
+	// RecordSep is the record separator the files are read with
+	// (--record-sep), if any. When it matches a NUL byte, files are not
+	// taken to be binary for holding NULs (see IsBinary).
+	RecordSep *regexp.Regexp
+}
+
+// ExpandInputs turns the input arguments into the list of files to read.
+// Glob patterns and directories expand to their files in sorted order,
+// filtered by Include and Exclude and with binary files skipped; a
+// directory lists only its own files unless Recursive is set. Plain file
+// arguments, "-" (stdin) and remote ssh:// inputs are kept as given. Cloud
+// object URLs ending in "/" or with glob patterns are listed with
+// ListBlobs; binary objects are not sniffed. Argument order is preserved
+// and a file named twice is read once. Binary files that were skipped are
+// returned separately so callers can report them.
+func ExpandInputs(args []string, opts ExpandOptions) (files, skipped []string, err error) {
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, arg := range args {
+		if arg == "-" || IsRemote(arg) {
+			add(arg)
+			continue
+		}
+		if IsBlob(arg) {
+			if !isGlob(arg) && !strings.HasSuffix(arg, "/") {
+				add(arg)
+				continue
+			}
+			objects, err := ListBlobs(arg, opts.Recursive)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(objects) == 0 {
+				return nil, nil, fmt.Errorf("input %q: no objects match", arg)
+			}
+			for _, obj := range objects {
+				if opts.selects(path.Base(obj)) {
+					add(obj)
+				}
+			}
+			continue
+		}
+
+		var candidates []string
+		if isGlob(arg) {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, nil, fmt.Errorf("input %q: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, nil, fmt.Errorf("input %q: no files match", arg)
+			}
+			for _, m := range matches {
+				found, err := listFiles(m, opts.Recursive)
+				if err != nil {
+					return nil, nil, err
+				}
+				candidates = append(candidates, found...)
+			}
+		} else {
+			info, err := os.Stat(arg)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !info.IsDir() {
+				add(arg)
+				continue
+			}
+			if candidates, err = listFiles(arg, opts.Recursive); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, path := range candidates {
+			if seen[path] || !opts.selects(filepath.Base(path)) {
+				continue
+			}
+			binary, err := IsBinary(path, opts.RecordSep)
+			if err != nil {
+				return nil, nil, err
+			}
+			if binary {
+				skipped = append(skipped, path)
+				continue
+			}
+			add(path)
+		}
+	}
+	return files, skipped, nil
+}
+
+// selects reports whether a file's base name passes Include and Exclude.
+func (o ExpandOptions) selects(name string) bool {
+	for _, p := range o.Exclude {
+		if ok, _ := filepath.Match(p, name); ok {
+			return false
+		}
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	for _, p := range o.Include {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// listFiles returns path itself when it is a file, or the regular files in
+// it, sorted, when it is a directory.
+func listFiles(path string, recursive bool) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// IsBinary reports whether the file at path looks binary: its content,
+// decompressed when compressed, has a NUL byte near the start. Compressed
+// logs are therefore not mistaken for binaries. When the records are
+// separated by NULs, that is when sep matches one, NULs are expected, and
+// the content is binary when more than a tenth of its other bytes are
+// control characters instead.
+func IsBinary(path string, sep *regexp.Regexp) (bool, error) {
+	rc, err := openReader(path)
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+
+	buf := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(rc, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if sep == nil || !sep.Match([]byte{0}) {
+		return bytes.IndexByte(buf[:n], 0) >= 0, nil
+	}
+	return controlRatio(buf[:n]) > maxControlRatio, nil
+}
+
+// controlRatio returns the share of the bytes of data other than NULs that
+// are control characters, counting neither whitespace nor ESC, which
+// colored logs hold.
+func controlRatio(data []byte) float64 {
+	control, total := 0, 0
+	for _, c := range data {
+		switch {
+		case c == 0:
+			continue
+		case c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\v' && c != 0x1b, c == 0x7f:
+			control++
+		}
+		total++
+	}
+	if total == 0 {
+		if len(data) > 0 {
+			return 1 // Nothing but NULs
+		}
+		return 0
+	}
+	return float64(control) / float64(total)
+}
+
+// EachFile calls fn for every path, running at most jobs calls at once
+// (-j); jobs below 1 means one at a time. It waits for all calls and
+// returns their errors joined.
+func EachFile(paths []string, jobs int, fn func(path string) error) error {
+	jobs = max(jobs, 1)
+	sem := make(chan struct{}, jobs)
+	errs := make([]error, len(paths))
+
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(path)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestExpandInputsBinary checks that NUL-separated records are only
+// skipped as binary when they are not read with a NUL separator.
+func TestExpandInputsBinary(t *testing.T) {
+	dir := t.TempDir()
+	exe := make([]byte, 4096)
+	for i := range exe {
+		exe[i] = byte(i * 7)
+	}
+	files := map[string]string{
+		"app.log":  "{\"level\":\"info\"}\n",
+		"app.bin":  string(exe),
+		"app.nul":  strings.Repeat("{\"level\":\"info\"}\x00", 100),
+		"app.zero": strings.Repeat("\x00", 100),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nul, err := ParseRecordSeparator("nul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		sep     *regexp.Regexp
+		skipped []string
+	}{
+		{nil, []string{"app.bin", "app.nul", "app.zero"}},
+		{regexp.MustCompile(`\n\n`), []string{"app.bin", "app.nul", "app.zero"}},
+		{nul, []string{"app.bin", "app.zero"}},
+	}
+	for _, tt := range tests {
+		_, skipped, err := ExpandInputs([]string{dir}, ExpandOptions{RecordSep: tt.sep})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, path := range skipped {
+			skipped[i] = filepath.Base(path)
+		}
+		if !reflect.DeepEqual(skipped, tt.skipped) {
+			t.Errorf("sep %v: skipped %q, want %q", tt.sep, skipped, tt.skipped)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JSONParser parses JSON object lines and flattens nested objects into
+// dotted keys (user.profile.name) and arrays into indexed keys (tags[0]).
+// Numbers are typed as LogfmtParser types them (see Number).
+type JSONParser struct{}
+
+// NewJSONParser creates a new JSONParser.
+func NewJSONParser() *JSONParser {
+	return &JSONParser{}
+}
+
+// CanParse checks if the line looks like a JSON object.
+func (p *JSONParser) CanParse(line string) bool {
+	s := strings.TrimSpace(line)
+	return len(s) >= 2 && s[0] == '{' && s[len(s)-1] == '}'
+}
+
+// Parse converts a JSON object line into a LogEntry.
+func (p *JSONParser) Parse(line string) (*LogEntry, error) {
+	var obj map[string]any
+	if err := decodeJSON(line, &obj); err != nil {
+		return nil, err
+	}
+
+	entry := NewLogEntry(line, 0)
+	for k, v := range obj {
+		Flatten(k, v, entry.Fields)
+	}
+	return entry, nil
+}
+
+// decodeJSON decodes the JSON text s into v as json.Unmarshal does, but
+// with numbers as json.Number so that Number sees their exact text.
+func decodeJSON(s string, v any) error {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+	return nil
+}
+
+// Flatten stores v under key in fields, recursing into objects and arrays.
+// Numbers are stored in their canonical form (see Number).
+func Flatten(key string, v any, fields map[string]any) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			Flatten(key+"."+k, child, fields)
+		}
+	case []any:
+		for i, child := range t {
+			Flatten(key+"["+strconv.Itoa(i)+"]", child, fields)
+		}
+	default:
+		fields[key] = Number(v)
+	}
+}
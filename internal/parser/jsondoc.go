@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SetJSONDocuments makes the reader yield one record per JSON value
+// instead of per line: each element of a top-level array, or each object
+// of a stream of (possibly pretty-printed) objects. Records are compacted
+// to a single line, and their positions are those of the value's first
+// byte. Input that starts with "[{" or with a line holding only "{" is
+// read this way even without it.
+func (r *StreamReader) SetJSONDocuments() {
+	r.jsonDocs = true
+}
+
+// IsJSONDocument reports whether prefix, the start of an input, looks
+// like a JSON array of objects or a pretty-printed object rather than
+// JSON lines.
+func IsJSONDocument(prefix []byte) bool {
+	s := bytes.TrimLeft(prefix, " \t\r\n")
+	switch {
+	case len(s) == 0:
+		return false
+	case s[0] == '[':
+		s = bytes.TrimLeft(s[1:], " \t\r\n")
+		return len(s) > 0 && s[0] == '{'
+	case s[0] == '{':
+		line, _, _ := bytes.Cut(s[1:], []byte("\n"))
+		return len(bytes.TrimSpace(line)) == 0
+	}
+	return false
+}
+
+// documents reports whether an input starting with prefix is read as JSON
+// documents.
+func (r *StreamReader) documents(prefix []byte) bool {
+	return r.jsonDocs || !r.multiline && r.recordSep == nil && IsJSONDocument(prefix)
+}
+
+// scanDocuments calls fn with each JSON value of rd, compacted, and the
+// position it starts at, within the window set by SetLimits (applied to
+// the values' starting lines).
+func (r *StreamReader) scanDocuments(rd *bufio.Reader, fn func(rec string, pos position) bool) error {
+	lines := &lineCounter{r: rd}
+	dec := json.NewDecoder(lines)
+	dec.UseNumber()
+
+	prefix, _ := rd.Peek(IDPrefixSize)
+	first := bytes.TrimLeft(prefix, " \t\r\n")
+	array := len(first) > 0 && first[0] == '['
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("parser: JSON document: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	for !array || dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF && !array {
+				return nil
+			}
+			return fmt.Errorf("parser: JSON document at line %d: %w", lines.line(dec.InputOffset()), err)
+		}
+		start := dec.InputOffset() - int64(len(raw))
+		pos := position{line: lines.line(start), offset: start}
+		if pos.line <= r.skip {
+			continue
+		}
+		if r.head > 0 && pos.line > r.skip+r.head {
+			return nil
+		}
+		buf.Reset()
+		if err := json.Compact(&buf, raw); err != nil {
+			return fmt.Errorf("parser: JSON document at line %d: %w", pos.line, err)
+		}
+		if !fn(buf.String(), pos) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// lineCounter records where the newlines of a stream are, so byte offsets
+// reported by a json.Decoder can be turned into line numbers. Offsets must
+// be looked up in increasing order; newlines before the last one looked up
+// are forgotten.
+type lineCounter struct {
+	r        io.Reader
+	read     int64   // Bytes read so far
+	newlines []int64 // Offsets of newlines not yet passed
+	passed   int     // Newlines before the last offset looked up
+}
+
+func (c *lineCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			c.newlines = append(c.newlines, c.read+int64(i))
+		}
+	}
+	c.read += int64(n)
+	return n, err
+}
+
+// line returns the line number, from 1, of the byte at offset.
+func (c *lineCounter) line(offset int64) int {
+	i := 0
+	for i < len(c.newlines) && c.newlines[i] < offset {
+		i++
+	}
+	c.passed += i
+	c.newlines = c.newlines[i:]
+	return c.passed + 1
+}
@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Synthetic fields set by K8sParser on entries from pod logs.
+const (
+	PodField       = "_pod"
+	ContainerField = "_container"
+	NamespaceField = "_namespace"
+)
+
+// KubectlCommand is the kubectl client used for pod log sources. It uses
+// the current kubeconfig context and its credentials.
+var KubectlCommand = "kubectl"
+
+// DefaultK8sStreams is how many pod log streams are read at once unless
+// K8sOptions.MaxStreams says otherwise.
+const DefaultK8sStreams = 50
+
+// K8sOptions selects the container logs read by flog k8s.
+type K8sOptions struct {
+	Namespace  string // -n; empty for the context's namespace
+	Selector   string // -l label selector, such as app=web
+	Container  string // -c; empty for all containers
+	Follow     bool   // --follow: keep streaming new lines
+	Since      string // --since: a duration (1h) or an RFC 3339 time
+	MaxStreams int    // Pods and containers streamed concurrently
+}
+
+// args builds the kubectl logs arguments. Every line is prefixed with its
+// pod and container, which K8sParser turns into fields.
+func (o K8sOptions) args() ([]string, error) {
+	if o.Selector == "" {
+		return nil, fmt.Errorf("k8s: a label selector is required")
+	}
+	args := []string{"logs", "--prefix", "--ignore-errors", "-l", o.Selector}
+	if o.Namespace != "" {
+		args = append(args, "-n", o.Namespace)
+	}
+	if o.Container != "" {
+		args = append(args, "-c", o.Container)
+	} else {
+		args = append(args, "--all-containers")
+	}
+	if o.Follow {
+		args = append(args, "--follow")
+	}
+	if o.Since != "" {
+		if _, err := time.ParseDuration(o.Since); err == nil {
+			args = append(args, "--since="+o.Since)
+		} else if _, err := time.Parse(time.RFC3339, o.Since); err == nil {
+			args = append(args, "--since-time="+o.Since)
+		} else {
+			return nil, fmt.Errorf("k8s: --since %q: want a duration or an RFC 3339 time", o.Since)
+		}
+	}
+	streams := o.MaxStreams
+	if streams <= 0 {
+		streams = DefaultK8sStreams
+	}
+	return append(args, "--max-log-requests="+strconv.Itoa(streams)), nil
+}
+
+// OpenK8s streams the logs of the containers selected by opts, merged as
+// lines arrive, for reading with StreamReader.ScanChunks and parsing with
+// K8sParser.
+func OpenK8s(opts K8sOptions) (io.ReadCloser, error) {
+	args, err := opts.args()
+	if err != nil {
+		return nil, err
+	}
+	return startRemote("k8s "+opts.Selector, KubectlCommand, args)
+}
+
+// K8sParser wraps a Parser for merged pod logs: it strips the
+// "[pod/NAME/CONTAINER] " prefix kubectl adds, parses the rest with the
+// wrapped parser and sets PodField, ContainerField and, when known,
+// NamespaceField.
+type K8sParser struct {
+	Parser
+	Namespace string
+}
+
+// NewK8sParser wraps p for logs from namespace.
+func NewK8sParser(p Parser, namespace string) *K8sParser {
+	return &K8sParser{Parser: p, Namespace: namespace}
+}
+
+// Unwrap returns the wrapped parser.
+func (p *K8sParser) Unwrap() Parser {
+	return p.Parser
+}
+
+// Rewrap returns a copy of p wrapping inner.
+func (p *K8sParser) Rewrap(inner Parser) Parser {
+	c := *p
+	c.Parser = inner
+	return &c
+}
+
+// Parse parses the message of line and tags it with its pod and container.
+func (p *K8sParser) Parse(line string) (*LogEntry, error) {
+	pod, container, msg := splitK8sPrefix(line)
+	entry, err := p.Parser.Parse(msg)
+	if err != nil || pod == "" {
+		return entry, err
+	}
+	entry.Fields[PodField] = pod
+	entry.Fields[ContainerField] = container
+	if p.Namespace != "" {
+		entry.Fields[NamespaceField] = p.Namespace
+	}
+	return entry, nil
+}
+
+// CanParse reports whether the wrapped parser handles the message of line.
+func (p *K8sParser) CanParse(line string) bool {
+	_, _, msg := splitK8sPrefix(line)
+	return p.Parser.CanParse(msg)
+}
+
+// splitK8sPrefix splits "[pod/NAME/CONTAINER] message". Lines without the
+// prefix are returned whole as the message.
+func splitK8sPrefix(line string) (pod, container, msg string) {
+	rest, ok := strings.CutPrefix(line, "[pod/")
+	if !ok {
+		return "", "", line
+	}
+	tag, msg, ok := strings.Cut(rest, "] ")
+	if !ok {
+		if tag, ok = strings.CutSuffix(rest, "]"); !ok {
+			return "", "", line
+		}
+	}
+	pod, container, ok = strings.Cut(tag, "/")
+	if !ok || pod == "" {
+		return "", "", line
+	}
+	return pod, container, msg
+}
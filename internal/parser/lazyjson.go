@@ -0,0 +1,248 @@
+package parser
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LazyJSON reports whether p parses line as JSONParser alone would, so
+// that ExtractJSON can stand in for it when only some fields are needed.
+func LazyJSON(p Parser, line string) bool {
+	switch t := p.(type) {
+	case *JSONParser:
+		return t.CanParse(line)
+	case *AutoParser:
+		_, ok := t.detect(line).(*JSONParser)
+		return ok && !strings.ContainsAny(line, "\r\n")
+	}
+	return false
+}
+
+// ExtractJSON returns an entry holding only the fields of the JSON object
+// line that keys can refer to. Members that hold such a field are decoded
+// and flattened, objects on the way to one (user in user.id) are scanned
+// the same way, and every other member is skipped over without decoding
+// or allocating. It returns false when line is not an object the scan can
+// read, in which case Parse reports the error. Values inside skipped
+// members are not validated.
+func ExtractJSON(line string, keys []string) (*LogEntry, bool) {
+	s := jsonScanner{s: line}
+	entry := NewLogEntry(line, 0)
+	s.space()
+	if !s.extractObject("", keys, entry.Fields) || !s.end() {
+		return nil, false
+	}
+	return entry, true
+}
+
+// extractObject scans the object at the current position, storing the
+// wanted fields below prefix.
+func (j *jsonScanner) extractObject(prefix string, keys []string, fields map[string]any) bool {
+	if !j.consume('{') {
+		return false
+	}
+	if j.space(); j.consume('}') {
+		return true
+	}
+	for {
+		j.space()
+		name, ok := j.key()
+		if !ok {
+			return false
+		}
+		if j.space(); !j.consume(':') {
+			return false
+		}
+		j.space()
+		name = prefix + name
+
+		switch want := wantMember(name, keys); {
+		case want == wantBelow && j.i < len(j.s) && j.s[j.i] == '{':
+			if !j.extractObject(name+".", keys, fields) {
+				return false
+			}
+		case want != wantNone:
+			start := j.i
+			if !j.skipValue() {
+				return false
+			}
+			v, ok := decodeJSONValue(j.s[start:j.i])
+			if !ok {
+				return false
+			}
+			Flatten(name, v, fields)
+		default:
+			if !j.skipValue() {
+				return false
+			}
+		}
+
+		j.space()
+		if j.consume(',') {
+			continue
+		}
+		return j.consume('}')
+	}
+}
+
+// Results of wantMember.
+const (
+	wantNone  = iota
+	wantAll   // A key names the member or an element of it
+	wantBelow // Keys only name fields of the member's object
+)
+
+// wantMember reports how the member name is referred to by keys.
+func wantMember(name string, keys []string) int {
+	want := wantNone
+	for _, k := range keys {
+		rest, ok := strings.CutPrefix(k, name)
+		switch {
+		case !ok:
+		case rest == "" || rest[0] == '[':
+			return wantAll
+		case rest[0] == '.':
+			want = wantBelow
+		}
+	}
+	return want
+}
+
+// decodeJSONValue decodes one JSON value as JSONParser would, taking
+// shortcuts for plain strings, numbers and literals.
+func decodeJSONValue(raw string) (any, bool) {
+	switch raw[0] {
+	case '"':
+		if s := raw[1 : len(raw)-1]; !strings.ContainsAny(s, `\"`) && utf8.ValidString(s) {
+			return s, true
+		}
+	case 't', 'f', 'n':
+		switch raw {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		case "null":
+			return nil, true
+		}
+		return nil, false
+	case '{', '[':
+	default:
+		if (raw[0] == '-' || raw[0] >= '0' && raw[0] <= '9') && strings.Trim(raw, "0123456789+-.eE") == "" {
+			if _, err := strconv.ParseFloat(raw, 64); err == nil {
+				return json.Number(raw), true
+			}
+		}
+	}
+	var v any
+	if err := decodeJSON(raw, &v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// jsonScanner walks a JSON text without decoding it.
+type jsonScanner struct {
+	s string
+	i int
+}
+
+func (j *jsonScanner) space() {
+	for j.i < len(j.s) {
+		switch j.s[j.i] {
+		case ' ', '\t', '\n', '\r':
+			j.i++
+		default:
+			return
+		}
+	}
+}
+
+func (j *jsonScanner) consume(c byte) bool {
+	if j.i < len(j.s) && j.s[j.i] == c {
+		j.i++
+		return true
+	}
+	return false
+}
+
+// end reports whether only whitespace is left.
+func (j *jsonScanner) end() bool {
+	j.space()
+	return j.i == len(j.s)
+}
+
+// key reads a member name, unescaping it only when it has escapes.
+func (j *jsonScanner) key() (string, bool) {
+	start := j.i
+	if !j.skipString() {
+		return "", false
+	}
+	raw := j.s[start:j.i]
+	if !strings.Contains(raw, `\`) {
+		return raw[1 : len(raw)-1], true
+	}
+	var name string
+	if err := json.Unmarshal([]byte(raw), &name); err != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// skipString moves past a string starting at the current position.
+func (j *jsonScanner) skipString() bool {
+	if !j.consume('"') {
+		return false
+	}
+	for j.i < len(j.s) {
+		switch j.s[j.i] {
+		case '\\':
+			j.i += 2
+		case '"':
+			j.i++
+			return true
+		default:
+			j.i++
+		}
+	}
+	return false
+}
+
+// skipValue moves past the value starting at the current position,
+// matching brackets and strings but not checking what lies between.
+func (j *jsonScanner) skipValue() bool {
+	if j.i >= len(j.s) {
+		return false
+	}
+	switch j.s[j.i] {
+	case '"':
+		return j.skipString()
+	case '{', '[':
+		depth := 0
+		for j.i < len(j.s) {
+			switch j.s[j.i] {
+			case '"':
+				if !j.skipString() {
+					return false
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+			j.i++
+			if depth == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	start := j.i
+	for j.i < len(j.s) && !strings.ContainsRune(",}] \t\r\n", rune(j.s[j.i])) {
+		j.i++
+	}
+	return j.i > start
+}
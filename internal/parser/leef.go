@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrNotLEEF is returned when a line is not an IBM QRadar LEEF event.
+var ErrNotLEEF = errors.New("parser: not a LEEF event")
+
+// leefHeaderFields names the pipe-separated header fields after "LEEF:".
+var leefHeaderFields = []string{"leef_version", "vendor", "product", "product_version", "event_id"}
+
+// LEEFParser parses IBM QRadar Log Event Extended Format events, version
+// 1.0 (tab-separated attributes) and 2.0 (attributes separated by the
+// delimiter declared in the header):
+//
+//	LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^sev=5
+//
+// Header fields become leef_version, vendor, product, product_version and
+// event_id; attributes keep their keys. Values are type-inferred. A syslog
+// header before "LEEF:" is kept in syslog_header.
+type LEEFParser struct{}
+
+// NewLEEFParser creates a new LEEFParser.
+func NewLEEFParser() *LEEFParser {
+	return &LEEFParser{}
+}
+
+// CanParse checks if the line carries a LEEF header.
+func (p *LEEFParser) CanParse(line string) bool {
+	_, _, _, err := splitLEEF(line)
+	return err == nil
+}
+
+// Parse converts a LEEF event into a LogEntry.
+func (p *LEEFParser) Parse(line string) (*LogEntry, error) {
+	prefix, header, attrs, err := splitLEEF(line)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := NewLogEntry(line, 0)
+	setString(entry, "syslog_header", prefix)
+	for i, name := range leefHeaderFields {
+		if v := unescapeHeader(header[i]); v != "" {
+			entry.Fields[name] = v
+		}
+	}
+
+	delim := byte('\t')
+	if len(header) > len(leefHeaderFields) {
+		delim, _ = parseDelimiter(header[len(leefHeaderFields)])
+	}
+	for _, pair := range strings.Split(attrs, string(delim)) {
+		key, value, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); ok && key != "" && value != "" {
+			entry.Fields[key] = InferType(value)
+		}
+	}
+	return entry, nil
+}
+
+// splitLEEF separates the syslog prefix, the header fields (including the
+// 2.0 delimiter field) and the attribute section of a LEEF event.
+func splitLEEF(line string) (prefix string, header []string, attrs string, err error) {
+	prefix, body, ok := cutSecurityPrefix(line, "LEEF:")
+	if !ok {
+		return "", nil, "", ErrNotLEEF
+	}
+
+	n := len(leefHeaderFields)
+	if strings.HasPrefix(body, "2.") {
+		n++ // Delimiter field
+	}
+	parts := splitHeader(body, n)
+	if len(parts) != n+1 {
+		return "", nil, "", ErrNotLEEF
+	}
+	if n > len(leefHeaderFields) {
+		if _, ok := parseDelimiter(parts[n-1]); !ok {
+			return "", nil, "", ErrNotLEEF
+		}
+	}
+	return prefix, parts[:n], parts[n], nil
+}
+
+// parseDelimiter decodes a LEEF 2.0 delimiter: a single character or a
+// hex code such as 0x09 or x5E.
+func parseDelimiter(s string) (byte, bool) {
+	if len(s) == 1 {
+		return s[0], true
+	}
+	lower := strings.ToLower(s)
+	hex, ok := strings.CutPrefix(lower, "0x")
+	if !ok {
+		hex, ok = strings.CutPrefix(lower, "x")
+	}
+	if !ok || hex == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(hex, 16, 8)
+	return byte(n), err == nil
+}
@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLEEFParser(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]any
+	}{
+		// 1.0 separates attributes with tabs.
+		{"LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=10.50.1.1\tdst=2.10.20.20\tsev=5\tusrName=joe.black", map[string]any{
+			"leef_version": "1.0", "vendor": "Microsoft", "product": "MSExchange", "product_version": "4.0 SP1", "event_id": "15345",
+			"src": "10.50.1.1", "dst": "2.10.20.20", "sev": int64(5), "usrName": "joe.black",
+		}},
+		// 2.0 declares its delimiter, as a character or a hex code.
+		{`<13>Jan 18 11:07:53 host LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^msg=a b=c^empty=`, map[string]any{
+			"syslog_header": "<13>Jan 18 11:07:53 host", "leef_version": "2.0", "vendor": "Lancope", "product": "StealthWatch",
+			"product_version": "1.0", "event_id": "41", "src": "10.0.1.8", "dst": "10.0.0.5", "msg": "a b=c",
+		}},
+		{`LEEF:2.0|V|P|1|7|0x7C|a=1|b=two`, map[string]any{
+			"leef_version": "2.0", "vendor": "V", "product": "P", "product_version": "1", "event_id": "7", "a": int64(1), "b": "two",
+		}},
+	}
+	p := NewLEEFParser()
+	for _, tt := range tests {
+		if !p.CanParse(tt.line) {
+			t.Errorf("CanParse(%q) = false", tt.line)
+			continue
+		}
+		entry, err := p.Parse(tt.line)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(entry.Fields, tt.want) {
+			t.Errorf("Parse(%q)\n got %#v\nwant %#v", tt.line, entry.Fields, tt.want)
+		}
+	}
+}
+
+func TestLEEFParserRejects(t *testing.T) {
+	p := NewLEEFParser()
+	for _, line := range []string{
+		`LEEF:1.0|V|P|1`,
+		`LEEF:2.0|V|P|1|7|zz|a=1`,
+		`LEEF:|V|P|1|7|a=1`,
+		`CEF:0|V|P|1|100|name|5|`,
+	} {
+		if p.CanParse(line) {
+			t.Errorf("CanParse(%q) = true", line)
+		}
+		if _, err := p.Parse(line); err != ErrNotLEEF {
+			t.Errorf("Parse(%q): err = %v, want ErrNotLEEF", line, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package parser
+
+import "bytes"
+
+// cutLine finds the first line of data. Lines end at "\n", "\r\n" or a
+// lone "\r", so Windows, classic Mac and mixed files split alike, and any
+// run of carriage returns before a newline is dropped with it. advance is
+// the length of the line with its terminator. ok is false when data holds
+// no complete line and more may follow (!atEOF).
+func cutLine(data []byte, atEOF bool) (line []byte, advance int, ok bool) {
+	nl := bytes.IndexByte(data, '\n')
+	seg := data
+	if nl >= 0 {
+		seg = data[:nl]
+	}
+	if cr := bytes.IndexByte(seg, '\r'); cr >= 0 {
+		run := cr
+		for run < len(seg) && seg[run] == '\r' {
+			run++
+		}
+		switch {
+		case run < len(seg): // A lone \r ends the line
+			return seg[:cr], cr + 1, true
+		case nl >= 0:
+			return seg[:cr], nl + 1, true
+		case atEOF:
+			return seg[:cr], len(data), true
+		}
+		return nil, 0, false // \r at the end of the buffer: \n may follow
+	}
+	switch {
+	case nl >= 0:
+		return seg, nl + 1, true
+	case atEOF && len(data) > 0:
+		return data, len(data), true
+	}
+	return nil, 0, false
+}
+
+// scanLines is a bufio.SplitFunc like bufio.ScanLines that also ends
+// lines at a lone "\r" (see cutLine).
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	line, advance, ok := cutLine(data, atEOF)
+	if !ok {
+		return 0, nil, nil
+	}
+	return advance, line, nil
+}
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ErrLineTooLong is returned when a line exceeds the maximum line size
+// under OversizeFail.
+var ErrLineTooLong = errors.New("line too long")
+
+// OversizeMode selects what happens to lines longer than the maximum line
+// size (--oversize).
+type OversizeMode int
+
+const (
+	OversizeFail     OversizeMode = iota // Stop reading the input with an error
+	OversizeSplit                        // Emit the line in pieces of the maximum size
+	OversizeTruncate                     // Keep the first piece, drop the rest
+	OversizeSkip                         // Drop the line
+)
+
+var oversizeModes = map[string]OversizeMode{
+	"fail":     OversizeFail,
+	"split":    OversizeSplit,
+	"truncate": OversizeTruncate,
+	"skip":     OversizeSkip,
+}
+
+// ParseOversizeMode parses an --oversize value: fail, split, truncate or
+// skip.
+func ParseOversizeMode(s string) (OversizeMode, error) {
+	m, ok := oversizeModes[s]
+	if !ok {
+		return 0, fmt.Errorf("parser: unknown oversize mode %q (want fail, split, truncate or skip)", s)
+	}
+	return m, nil
+}
+
+// String returns the --oversize name of the mode.
+func (m OversizeMode) String() string {
+	for name, mode := range oversizeModes {
+		if mode == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("OversizeMode(%d)", int(m))
+}
+
+// SetMaxLineSize sets the longest line, in bytes, the reader accepts
+// (default DefaultBufferSize) and what to do with longer ones. The read
+// buffer starts small and grows up to n as long lines arrive. Lines cut
+// by OversizeSplit or OversizeTruncate end at a UTF-8 boundary, and every
+// piece of a split line has the line's number.
+func (r *StreamReader) SetMaxLineSize(n int, mode OversizeMode) {
+	if n > 0 {
+		r.bufferSize = n
+	}
+	r.oversize = mode
+}
+
+// lineTooLong is the error for line being longer than max under
+// OversizeFail.
+func lineTooLong(line, max int) error {
+	return fmt.Errorf("parser: line %d is longer than %d bytes (see --max-line-size and --oversize): %w", line, max, ErrLineTooLong)
+}
+
+// Oversized returns the number of lines found longer than the maximum
+// line size so far, whether split, truncated or skipped.
+func (r *StreamReader) Oversized() int64 {
+	return r.oversized.Load()
+}
+
+// lineSplitter wraps a split function to bound token length.
+type lineSplitter struct {
+	split func(data []byte, atEOF bool) (int, []byte, error)
+	max   int
+	mode  OversizeMode
+	count func() // Called once per oversized line
+
+	continued bool // The last token continues a split line
+	piece     bool // The next token continues a split line
+	discard   bool // Dropping the rest of an oversized line
+	dropped   int  // Lines skipped since the last token
+}
+
+func (s *lineSplitter) next(data []byte, atEOF bool) (int, []byte, error) {
+	if s.discard {
+		n, tok, err := s.split(data, atEOF)
+		switch {
+		case err != nil:
+			return n, nil, err
+		case tok != nil:
+			s.discard = false
+			return n, nil, nil
+		}
+		keep := 0
+		if len(data) > 0 && data[len(data)-1] == '\r' {
+			keep = 1 // A \n may follow
+		}
+		return len(data) - keep, nil, nil
+	}
+
+	n, tok, err := s.split(data, atEOF)
+	if err != nil {
+		return n, tok, err
+	}
+	if tok != nil && len(tok) <= s.max {
+		s.continued, s.piece = s.piece, false
+		return n, tok, nil
+	}
+	if tok == nil && (len(data) <= s.max || len(data) == s.max+1 && data[s.max] == '\r') {
+		// Not yet longer than the maximum (a \n may follow a \r): read on.
+		return n, nil, nil
+	}
+
+	if !s.piece {
+		s.count()
+	}
+	cut := s.max
+	for cut > 0 && cut < len(data) && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		cut = s.max
+	}
+	switch s.mode {
+	case OversizeSplit:
+		s.continued, s.piece = s.piece, true
+		return cut, data[:cut], nil
+	case OversizeTruncate:
+		s.continued = false
+		if tok != nil {
+			return n, data[:cut], nil
+		}
+		s.discard = true
+		return cut, data[:cut], nil
+	case OversizeSkip:
+		s.dropped++
+		if tok != nil {
+			return n, nil, nil
+		}
+		s.discard = true
+		return s.max, nil, nil
+	}
+	return 0, nil, ErrLineTooLong
+}
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestMaxLineSizeOneByteReads(t *testing.T) {
+	const max = 8
+	// Enough short lines to get past the bytes peeked for the file ID, so
+	// the line under test arrives one byte per read.
+	pad := strings.Repeat("ok\n", 2*IDPrefixSize/3)
+	long := strings.Repeat("x", max+1)
+	tests := []struct {
+		mode OversizeMode
+		line string
+		want []string
+		err  error
+	}{
+		{OversizeFail, strings.Repeat("x", max-1), []string{strings.Repeat("x", max-1), "ok"}, nil},
+		{OversizeFail, strings.Repeat("x", max), []string{strings.Repeat("x", max), "ok"}, nil},
+		{OversizeFail, long, nil, ErrLineTooLong},
+		{OversizeSplit, strings.Repeat("x", max-1), []string{strings.Repeat("x", max-1), "ok"}, nil},
+		{OversizeSplit, strings.Repeat("x", max), []string{strings.Repeat("x", max), "ok"}, nil},
+		{OversizeSplit, long, []string{long[:max], "x", "ok"}, nil},
+		{OversizeTruncate, strings.Repeat("x", max-1), []string{strings.Repeat("x", max-1), "ok"}, nil},
+		{OversizeTruncate, strings.Repeat("x", max), []string{strings.Repeat("x", max), "ok"}, nil},
+		{OversizeTruncate, long, []string{long[:max], "ok"}, nil},
+		{OversizeSkip, strings.Repeat("x", max-1), []string{strings.Repeat("x", max-1), "ok"}, nil},
+		{OversizeSkip, strings.Repeat("x", max), []string{strings.Repeat("x", max), "ok"}, nil},
+		{OversizeSkip, long, []string{"ok"}, nil},
+	}
+	for _, tt := range tests {
+		for _, end := range []string{"\n", "\r\n"} {
+			r := NewStreamReader()
+			r.SetMaxLineSize(max, tt.mode)
+			var got []string
+			in := pad + tt.line + end + "ok" + end
+			err := r.ScanLines(iotest.OneByteReader(strings.NewReader(in)), func(line string) bool {
+				got = append(got, line)
+				return true
+			})
+			got = got[min(len(got), strings.Count(pad, "\n")):]
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("%v, %d bytes, %q: err = %v, want %v", tt.mode, len(tt.line), end, err, tt.err)
+				}
+				continue
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("%v, %d bytes, %q: got %q, %v; want %q", tt.mode, len(tt.line), end, got, err, tt.want)
+			}
+			wantOversized := int64(0)
+			if len(tt.line) > max {
+				wantOversized = 1
+			}
+			if n := r.Oversized(); n != wantOversized {
+				t.Errorf("%v, %d bytes, %q: Oversized() = %d, want %d", tt.mode, len(tt.line), end, n, wantOversized)
+			}
+		}
+	}
+}
+
+// TestMaxLineSizeMapped checks that a memory-mapped file is cut to the
+// same lines, and fails on the same line, as a scanned one.
+func TestMaxLineSizeMapped(t *testing.T) {
+	const max = 8
+	pad := strings.Repeat("ok\n", 50)
+	inputs := []string{
+		pad + strings.Repeat("x", max) + "\r\n" + pad,
+		pad + strings.Repeat("x", max+1) + "\n" + pad,
+		pad + strings.Repeat("x", max+1),
+	}
+	defer func(n int64) { MmapThreshold = n }(MmapThreshold)
+	for _, input := range inputs {
+		path := filepath.Join(t.TempDir(), "app.log")
+		if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, mode := range []OversizeMode{OversizeFail, OversizeSplit, OversizeTruncate, OversizeSkip} {
+			var lines [2][]string
+			var errs [2]error
+			for i, threshold := range []int64{0, 1} {
+				MmapThreshold = threshold
+				f, err := os.Open(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r := NewStreamReader()
+				r.SetMaxLineSize(max, mode)
+				errs[i] = r.ScanChunks(f, 7, func(c Chunk) bool {
+					lines[i] = append(lines[i], c.Lines...)
+					return true
+				})
+				f.Close()
+			}
+			if !slices.Equal(lines[0], lines[1]) {
+				t.Errorf("%v, %q: mapped lines %q, scanned %q", mode, input[len(pad):], lines[1], lines[0])
+			}
+			if fmt.Sprint(errs[0]) != fmt.Sprint(errs[1]) {
+				t.Errorf("%v, %q: mapped error %v, scanned %v", mode, input[len(pad):], errs[1], errs[0])
+			}
+		}
+	}
+}
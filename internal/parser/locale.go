@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Locale describes how numbers and month names are written in a language
+// (--locale), for logs that print "1.234,5" or "12. März 2024".
+type Locale struct {
+	Name    string
+	Decimal rune              // Decimal separator
+	Group   []rune            // Accepted digit group separators
+	months  map[string]string // Lowercase month name or abbreviation → English "Jan"
+}
+
+// localeMonths lists month names and abbreviations per language, January
+// first, separated by "|".
+var localeMonths = map[string][12]string{
+	"de": {"januar|jänner|jan", "februar|feb", "märz|mär|mrz", "april|apr", "mai", "juni|jun", "juli|jul", "august|aug", "september|sept|sep", "oktober|okt", "november|nov", "dezember|dez"},
+	"fr": {"janvier|janv", "février|févr|fév", "mars", "avril|avr", "mai", "juin", "juillet|juil", "août", "septembre|sept", "octobre|oct", "novembre|nov", "décembre|déc"},
+	"es": {"enero|ene", "febrero|feb", "marzo|mar", "abril|abr", "mayo|may", "junio|jun", "julio|jul", "agosto|ago", "septiembre|setiembre|sept|sep", "octubre|oct", "noviembre|nov", "diciembre|dic"},
+	"it": {"gennaio|gen", "febbraio|feb", "marzo|mar", "aprile|apr", "maggio|mag", "giugno|giu", "luglio|lug", "agosto|ago", "settembre|set", "ottobre|ott", "novembre|nov", "dicembre|dic"},
+	"nl": {"januari|jan", "februari|feb", "maart|mrt", "april|apr", "mei", "juni|jun", "juli|jul", "augustus|aug", "september|sep", "oktober|okt", "november|nov", "december|dec"},
+	"pt": {"janeiro|jan", "fevereiro|fev", "março|mar", "abril|abr", "maio|mai", "junho|jun", "julho|jul", "agosto|ago", "setembro|set", "outubro|out", "novembro|nov", "dezembro|dez"},
+}
+
+// localeSeparators lists the decimal and group separators per language.
+// French groups with a space, often a no-break or narrow no-break one.
+var localeSeparators = map[string]struct {
+	decimal rune
+	group   []rune
+}{
+	"en": {'.', []rune{','}},
+	"de": {',', []rune{'.'}},
+	"fr": {',', []rune{' ', '\u00a0', '\u202f'}},
+	"es": {',', []rune{'.'}},
+	"it": {',', []rune{'.'}},
+	"nl": {',', []rune{'.'}},
+	"pt": {',', []rune{'.'}},
+}
+
+// localeLayouts are the day-first layouts tried after month names have
+// been translated, e.g. "12. Mar 2024 10:00:00".
+var localeLayouts = []string{
+	"2 Jan 2006 15:04:05.999999999",
+	"2. Jan 2006 15:04:05.999999999",
+	"2 Jan 2006",
+	"2. Jan 2006",
+	"Jan 2, 2006 15:04:05.999999999",
+}
+
+// LookupLocale returns the locale for a name such as "de", "de_DE" or
+// "fr-FR.UTF-8". Only the language part is used.
+func LookupLocale(name string) (*Locale, error) {
+	lang := strings.ToLower(name)
+	if i := strings.IndexAny(lang, "_-."); i >= 0 {
+		lang = lang[:i]
+	}
+	seps, ok := localeSeparators[lang]
+	if !ok {
+		return nil, fmt.Errorf("parser: unsupported locale %q", name)
+	}
+
+	l := &Locale{Name: lang, Decimal: seps.decimal, Group: seps.group, months: make(map[string]string)}
+	for i, names := range localeMonths[lang] {
+		english := time.Month(i + 1).String()[:3]
+		for _, n := range strings.Split(names, "|") {
+			l.months[n] = english
+		}
+	}
+	return l, nil
+}
+
+// ParseNumber parses a number written with the locale's separators, such
+// as "1.234,5" or "3,14" in German. Digit groups must have three digits.
+// Plain numbers without separators are left to InferType and rejected.
+func (l *Locale) ParseNumber(s string) (any, bool) {
+	s = strings.TrimSpace(s)
+	body := strings.TrimLeft(s, "+-")
+	if len(s)-len(body) > 1 || body == "" {
+		return nil, false
+	}
+
+	var digits strings.Builder
+	if s[0] == '-' {
+		digits.WriteByte('-')
+	}
+	group, grouped, decimal := 0, false, false
+	for i, r := range body {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+			group++
+		case r == l.Decimal && !decimal:
+			if i == 0 || (grouped && group != 3) {
+				return nil, false
+			}
+			digits.WriteByte('.')
+			decimal, group = true, 0
+		case l.isGroup(r) && !decimal:
+			if group == 0 || group > 3 || (grouped && group != 3) {
+				return nil, false
+			}
+			grouped, group = true, 0
+		default:
+			return nil, false
+		}
+	}
+	if group == 0 || (!decimal && !grouped) || (grouped && !decimal && group != 3) {
+		return nil, false
+	}
+
+	if !decimal {
+		n, err := strconv.ParseInt(digits.String(), 10, 64)
+		return n, err == nil
+	}
+	f, err := strconv.ParseFloat(digits.String(), 64)
+	return Number(f), err == nil
+}
+
+func (l *Locale) isGroup(r rune) bool {
+	for _, g := range l.Group {
+		if r == g {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTime parses a timestamp whose month is written in the locale's
+// language, such as "12. März 2024 10:00:00" or "3 févr. 2024".
+func (l *Locale) ParseTime(s string) (time.Time, bool) {
+	translated, ok := l.translateMonths(s)
+	if !ok {
+		return time.Time{}, false
+	}
+	if t, ok := parseTimeString(translated); ok {
+		return t, true
+	}
+	translated = strings.TrimSpace(translated)
+	for _, layout := range localeLayouts {
+		if t, err := time.Parse(layout, translated); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// translateMonths replaces localized month names in s with English
+// abbreviations, dropping an abbreviation's trailing dot. It reports
+// whether any name was replaced.
+func (l *Locale) translateMonths(s string) (string, bool) {
+	if len(l.months) == 0 {
+		return s, false
+	}
+	var b strings.Builder
+	replaced := false
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && unicode.IsLetter(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if english, ok := l.months[strings.ToLower(word)]; ok {
+			b.WriteString(english)
+			replaced = true
+			if j < len(runes) && runes[j] == '.' && (j+1 == len(runes) || runes[j+1] == ' ') {
+				j++
+			}
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+	return b.String(), replaced
+}
+
+// LocaleParser wraps a Parser and converts string field values written in
+// a locale's conventions: numbers become int64 or float64 and month-name
+// timestamps become time.Time. Values the wrapped parser already typed
+// are kept, so an ambiguous "1.234" stays 1.234.
+type LocaleParser struct {
+	Parser
+	Locale *Locale
+}
+
+// NewLocaleParser wraps p with locale-aware value conversion.
+func NewLocaleParser(p Parser, l *Locale) *LocaleParser {
+	return &LocaleParser{Parser: p, Locale: l}
+}
+
+// Unwrap returns the wrapped parser.
+func (p *LocaleParser) Unwrap() Parser {
+	return p.Parser
+}
+
+// Rewrap returns a copy of p wrapping inner.
+func (p *LocaleParser) Rewrap(inner Parser) Parser {
+	c := *p
+	c.Parser = inner
+	return &c
+}
+
+// Parse parses line with the wrapped parser and converts its fields.
+func (p *LocaleParser) Parse(line string) (*LogEntry, error) {
+	entry, err := p.Parser.Parse(line)
+	if err != nil {
+		return entry, err
+	}
+	for k, v := range entry.Fields {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if n, ok := p.Locale.ParseNumber(s); ok {
+			entry.Fields[k] = n
+		} else if t, ok := p.Locale.ParseTime(s); ok {
+			entry.Fields[k] = t
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,185 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LogfmtParser is a strict logfmt parser following the Heroku/go-logfmt
+// conventions: keys are runs of non-space characters without '=' or '"',
+// values are bare or double-quoted with backslash escapes, and bare keys
+// without a value are stored as true. Bare values are type-inferred;
+// quoted values are kept as strings.
+type LogfmtParser struct{}
+
+// NewLogfmtParser creates a new LogfmtParser.
+func NewLogfmtParser() *LogfmtParser {
+	return &LogfmtParser{}
+}
+
+// CanParse checks if the line looks like logfmt: it must start with a
+// key=value pair and parse cleanly.
+func (p *LogfmtParser) CanParse(line string) bool {
+	s := strings.TrimLeft(line, " \t")
+	end := strings.IndexAny(s, " \t")
+	if end < 0 {
+		end = len(s)
+	}
+	if eq := strings.IndexByte(s[:end], '='); eq <= 0 {
+		return false
+	}
+	_, err := parseLogfmt(line, nil)
+	return err == nil
+}
+
+// Parse converts a logfmt line into a LogEntry, ignoring a trailing
+// carriage return.
+func (p *LogfmtParser) Parse(line string) (*LogEntry, error) {
+	line = strings.TrimRight(line, "\r")
+	entry := NewLogEntry(line, 0)
+	if _, err := parseLogfmt(line, entry.Fields); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// parseLogfmt scans line into fields (which may be nil to only validate)
+// and returns the number of pairs found.
+func parseLogfmt(line string, fields map[string]any) (int, error) {
+	n := 0
+	i := 0
+	for {
+		for i < len(line) && line[i] <= ' ' {
+			i++
+		}
+		if i >= len(line) {
+			return n, nil
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return n, fmt.Errorf("logfmt: expected key at position %d", i)
+		}
+		n++
+
+		if i >= len(line) || line[i] <= ' ' {
+			if fields != nil {
+				fields[key] = true
+			}
+			continue
+		}
+		if line[i] == '"' {
+			return n, fmt.Errorf("logfmt: unexpected '\"' in key at position %d", i)
+		}
+		i++ // '='
+
+		var value any
+		switch {
+		case i >= len(line) || line[i] <= ' ':
+			value = ""
+		case line[i] == '"':
+			s, next, err := unquoteLogfmt(line, i)
+			if err != nil {
+				return n, err
+			}
+			value, i = s, next
+			if i < len(line) && line[i] > ' ' {
+				return n, fmt.Errorf("logfmt: unexpected %q after quoted value at position %d", line[i], i)
+			}
+		default:
+			start := i
+			for i < len(line) && line[i] > ' ' {
+				if line[i] == '=' || line[i] == '"' {
+					return n, fmt.Errorf("logfmt: unexpected %q in value at position %d", line[i], i)
+				}
+				i++
+			}
+			value = InferType(line[start:i])
+		}
+
+		if fields != nil {
+			fields[key] = value
+		}
+	}
+}
+
+// unquoteLogfmt decodes the quoted value starting at line[i] and returns it
+// with the index just past the closing quote.
+func unquoteLogfmt(line string, i int) (string, int, error) {
+	start := i
+	i++ // opening quote
+
+	var b strings.Builder
+	for i < len(line) {
+		c := line[i]
+		switch c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(line) {
+				return "", i, fmt.Errorf("logfmt: unterminated escape at position %d", i)
+			}
+			i++
+			switch e := line[i]; e {
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if i+4 >= len(line) {
+					return "", i, fmt.Errorf("logfmt: short \\u escape at position %d", i)
+				}
+				r, err := strconv.ParseUint(line[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", i, fmt.Errorf("logfmt: invalid \\u escape at position %d", i)
+				}
+				b.WriteRune(rune(r))
+				i += 4
+			default:
+				return "", i, fmt.Errorf("logfmt: invalid escape '\\%c' at position %d", e, i)
+			}
+			i++
+		default:
+			r, size := utf8.DecodeRuneInString(line[i:])
+			b.WriteRune(r)
+			i += size
+		}
+	}
+	return "", start, fmt.Errorf("logfmt: unterminated quoted value at position %d", start)
+}
+
+// InferType converts a bare value into int64, float64 or bool when it
+// looks like one, and returns it unchanged otherwise. Numbers take their
+// canonical form (see Number); words such as nan and inf stay strings
+// (see IsNumeric).
+func InferType(s string) any {
+	if IsNumeric(s) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return Number(f)
+		}
+	}
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s
+}
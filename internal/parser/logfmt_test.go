@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLogfmtParser(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]any
+	}{
+		{`level=info status=200 ms=1.5 ok=true`, map[string]any{"level": "info", "status": int64(200), "ms": 1.5, "ok": true}},
+		{`msg="hello world" path=/a=b`, nil},
+		{`msg="a=b \"c\"" q="x\\y"`, map[string]any{"msg": `a=b "c"`, "q": `x\y`}},
+		{`msg="line\nnext\ttab é"`, map[string]any{"msg": "line\nnext\ttab é"}},
+		{`debug user=ann`, map[string]any{"debug": true, "user": "ann"}},
+		{`empty= next=1`, map[string]any{"empty": "", "next": int64(1)}},
+		{`code="500" n=500`, map[string]any{"code": "500", "n": int64(500)}},
+		{`v=nan w=inf x=1_000 y=1e3`, map[string]any{"v": "nan", "w": "inf", "x": "1_000", "y": int64(1000)}},
+		{"  a=1\t b=2\r", map[string]any{"a": int64(1), "b": int64(2)}},
+		{`msg="unterminated`, nil},
+		{`msg="bad \q escape"`, nil},
+		{`msg="x"y a=1`, nil},
+		{`k"ey=1`, nil},
+		{`=1`, nil},
+	}
+	p := NewLogfmtParser()
+	for _, tt := range tests {
+		entry, err := p.Parse(tt.line)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v, want an error", tt.line, entry.Fields)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(entry.Fields, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.line, entry.Fields, tt.want)
+		}
+	}
+}
+
+func TestLogfmtCanParse(t *testing.T) {
+	for line, want := range map[string]bool{
+		`level=info msg=started`: true,
+		` a=1`:                   true,
+		`started a=1`:            false,
+		`{"a":1}`:                false,
+		`a="open`:                false,
+		``:                       false,
+	} {
+		if got := NewLogfmtParser().CanParse(line); got != want {
+			t.Errorf("CanParse(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestInferType(t *testing.T) {
+	tests := []struct {
+		s    string
+		want any
+	}{
+		{"200", int64(200)},
+		{"-7", int64(-7)},
+		{"1.5", 1.5},
+		{"2.0", int64(2)},
+		{"1e3", int64(1000)},
+		{"9223372036854775808", 9223372036854775808.0},
+		{"true", true},
+		{"false", false},
+		{"True", "True"},
+		{"nan", "nan"},
+		{"-inf", "-inf"},
+		{"0x10", "0x10"},
+		{"1_000", "1_000"},
+		{"", ""},
+		{"v1.2", "v1.2"},
+	}
+	for _, tt := range tests {
+		if got := InferType(tt.s); got != tt.want {
+			t.Errorf("InferType(%q) = %#v, want %#v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestJSONParserFlattens(t *testing.T) {
+	entry, err := NewJSONParser().Parse(`{"user":{"id":42,"name":"ann"},"tags":["a",{"b":true}],"ms":1.5,"none":null}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"user.id": int64(42), "user.name": "ann", "tags[0]": "a", "tags[1].b": true, "ms": 1.5, "none": nil,
+	}
+	if !reflect.DeepEqual(entry.Fields, want) {
+		t.Errorf("Fields = %#v, want %#v", entry.Fields, want)
+	}
+	for _, bad := range []string{`{"a":1} {"b":2}`, `{"a":}`, `[1,2]`} {
+		if _, err := NewJSONParser().Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestAutoParserDetects(t *testing.T) {
+	tests := []struct {
+		line  string
+		field string
+		want  any
+	}{
+		{`{"level":"error"}`, "level", "error"},
+		{`level=warn msg="disk full"`, "msg", "disk full"},
+		{`10.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /a HTTP/1.1" 404 12`, "status", int64(404)},
+		{"level=info msg=start\r", "msg", "start"},
+		{"level=error msg=panic\ngoroutine 1\nmain.go:10", "msg", "panic\ngoroutine 1\nmain.go:10"},
+		{"plain text\nmore text", "message", "plain text\nmore text"},
+	}
+	p := NewAutoParser()
+	for _, tt := range tests {
+		entry, err := p.Parse(tt.line)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.line, err)
+			continue
+		}
+		if got := entry.Fields[tt.field]; got != tt.want {
+			t.Errorf("Parse(%q): %s = %#v, want %#v", tt.line, tt.field, got, tt.want)
+		}
+	}
+	if _, err := p.Parse("just words"); err != ErrUnknownFormat {
+		t.Errorf("plain line: err = %v, want ErrUnknownFormat", err)
+	}
+}
@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+)
+
+// MmapThreshold is the size from which a local file is memory-mapped and
+// cut into chunks at newline boundaries, with the chunks split into lines
+// in parallel, instead of being read through a single scanner. 0 disables
+// mapping. Compressed files, and inputs read with multiline, header
+// context, JSON documents, a record separator, ANSI stripping, an
+// oversize mode other than OversizeFail or SetLimits, are always scanned.
+// Mapped files fail on lines longer than the maximum line size as scanned
+// ones do.
+//
+// A mapped file truncated while it is read, as by copytruncate, ends the
+// read with an error after the lines read before.
+var MmapThreshold int64 = 64 << 20
+
+// mapping is an input mapped by mapInput.
+type mapping struct {
+	name   string
+	data   []byte // The file from where it was positioned to its end
+	base   int64  // Offset of data in the file
+	fileID string
+	unmap  func() error
+}
+
+// mapInput maps f from its current offset to its end if the reader can
+// take the mapped path for it. ok is false when the input must be scanned.
+func (r *StreamReader) mapInput(f *os.File) (m *mapping, ok bool) {
+	if MmapThreshold <= 0 || r.multiline || r.header != nil || r.jsonDocs || r.recordSep != nil || r.stripANSI || r.oversize != OversizeFail || r.skip > 0 || r.head > 0 {
+		return nil, false
+	}
+	if _, compressed := extensions[filepath.Ext(f.Name())]; compressed {
+		return nil, false
+	}
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, false
+	}
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil || info.Size()-pos < MmapThreshold {
+		return nil, false
+	}
+	whole, unmap, err := mapFile(f, info.Size())
+	if err != nil {
+		return nil, false
+	}
+	data := whole[pos:]
+	prefix := data[:min(len(data), IDPrefixSize)]
+	if DetectCompression(prefix) != CompressNone || IsJSONDocument(prefix) {
+		unmap()
+		return nil, false
+	}
+	fileID := FileID(whole[:min(len(whole), IDPrefixSize)], info)
+	return &mapping{name: f.Name(), data: data, base: pos, fileID: fileID, unmap: unmap}, true
+}
+
+// mappedChunk is a chunk split by mapping.split.
+type mappedChunk struct {
+	Chunk
+	tooLong bool  // The line after the chunk's lines is too long
+	err     error // Reading the data faulted
+}
+
+// scanMapped cuts m into chunks of chunkSize newlines and calls fn for
+// each, in order, until fn returns false. Cutting only looks for newlines;
+// the chunks are split into lines, and checked for lines longer than
+// maxLine, on one goroutine per CPU. A line longer than maxLine ends the
+// scan with ErrLineTooLong, after the lines before it. Nothing refers to
+// the mapped data once scanMapped returns.
+func scanMapped(m *mapping, chunkSize, maxLine int, fn func(Chunk) bool) error {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	data := m.data
+	pending := make(chan chan mappedChunk, runtime.NumCPU())
+	stop := make(chan struct{})
+	var err error // Set before pending is closed
+
+	go func() {
+		defer close(pending)
+		defer m.recoverFault(&err)
+		debug.SetPanicOnFault(true)
+		for off := 0; off < len(data); {
+			end := cutChunk(data, off, chunkSize)
+			res := make(chan mappedChunk, 1)
+			select {
+			case pending <- res:
+			case <-stop:
+				return
+			}
+			go func(off, end int) {
+				res <- m.split(off, end, maxLine)
+			}(off, end)
+			off = end
+		}
+	}()
+
+	finish := func(err error) error {
+		close(stop)
+		for res := range pending {
+			<-res
+		}
+		return err
+	}
+	seq, line := 0, 1
+	for res := range pending {
+		c := <-res
+		c.Seq, c.Start, c.FileID = seq, line, m.fileID
+		if len(c.Lines) > 0 && !fn(c.Chunk) {
+			return finish(nil)
+		}
+		line += len(c.Lines)
+		switch {
+		case c.err != nil:
+			return finish(c.err)
+		case c.tooLong:
+			return finish(lineTooLong(line, maxLine))
+		}
+		seq++
+	}
+	return err
+}
+
+// cutChunk returns the end of the chunk starting at off: just after its
+// n-th newline, or the end of data.
+func cutChunk(data []byte, off, n int) int {
+	for ; n > 0; n-- {
+		i := bytes.IndexByte(data[off:], '\n')
+		if i < 0 {
+			return len(data)
+		}
+		off += i + 1
+	}
+	return off
+}
+
+// split returns a chunk of copies of the lines of m.data[off:end], split
+// and stripped of line endings as by the scanner (see cutLine), up to the
+// first line longer than maxLine.
+func (m *mapping) split(off, end, maxLine int) (c mappedChunk) {
+	defer m.recoverFault(&c.err)
+	debug.SetPanicOnFault(true)
+	for off < end {
+		line, advance, _ := cutLine(m.data[off:end], true)
+		if len(line) > maxLine {
+			c.tooLong = true
+			break
+		}
+		c.Lines = append(c.Lines, string(line))
+		c.Offsets = append(c.Offsets, m.base+int64(off))
+		off += advance
+	}
+	return c
+}
+
+// recoverFault turns the memory fault of reading m after the file was
+// truncated into an error in *err. Other panics go on. It must be
+// deferred on a goroutine that has called debug.SetPanicOnFault(true).
+func (m *mapping) recoverFault(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if _, ok := r.(interface{ Addr() uintptr }); !ok {
+		panic(r)
+	}
+	*err = fmt.Errorf("parser: %s was truncated while being read", m.name)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package parser
+
+import (
+	"errors"
+	"os"
+)
+
+// mapFile is not supported on this platform; inputs are always scanned.
+func mapFile(f *os.File, size int64) ([]byte, func() error, error) {
+	return nil, nil, errors.ErrUnsupported
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxRecordLines bounds how many physical lines one multiline record may
+// absorb, so a missing start pattern cannot buffer a whole file.
+const maxRecordLines = 10000
+
+// continuationPattern matches typical stack-trace continuation lines:
+// indented lines, Java "at ..." frames, "Caused by:" and "... N more".
+var continuationPattern = regexp.MustCompile(`^(\s+\S|\s*at \S|Caused by:|\.\.\. \d+ more|Suppressed:)`)
+
+// IsContinuation reports whether line continues the previous record under
+// automatic multiline detection.
+func IsContinuation(line string) bool {
+	return continuationPattern.MatchString(line)
+}
+
+// multiline assembles physical lines into records.
+type multiline struct {
+	start *regexp.Regexp // nil: automatic continuation detection
+
+	lines []string
+	pos   position // Position of lines[0]
+}
+
+// isStart reports whether line begins a new record.
+func (m *multiline) isStart(line string) bool {
+	if m.start != nil {
+		return m.start.MatchString(line)
+	}
+	return !IsContinuation(line)
+}
+
+// add feeds one physical line and returns a completed record, if any.
+func (m *multiline) add(line string, pos position) (string, position, bool) {
+	if len(m.lines) > 0 && (m.isStart(line) || len(m.lines) >= maxRecordLines) {
+		rec, start, _ := m.flush()
+		m.lines = append(m.lines, line)
+		m.pos = pos
+		return rec, start, true
+	}
+	if len(m.lines) == 0 {
+		m.pos = pos
+	}
+	m.lines = append(m.lines, line)
+	return "", position{}, false
+}
+
+// flush returns the buffered record, if any, and resets the buffer.
+func (m *multiline) flush() (string, position, bool) {
+	if len(m.lines) == 0 {
+		return "", position{}, false
+	}
+	rec := strings.Join(m.lines, "\n")
+	m.lines = m.lines[:0]
+	return rec, m.pos, true
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// maxExactInt is the largest magnitude up to which every integer has an
+// exact float64 representation (2^53).
+const maxExactInt = 1 << 53
+
+// Number returns the canonical form of a numeric field value, so the same
+// number is the same Go value whichever parser read it: status=500 from
+// logfmt and {"status":500} from JSON both become int64(500), and 1.5
+// stays float64. Whole floats become int64 when they are exactly
+// representable; NaN, infinities and larger magnitudes stay float64.
+// JSON numbers (json.Number) are read like logfmt values, so integers
+// that fit int64 stay exact beyond 2^53 in both. Values that are not
+// numbers are returned unchanged.
+func Number(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n
+		}
+		if f, err := t.Float64(); err == nil {
+			return Number(f)
+		}
+		return string(t)
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) <= maxExactInt {
+			return int64(t)
+		}
+	case int:
+		return int64(t)
+	}
+	return v
+}
+
+// IsNumeric reports whether s is a plain decimal number, optionally signed
+// and with an exponent: 500, -1.5, .5, 1e3. strconv.ParseFloat also
+// accepts "nan", "inf", "Infinity", hex floats and "1_000"; log values
+// such as name=nan are words, so callers check IsNumeric first.
+func IsNumeric(s string) bool {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		exp := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == exp {
+			return false
+		}
+	}
+	return i == len(s)
+}
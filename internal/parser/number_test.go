@@ -0,0 +1,127 @@
+package parser_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// TestNumbersAcrossParsers checks that a value reads the same from JSON
+// and logfmt, and so filters the same.
+func TestNumbersAcrossParsers(t *testing.T) {
+	tests := []struct {
+		json, logfmt string // Value as written in each format
+		want         any
+		queries      []string
+	}{
+		{`500`, `500`, int64(500), []string{"v:500", "v>=500", "v>499.5", "v:500.0"}},
+		{`-7`, `-7`, int64(-7), []string{"v:-7", "v<0"}},
+		{`9007199254740993`, `9007199254740993`, int64(9007199254740993), []string{"v:9007199254740993", "v:9007199254740992", "v>9007199254740992"}},
+		{`1.5`, `1.5`, 1.5, []string{"v:1.5", "v>1", "v<2"}},
+		{`2.0`, `2.0`, int64(2), []string{"v:2", "v>=2"}},
+		{`1e3`, `1e3`, int64(1000), []string{"v:1000", "v>999"}},
+		{`2.5e-3`, `2.5e-3`, 0.0025, []string{"v:0.0025", "v<0.01"}},
+		{`1e20`, `1e20`, 1e20, []string{"v:1e20", "v>1e19"}},
+		{`"500"`, `"500"`, "500", []string{"v:500", "v>=500", `v:"500"`}},
+		{`"1e3"`, `"1e3"`, "1e3", []string{"v:1000", "v:1e3"}},
+		{`"nan"`, `nan`, "nan", []string{"v:nan", "v:NaN", "v>0"}},
+		{`"inf"`, `inf`, "inf", []string{"v:inf", "v:Infinity"}},
+		{`"1_000"`, `1_000`, "1_000", []string{"v:1000", "v:1_000"}},
+		{`"0x10"`, `0x10`, "0x10", []string{"v:16", "v:0x10"}},
+	}
+	jp, lp := parser.NewJSONParser(), parser.NewLogfmtParser()
+	m := filter.NewMatcher()
+	for _, tt := range tests {
+		je, err := jp.Parse(`{"v":` + tt.json + `}`)
+		if err != nil {
+			t.Fatalf("JSON %s: %v", tt.json, err)
+		}
+		le, err := lp.Parse(`v=` + tt.logfmt)
+		if err != nil {
+			t.Fatalf("logfmt %s: %v", tt.logfmt, err)
+		}
+		if !reflect.DeepEqual(je.Fields, le.Fields) {
+			t.Errorf("%s: JSON gives %#v, logfmt %#v", tt.json, je.Fields, le.Fields)
+		}
+		if got := je.Fields["v"]; got != tt.want {
+			t.Errorf("%s: got %#v, want %#v", tt.json, got, tt.want)
+		}
+		for _, q := range tt.queries {
+			chain, err := filter.ParseQuery(q)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", q, err)
+			}
+			if j, l := m.Match(je, chain), m.Match(le, chain); j != l {
+				t.Errorf("%s with %s: JSON matches %v, logfmt %v", tt.json, q, j, l)
+			}
+		}
+	}
+}
+
+// TestLazyJSONNumbers checks that extracting fields lazily types numbers
+// as JSONParser does.
+func TestLazyJSONNumbers(t *testing.T) {
+	line := `{"a":9007199254740993,"b":1.5,"c":1e3,"d":{"e":2.0}}`
+	full, err := parser.NewJSONParser().Parse(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lazy, ok := parser.ExtractJSON(line, []string{"a", "b", "c", "d.e"})
+	if !ok {
+		t.Fatal("ExtractJSON failed")
+	}
+	if !reflect.DeepEqual(full.Fields, lazy.Fields) {
+		t.Errorf("Parse gives %#v, ExtractJSON %#v", full.Fields, lazy.Fields)
+	}
+}
+
+// TestWordsAreNotNumbers checks that values strconv would read as numbers
+// but logs mean as words match themselves, and only themselves.
+func TestWordsAreNotNumbers(t *testing.T) {
+	tests := []struct {
+		line, query string
+		want        bool
+	}{
+		{`name=nan`, `name:nan`, true},
+		{`{"name":"nan"}`, `name:nan`, true},
+		{`{"name":"NaN"}`, `name:NaN`, true},
+		{`name=inf`, `name:inf`, true},
+		{`name=Infinity`, `name:inf`, false},
+		{`v=1_000`, `v:1000`, false},
+		{`v=1000`, `v:1_000`, false},
+		{`v=1_000`, `v:1_000`, true},
+		{`v=1000`, `v:1e3`, true},
+		{`v=.5`, `v:0.5`, true},
+	}
+	p := parser.NewAutoParser()
+	m := filter.NewMatcher()
+	for _, tt := range tests {
+		e, err := p.Parse(tt.line)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.line, err)
+		}
+		chain, err := filter.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		if got := m.Match(e, chain); got != tt.want {
+			t.Errorf("%s with %s: got %v, want %v", tt.line, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIsNumeric(t *testing.T) {
+	for s, want := range map[string]bool{
+		"0": true, "-7": true, "+7": true, "1.5": true, ".5": true, "5.": true,
+		"1e3": true, "2.5E-3": true, "-1e+20": true,
+		"": false, "-": false, ".": false, "e3": false, "1e": false, "1e+": false,
+		"nan": false, "NaN": false, "inf": false, "-Inf": false, "Infinity": false,
+		"1_000": false, "0x10": false, "0x1p-2": false, " 1": false, "1 ": false,
+	} {
+		if got := parser.IsNumeric(s); got != want {
+			t.Errorf("IsNumeric(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -1,11 +1,33 @@
 // Package parser provides log parsing functionality for various formats.
 package parser
 
+import "time"
+
 // LogEntry represents a parsed log line with extracted fields.
 type LogEntry struct {
 	Raw     string         // Original log line
 	Fields  map[string]any // Flattened key-value fields
 	LineNum int            // Line number in source file
+	Source  string         // Input file, "-" for stdin; empty when unknown
+	ID      EntryID        // Stable identifier, set when read in chunks
+
+	// Timestamp is the normalized event time, set by TimeParser (zero when
+	// unknown). Formatters, sorters and time filters prefer it over
+	// re-parsing the time field.
+	Timestamp time.Time
+
+	// Matched lists the fields that satisfied the filter, set when the
+	// matcher records them (--highlight) so formatters can emphasize them.
+	Matched []FieldMatch
+}
+
+// FieldMatch is a field that satisfied a filter condition and the text of
+// its value that matched: the substring or regex match for *= and ~=, the
+// whole value for comparisons, and empty when only the field counts (?,
+// !=, len()).
+type FieldMatch struct {
+	Field string
+	Text  string
 }
 
 // Parser defines the interface for log format parsers.
@@ -17,6 +39,23 @@ type Parser interface {
 	CanParse(line string) bool
 }
 
+// Clone returns a copy of the entry that shares no mutable state with it.
+// Entries are never pooled or recycled, so a sink may keep the entries it
+// is given; it needs a clone only to modify one that another stage, such
+// as a tee to several outputs, still reads. Field values that are
+// themselves maps or slices (only from custom parsers) are shared.
+func (e *LogEntry) Clone() *LogEntry {
+	c := *e
+	c.Fields = make(map[string]any, len(e.Fields))
+	for k, v := range e.Fields {
+		c.Fields[k] = v
+	}
+	if e.Matched != nil {
+		c.Matched = append([]FieldMatch(nil), e.Matched...)
+	}
+	return &c
+}
+
 // NewLogEntry creates a new LogEntry with initialized fields map.
 func NewLogEntry(line string, lineNum int) *LogEntry {
 	return &LogEntry{
@@ -0,0 +1,410 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBufferSize is the maximum line length StreamReader accepts unless
+// SetMaxLineSize says otherwise. The buffer grows to it only as needed.
+const DefaultBufferSize = 64 << 20
+
+// Chunk is a batch of consecutive lines handed to a worker pool.
+type Chunk struct {
+	Seq      int              // Position of the chunk in the stream, from 0
+	Start    int              // Line number of Lines[0], from 1
+	Lines    []string         // Raw lines (or multiline records) without trailing newlines
+	LineNums []int            // Starting line of each record when not one per line (multiline, JSON documents, oversize modes), else nil
+	Offsets  []int64          // Byte offset of each record in the decompressed input
+	Context  []map[string]any // Header fields in effect for each record (SetHeaderContext), else nil
+	Source   string           // Input file the lines came from, if known
+	FileID   string           // Identity of the input (see FileID)
+	Columns  []string         // Header of a delimited input (SetCSVHeader), else nil
+}
+
+// LineNum returns the source line number of Lines[i].
+func (c *Chunk) LineNum(i int) int {
+	if c.LineNums != nil {
+		return c.LineNums[i]
+	}
+	return c.Start + i
+}
+
+// EntryID returns the stable identifier of Lines[i], or the zero EntryID
+// when the chunk carries no offsets.
+func (c *Chunk) EntryID(i int) EntryID {
+	if i >= len(c.Offsets) {
+		return EntryID{}
+	}
+	return EntryID{File: c.FileID, Offset: c.Offsets[i]}
+}
+
+// position locates a record in its input.
+type position struct {
+	line   int   // Line number, from 1
+	offset int64 // Byte offset in the decompressed input
+}
+
+// StreamReader reads files line by line without loading them into memory.
+// Paths ending in .gz are decompressed, "-" reads stdin, ssh:// paths are
+// streamed from a remote host and s3://, gs:// and az:// from object
+// stores.
+type StreamReader struct {
+	bufferSize     int
+	multiline      bool
+	multilineStart *regexp.Regexp
+	skip, head     int
+	header         *regexp.Regexp
+	jsonDocs       bool
+	recordSep      *regexp.Regexp
+	oversize       OversizeMode
+	oversized      atomic.Int64
+	stripANSI      bool
+	csvComma       rune
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewStreamReader creates a StreamReader with the default buffer size.
+func NewStreamReader() *StreamReader {
+	return &StreamReader{bufferSize: DefaultBufferSize}
+}
+
+// SetMultiline enables multiline assembly so that, for example, a stack
+// trace becomes one record with its lines joined by "\n". A line matching
+// start begins a new record; any other line is appended to the current one.
+// With a nil start, continuation lines are detected by IsContinuation.
+func (r *StreamReader) SetMultiline(start *regexp.Regexp) {
+	r.multiline = true
+	r.multilineStart = start
+}
+
+// SetStripANSI removes ANSI escape sequences and control bytes from every
+// line as it is read (--strip-ansi; see StripANSI), so logs captured from
+// a TTY parse like plain ones and print without escapes. Multiline
+// assembly and header detection see the stripped lines; JSON documents
+// are read as they are.
+func (r *StreamReader) SetStripANSI(strip bool) {
+	r.stripANSI = strip
+}
+
+// SetLimits restricts every input to a window of physical lines: the
+// first skip lines (headers, banners) are dropped before parsing, and at
+// most head lines after them are read; head <= 0 reads to the end. Line
+// numbers still count from the start of the file.
+func (r *StreamReader) SetLimits(skip, head int) {
+	r.skip = max(skip, 0)
+	r.head = head
+}
+
+// Read returns a channel that yields the lines of path. The channel is
+// closed at end of input, on error, or once ctx is done, so a consumer
+// that stops early cancels ctx to release the reader; check Err
+// afterwards, which then returns ctx's error.
+func (r *StreamReader) Read(ctx context.Context, path string) <-chan string {
+	out := make(chan string, 1024)
+	go func() {
+		defer close(out)
+		r.setErr(r.withFile(path, func(rd io.Reader) error {
+			return r.ScanLines(rd, func(line string) bool {
+				select {
+				case out <- line:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+		}))
+		r.setErr(ctx.Err())
+	}()
+	return out
+}
+
+// ReadChunks returns a channel of line batches of up to chunkSize lines for
+// worker pools. The channel is closed at end of input, on error, or once
+// ctx is done; check Err afterwards, as for Read.
+func (r *StreamReader) ReadChunks(ctx context.Context, path string, chunkSize int) <-chan Chunk {
+	out := make(chan Chunk, 16)
+	go func() {
+		defer close(out)
+		defer func() { r.setErr(ctx.Err()) }()
+		send := func(c Chunk) bool {
+			c.Source = path
+			select {
+			case out <- c:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if f, ok := openLocal(path); ok {
+			defer f.Close()
+			if m, ok := r.mapInput(f); ok {
+				defer m.unmap()
+				r.setErr(scanMapped(m, chunkSize, r.bufferSize, r.withColumns(m.data, send)))
+				return
+			}
+		}
+		r.setErr(r.withFile(path, func(rd io.Reader) error {
+			return r.ScanChunks(rd, chunkSize, send)
+		}))
+	}()
+	return out
+}
+
+// openLocal opens path if it names a local file.
+func openLocal(path string) (*os.File, bool) {
+	if path == "-" || IsRemote(path) || IsBlob(path) {
+		return nil, false
+	}
+	f, err := os.Open(path)
+	return f, err == nil
+}
+
+// ScanLines calls fn for every line (or multiline record) of rd until fn
+// returns false.
+func (r *StreamReader) ScanLines(rd io.Reader, fn func(line string) bool) error {
+	return r.scanRecords(rd, func(rec string, _ position) bool {
+		return fn(rec)
+	})
+}
+
+// ScanChunks groups the lines of rd into Chunks of up to chunkSize lines
+// (default 1000) and calls fn for each until fn returns false. Large
+// files are memory-mapped (see MmapThreshold).
+func (r *StreamReader) ScanChunks(rd io.Reader, chunkSize int, fn func(Chunk) bool) error {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	if f, ok := rd.(*os.File); ok {
+		if m, ok := r.mapInput(f); ok {
+			defer m.unmap()
+			return scanMapped(m, chunkSize, r.bufferSize, r.withColumns(m.data, fn))
+		}
+	}
+	br := bufio.NewReader(rd)
+	fileID, base := identify(rd, br)
+	if r.csvComma != 0 {
+		head, _ := br.Peek(csvHeaderPeek)
+		fn = r.withColumns(head, fn)
+	}
+	prefix, _ := br.Peek(IDPrefixSize)
+	docs := r.documents(prefix)
+
+	newChunk := func(seq int) Chunk {
+		c := Chunk{
+			Seq:     seq,
+			Lines:   make([]string, 0, chunkSize),
+			Offsets: make([]int64, 0, chunkSize),
+			FileID:  fileID,
+		}
+		if r.multiline || docs || r.oversize != OversizeFail {
+			c.LineNums = make([]int, 0, chunkSize)
+		}
+		if r.header != nil {
+			c.Context = make([]map[string]any, 0, chunkSize)
+		}
+		return c
+	}
+	var context map[string]any // Fields of the current header section
+
+	chunk := newChunk(0)
+	stopped := false
+	err := r.scanRecords(br, func(rec string, pos position) bool {
+		if len(chunk.Lines) == 0 {
+			chunk.Start = pos.line
+		}
+		chunk.Lines = append(chunk.Lines, rec)
+		chunk.Offsets = append(chunk.Offsets, base+pos.offset)
+		if chunk.LineNums != nil {
+			chunk.LineNums = append(chunk.LineNums, pos.line)
+		}
+		if chunk.Context != nil {
+			if r.header.MatchString(rec) {
+				context = headerFields(r.header, rec)
+			}
+			chunk.Context = append(chunk.Context, context)
+		}
+		if len(chunk.Lines) < chunkSize {
+			return true
+		}
+		if !fn(chunk) {
+			stopped = true
+			return false
+		}
+		chunk = newChunk(chunk.Seq + 1)
+		return true
+	})
+	if !stopped && len(chunk.Lines) > 0 {
+		fn(chunk)
+	}
+	return err
+}
+
+// scanRecords calls fn with each line, or each assembled record in
+// multiline mode, and the position it starts at, within the window set by
+// SetLimits. JSON documents are split by value instead (SetJSONDocuments).
+func (r *StreamReader) scanRecords(rd io.Reader, fn func(rec string, pos position) bool) error {
+	br := bufio.NewReader(rd)
+	if prefix, _ := br.Peek(IDPrefixSize); r.documents(prefix) {
+		return r.scanDocuments(br, fn)
+	}
+
+	scanner := bufio.NewScanner(br)
+	// Room for a line of the maximum size and its terminator, so longer
+	// ones are seen by the splitter before the scanner gives up.
+	scanner.Buffer(make([]byte, 0, min(64*1024, r.bufferSize+2)), r.bufferSize+2)
+	split := scanLines
+	if r.recordSep != nil {
+		split = splitRecords(r.recordSep)
+	}
+	lines := &lineSplitter{
+		split: split,
+		max:   r.bufferSize,
+		mode:  r.oversize,
+		count: func() { r.oversized.Add(1) },
+	}
+	var consumed, start int64 // Bytes consumed so far; offset of the last token
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		n, tok, err := lines.next(data, atEOF)
+		if tok != nil {
+			start = consumed
+		}
+		consumed += int64(n)
+		return n, tok, err
+	})
+
+	var ml *multiline
+	if r.multiline {
+		ml = &multiline{start: r.multilineStart}
+	}
+
+	line := 0
+	for scanner.Scan() {
+		if r.recordSep != nil && len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if !lines.continued {
+			line++
+		}
+		line, lines.dropped = line+lines.dropped, 0
+		pos := position{line: line, offset: start}
+		if pos.line <= r.skip {
+			continue
+		}
+		if r.head > 0 && pos.line > r.skip+r.head {
+			break
+		}
+		text := scanner.Text()
+		if r.stripANSI {
+			text = StripANSI(text)
+		}
+		if ml == nil {
+			if !fn(text, pos) {
+				return nil
+			}
+			continue
+		}
+		if rec, start, ok := ml.add(text, pos); ok && !fn(rec, start) {
+			return nil
+		}
+	}
+	if ml != nil {
+		if rec, start, ok := ml.flush(); ok {
+			fn(rec, start)
+		}
+	}
+	if err := scanner.Err(); errors.Is(err, ErrLineTooLong) {
+		return lineTooLong(line+1, r.bufferSize)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Err returns the first error encountered by Read or ReadChunks.
+func (r *StreamReader) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *StreamReader) setErr(err error) {
+	if err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// withFile opens path and passes the (decompressed) stream to fn.
+func (r *StreamReader) withFile(path string, fn func(rd io.Reader) error) error {
+	rc, err := openReader(path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return fn(rc)
+}
+
+// OpenInput opens an input the way a StreamReader does, decompressed, for
+// callers that read it themselves.
+func OpenInput(path string) (io.ReadCloser, error) {
+	return openReader(path)
+}
+
+// openReader opens a file, stdin ("-"), a remote input (ssh://) or a cloud
+// object (s3://, gs://, az://), decompressing gzip, bzip2, zstd and xz
+// input detected by magic bytes.
+func openReader(path string) (io.ReadCloser, error) {
+	var f io.ReadCloser = io.NopCloser(os.Stdin)
+	switch {
+	case IsRemote(path):
+		rf, err := openRemote(path)
+		if err != nil {
+			return nil, err
+		}
+		f = rf
+	case IsBlob(path):
+		bf, err := openBlob(path)
+		if err != nil {
+			return nil, err
+		}
+		f = bf
+	case path != "-":
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		f = file
+	}
+
+	r, closeDecoder, err := decompress(f, path)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &decodedFile{Reader: r, closeDecoder: closeDecoder, file: f}, nil
+}
+
+// decodedFile closes both the decoder and the underlying file.
+type decodedFile struct {
+	io.Reader
+	closeDecoder func() error
+	file         io.Closer
+}
+
+func (d *decodedFile) Close() error {
+	d.closeDecoder()
+	return d.file.Close()
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReadStopsOnCancel checks that a consumer that stops reading releases
+// the reader by canceling its context: the reader returns without anyone
+// draining its channel.
+func TestReadStopsOnCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(strings.Repeat("line\n", 100000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(n int64) { MmapThreshold = n }(MmapThreshold)
+
+	tests := []struct {
+		name string
+		mmap int64
+		read func(ctx context.Context, r *StreamReader) bool
+	}{
+		{"Read", 0, func(ctx context.Context, r *StreamReader) bool {
+			_, ok := <-r.Read(ctx, path)
+			return ok
+		}},
+		{"ReadChunks", 0, func(ctx context.Context, r *StreamReader) bool {
+			_, ok := <-r.ReadChunks(ctx, path, 10)
+			return ok
+		}},
+		{"ReadChunks mmap", 1, func(ctx context.Context, r *StreamReader) bool {
+			_, ok := <-r.ReadChunks(ctx, path, 10)
+			return ok
+		}},
+	}
+	for _, tt := range tests {
+		MmapThreshold = tt.mmap
+		r := NewStreamReader()
+		ctx, cancel := context.WithCancel(context.Background())
+		if !tt.read(ctx, r) {
+			t.Fatalf("%s: no input read", tt.name)
+		}
+		cancel()
+		deadline := time.Now().Add(5 * time.Second)
+		for r.Err() == nil && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if err := r.Err(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: Err() = %v, want context.Canceled", tt.name, err)
+		}
+	}
+}
+
+// TestMappedTruncated checks that a mapped file truncated while it is read
+// ends the read with an error instead of a crash.
+func TestMappedTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(strings.Repeat("line\n", 1<<20)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(n int64) { MmapThreshold = n }(MmapThreshold)
+	MmapThreshold = 1
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := NewStreamReader()
+	m, ok := r.mapInput(f)
+	if !ok {
+		t.Skip("file not mapped on this platform")
+	}
+	defer m.unmap()
+	if err := os.Truncate(path, 4096); err != nil {
+		t.Fatal(err)
+	}
+	lines := 0
+	err = scanMapped(m, 1000, DefaultBufferSize, func(c Chunk) bool {
+		lines += len(c.Lines)
+		return true
+	})
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("err = %v, want a truncation error", err)
+	}
+	if max := 4096 / len("line\n"); lines > max {
+		t.Errorf("%d lines read, want at most the %d left", lines, max)
+	}
+}
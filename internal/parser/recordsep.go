@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// recordSepNames are the named --record-sep values.
+var recordSepNames = map[string]string{
+	"rs":  "\x1e", // RFC 7464 JSON text sequences
+	"nul": "\x00", // find -print0, xargs -0
+}
+
+// ParseRecordSeparator parses a --record-sep value: "rs", "nul", a string
+// with Go escapes such as '\x1e' or '\0', or a regex between slashes such
+// as "/\n\n+/" for blank-line separated records. The result matches one separator.
+func ParseRecordSeparator(s string) (*regexp.Regexp, error) {
+	if sep, ok := recordSepNames[strings.ToLower(s)]; ok {
+		return regexp.MustCompile(regexp.QuoteMeta(sep)), nil
+	}
+	if len(s) >= 2 && s[0] == '/' && s[len(s)-1] == '/' {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return nil, fmt.Errorf("parser: record separator %q: %w", s, err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf("parser: record separator %q matches the empty string", s)
+		}
+		return re, nil
+	}
+	if s == `\0` {
+		s = `\x00`
+	}
+	sep, err := strconv.Unquote(`"` + strings.ReplaceAll(s, `"`, `\"`) + `"`)
+	if err != nil {
+		return nil, fmt.Errorf("parser: record separator %q: bad escape", s)
+	}
+	if sep == "" {
+		return nil, fmt.Errorf("parser: empty record separator")
+	}
+	return regexp.MustCompile(regexp.QuoteMeta(sep)), nil
+}
+
+// SetRecordSeparator splits input into records at matches of sep instead
+// of at newlines. A line break ending a record is dropped and empty
+// records are skipped, so RFC 7464 sequences ("\x1e{...}\n") yield one
+// JSON text per record. Line numbers then count records.
+func (r *StreamReader) SetRecordSeparator(sep *regexp.Regexp) {
+	r.recordSep = sep
+}
+
+// splitRecords is a bufio.SplitFunc cutting at matches of sep. A match
+// reaching the end of the buffer could continue in the next read, so more
+// data is requested first.
+func splitRecords(sep *regexp.Regexp) func(data []byte, atEOF bool) (int, []byte, error) {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if loc := sep.FindIndex(data); loc != nil && (loc[1] < len(data) || atEOF) {
+			return loc[1], trimRecord(data[:loc[0]]), nil
+		}
+		if atEOF {
+			return len(data), trimRecord(data), nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// trimRecord drops a trailing line break from a record.
+func trimRecord(rec []byte) []byte {
+	rec = bytes.TrimSuffix(rec, []byte("\n"))
+	return bytes.TrimRight(rec, "\r")
+}
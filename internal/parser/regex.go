@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrNoRegexMatch is returned when a line does not match a RegexParser's
+// expression.
+var ErrNoRegexMatch = errors.New("parser: line does not match the regex")
+
+// RegexParser parses unstructured lines with a regular expression whose
+// named groups become fields (--regex-parse):
+//
+//	^(?P<ts>\S+) (?P<level>\w+) (?P<message>.*)$
+//
+// Values are typed as in logfmt (see InferType); groups that do not take
+// part in the match are omitted.
+type RegexParser struct {
+	re     *regexp.Regexp
+	groups []int // Indexes of the named groups
+}
+
+// NewRegexParser compiles expr, which must have at least one named group.
+func NewRegexParser(expr string) (*RegexParser, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parser: regex %q: %w", expr, err)
+	}
+	p := &RegexParser{re: re}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			p.groups = append(p.groups, i)
+		}
+	}
+	if len(p.groups) == 0 {
+		return nil, fmt.Errorf("parser: regex %q has no named groups, such as (?P<level>\\w+)", expr)
+	}
+	return p, nil
+}
+
+// CanParse reports whether the line matches the expression.
+func (p *RegexParser) CanParse(line string) bool {
+	return p.re.MatchString(line)
+}
+
+// Parse extracts the named groups of line as fields.
+func (p *RegexParser) Parse(line string) (*LogEntry, error) {
+	m := p.re.FindStringSubmatchIndex(line)
+	if m == nil {
+		return nil, ErrNoRegexMatch
+	}
+	entry := NewLogEntry(line, 0)
+	names := p.re.SubexpNames()
+	for _, i := range p.groups {
+		start, end := m[2*i], m[2*i+1]
+		if start < 0 {
+			continue
+		}
+		entry.Fields[names[i]] = InferType(line[start:end])
+	}
+	return entry, nil
+}
@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// RemoteScheme prefixes inputs read from another machine over SSH:
+// ssh://user@host:port/var/log/app.log. Adding ?follow streams the file
+// as it grows (tail -F) instead of reading it once.
+const RemoteScheme = "ssh://"
+
+// SSHCommand is the ssh client used for remote inputs. It runs in batch
+// mode, so authentication must not prompt (keys or an agent).
+var SSHCommand = "ssh"
+
+// IsRemote reports whether path names a remote input.
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, RemoteScheme)
+}
+
+// remoteArgs builds the ssh arguments reading the file named by path.
+func remoteArgs(path string) ([]string, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("remote input %q: %w", path, err)
+	}
+	if u.Hostname() == "" || u.Path == "" || u.Path == "/" {
+		return nil, fmt.Errorf("remote input %q: want ssh://[user@]host[:port]/path", path)
+	}
+
+	args := []string{"-o", "BatchMode=yes"}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	target := u.Hostname()
+	if u.User != nil {
+		target = u.User.Username() + "@" + target
+	}
+	remote := "cat -- " + shellQuote(u.Path)
+	if u.Query().Has("follow") {
+		remote = "tail -n +1 -F -- " + shellQuote(u.Path)
+	}
+	// "--" ends ssh's options, so a host cannot be read as a flag.
+	return append(args, "--", target, remote), nil
+}
+
+// shellQuote quotes s for a POSIX shell on the remote side.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// remoteWaitDelay bounds how long closing a remote input waits for the
+// client process's output to be released.
+const remoteWaitDelay = time.Second
+
+// openRemote starts ssh and returns its output stream.
+func openRemote(path string) (io.ReadCloser, error) {
+	args, err := remoteArgs(path)
+	if err != nil {
+		return nil, err
+	}
+	return startRemote(path, SSHCommand, args)
+}
+
+// startRemote runs a client command streaming the input path to stdout.
+func startRemote(path, name string, args []string) (io.ReadCloser, error) {
+	cmd := exec.Command(name, args...)
+	cmd.WaitDelay = remoteWaitDelay
+	rf := &remoteFile{path: path, cmd: cmd}
+	cmd.Stderr = &rf.stderr
+	var err error
+	if rf.out, err = cmd.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("remote input %q: %w", path, err)
+	}
+	return rf, nil
+}
+
+// remoteFile is the output of an ssh or object-store client process.
+// Reading to the end reports a failed command (unreachable host, missing
+// file) as an error; closing early stops the process.
+type remoteFile struct {
+	path   string
+	cmd    *exec.Cmd
+	out    io.ReadCloser
+	stderr bytes.Buffer
+	done   bool
+	err    error // Returned by every Read once done
+}
+
+func (f *remoteFile) Read(p []byte) (int, error) {
+	if f.done {
+		return 0, f.err
+	}
+	n, err := f.out.Read(p)
+	if errors.Is(err, io.EOF) {
+		f.done, f.err = true, io.EOF
+		if werr := f.cmd.Wait(); werr != nil {
+			msg := strings.TrimSpace(f.stderr.String())
+			if msg == "" {
+				msg = werr.Error()
+			}
+			f.err = fmt.Errorf("remote input %q: %s", f.path, msg)
+		}
+		return n, f.err
+	}
+	return n, err
+}
+
+func (f *remoteFile) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.cmd.Process.Kill()
+	f.cmd.Wait()
+	return nil
+}
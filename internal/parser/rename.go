@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Rename is one field renaming: From, as some inputs log it, becomes the
+// common name To.
+type Rename struct {
+	From, To string
+}
+
+// RenameParser wraps a Parser and renames fields to a common name
+// (--rename lvl=level), so one query works across services that name a
+// field differently. Fields nested under a renamed one move with it
+// (lvl.code becomes level.code).
+//
+// Renames apply in order to the fields as parsed, so a field is renamed
+// at most once. A field is left in place when its new name is already
+// taken, whether logged or set by an earlier rename, so no value is lost:
+// with lvl=level and severity=level, level comes from lvl when the entry
+// has both.
+type RenameParser struct {
+	Parser
+	Renames []Rename
+}
+
+// NewRenameParser wraps p to apply renames in order.
+func NewRenameParser(p Parser, renames []Rename) *RenameParser {
+	return &RenameParser{Parser: p, Renames: renames}
+}
+
+// ParseRename parses a --rename value such as "lvl=level".
+func ParseRename(s string) (from, to string, err error) {
+	from, to, ok := strings.Cut(s, "=")
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if !ok || from == "" || to == "" || from == to {
+		return "", "", fmt.Errorf("parser: rename %q: want logged_name=common_name", s)
+	}
+	return from, to, nil
+}
+
+// CheckRenames validates renames, each of which may name a field once.
+func CheckRenames(renames []Rename) error {
+	seen := make(map[string]bool, len(renames))
+	for _, r := range renames {
+		if _, _, err := ParseRename(r.From + "=" + r.To); err != nil {
+			return err
+		}
+		if seen[r.From] {
+			return fmt.Errorf("parser: rename %q: field renamed twice", r.From)
+		}
+		seen[r.From] = true
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped parser.
+func (p *RenameParser) Unwrap() Parser {
+	return p.Parser
+}
+
+// Rewrap returns a copy of p wrapping inner.
+func (p *RenameParser) Rewrap(inner Parser) Parser {
+	c := *p
+	c.Parser = inner
+	return &c
+}
+
+// Parse parses line with the wrapped parser and renames its fields.
+func (p *RenameParser) Parse(line string) (*LogEntry, error) {
+	entry, err := p.Parser.Parse(line)
+	if err != nil {
+		return entry, err
+	}
+	type move struct{ from, to string }
+	var moves []move
+	for _, r := range p.Renames {
+		start := len(moves)
+		for k := range entry.Fields {
+			if fieldHead(k) == r.From {
+				moves = append(moves, move{k, r.To + k[len(r.From):]})
+			}
+		}
+		slices.SortFunc(moves[start:], func(a, b move) int { return strings.Compare(a.from, b.from) })
+	}
+	for _, m := range moves {
+		if _, taken := entry.Fields[m.to]; taken {
+			continue
+		}
+		entry.Fields[m.to] = entry.Fields[m.from]
+		delete(entry.Fields, m.from)
+	}
+	return entry, nil
+}
+
+// fieldHead returns the first path segment of a flattened field name:
+// everything before the first "." or "[".
+func fieldHead(field string) string {
+	if i := strings.IndexAny(field, ".["); i >= 0 {
+		return field[:i]
+	}
+	return field
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenameOrder(t *testing.T) {
+	renames := []Rename{{"lvl", "level"}, {"severity", "level"}, {"a", "b"}, {"b", "c"}}
+	tests := []struct {
+		line string
+		want map[string]any
+	}{
+		{`{"lvl":"warn"}`, map[string]any{"level": "warn"}},
+		{`{"severity":"warn"}`, map[string]any{"level": "warn"}},
+		// The first rename to a name wins; the other field stays.
+		{`{"severity":"error","lvl":"warn"}`, map[string]any{"level": "warn", "severity": "error"}},
+		// A field logged under the new name is never overwritten.
+		{`{"level":"info","lvl":"warn"}`, map[string]any{"level": "info", "lvl": "warn"}},
+		// Renames see the fields as parsed, so a is not renamed twice.
+		{`{"a":1}`, map[string]any{"b": int64(1)}},
+		{`{"a":1,"b":2}`, map[string]any{"a": int64(1), "c": int64(2)}},
+		{`{"lvl":{"code":3,"name":"warn"}}`, map[string]any{"level.code": int64(3), "level.name": "warn"}},
+	}
+	p := NewRenameParser(NewJSONParser(), renames)
+	for _, tt := range tests {
+		for range 20 { // Map iteration order must not matter
+			entry, err := p.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse(%s): %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(entry.Fields, tt.want) {
+				t.Fatalf("Parse(%s) = %v, want %v", tt.line, entry.Fields, tt.want)
+			}
+		}
+	}
+}
+
+func TestCheckRenames(t *testing.T) {
+	for _, renames := range [][]Rename{
+		{{"lvl", ""}},
+		{{"lvl", "lvl"}},
+		{{"lvl", "level"}, {"lvl", "severity"}},
+	} {
+		if err := CheckRenames(renames); err == nil {
+			t.Errorf("CheckRenames(%v) = nil, want an error", renames)
+		}
+	}
+	if err := CheckRenames([]Rename{{"lvl", "level"}, {"severity", "level"}}); err != nil {
+		t.Errorf("CheckRenames: %v", err)
+	}
+}
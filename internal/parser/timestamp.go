@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFields lists the field names checked, in order, when detecting an
+// entry's timestamp.
+var TimeFields = []string{"@timestamp", "timestamp", "time", "ts", "datetime", "date"}
+
+// timeLayouts lists the string layouts ParseTime understands.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999999999", // log4j/Python logging
+	"02/Jan/2006:15:04:05 -0700",    // Apache/Nginx
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+	time.ANSIC,
+	time.Stamp, // syslog, no year; fractional seconds are accepted
+	"2006-01-02",
+}
+
+// DetectTimeField returns the first field in TimeFields that holds a
+// parseable timestamp.
+func DetectTimeField(fields map[string]any) (string, bool) {
+	for _, name := range TimeFields {
+		if v, ok := fields[name]; ok {
+			if _, ok := ParseTime(v); ok {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// TimeParser wraps a Parser and sets LogEntry.Timestamp on every entry it
+// parses (--time-field).
+type TimeParser struct {
+	Parser
+	Field string // Empty to detect per entry (DetectTimeField)
+}
+
+// NewTimeParser wraps p so that entries carry a normalized Timestamp read
+// from field, or from the detected time field when field is empty.
+func NewTimeParser(p Parser, field string) *TimeParser {
+	return &TimeParser{Parser: p, Field: field}
+}
+
+// Unwrap returns the wrapped parser.
+func (p *TimeParser) Unwrap() Parser {
+	return p.Parser
+}
+
+// Rewrap returns a copy of p wrapping inner.
+func (p *TimeParser) Rewrap(inner Parser) Parser {
+	c := *p
+	c.Parser = inner
+	return &c
+}
+
+// Parse parses line with the wrapped parser and normalizes its timestamp.
+func (p *TimeParser) Parse(line string) (*LogEntry, error) {
+	entry, err := p.Parser.Parse(line)
+	if err == nil {
+		NormalizeTime(entry, p.Field)
+	}
+	return entry, err
+}
+
+// NormalizeTime sets entry.Timestamp (in UTC) from field, or from the
+// detected time field when field is empty, and reports whether a
+// timestamp was found.
+func NormalizeTime(entry *LogEntry, field string) bool {
+	if field == "" {
+		var ok bool
+		if field, ok = DetectTimeField(entry.Fields); !ok {
+			return false
+		}
+	}
+	t, ok := ParseTime(entry.Fields[field])
+	if !ok {
+		return false
+	}
+	entry.Timestamp = t.UTC()
+	return true
+}
+
+// ParseTime converts a field value into a time. Strings are tried against
+// RFC3339, common log layouts and syslog; numbers (and numeric strings) are
+// read as epoch seconds, millis, micros or nanos depending on magnitude.
+// Syslog timestamps without a year are placed in the current year.
+func ParseTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case float64:
+		return epochTime(t), true
+	case int64:
+		return epochTime(float64(t)), true
+	case int:
+		return epochTime(float64(t)), true
+	case string:
+		return parseTimeString(t)
+	}
+	return time.Time{}, false
+}
+
+func parseTimeString(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	if IsNumeric(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return epochTime(f), true
+		}
+	}
+
+	for _, layout := range timeLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if t.Year() == 0 {
+			t = t.AddDate(time.Now().Year(), 0, 0)
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// epochTime interprets n as seconds, millis, micros or nanos since the epoch.
+func epochTime(n float64) time.Time {
+	switch abs := max(n, -n); {
+	case abs < 1e11:
+		sec := int64(n)
+		return time.Unix(sec, int64((n-float64(sec))*1e9)).UTC()
+	case abs < 1e14:
+		return time.UnixMilli(int64(n)).UTC()
+	case abs < 1e17:
+		return time.UnixMicro(int64(n)).UTC()
+	}
+	return time.Unix(0, int64(n)).UTC()
+}
@@ -0,0 +1,283 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Transform rewrites a raw line before it is parsed.
+type Transform func(line string) (string, error)
+
+// ParseTransforms parses a --pre-transform pipeline: steps separated by
+// ";" and applied in order.
+//
+//	s/regex/replacement/[gi]  substitute, sed-like: \1 refers to a group,
+//	                          g replaces every match, i ignores case
+//	urldecode                 URL-decode the line
+//	base64 FIELD              base64-decode a string field of a JSON line
+//	json-unwrap [FIELD]       parse a string field holding JSON (or, with
+//	                          no field, a line that is a JSON string) into
+//	                          nested JSON
+//
+// Any delimiter may follow "s", as in sed. FIELD is a dotted path into the
+// JSON object.
+func ParseTransforms(spec string) ([]Transform, error) {
+	var steps []Transform
+	rest := spec
+	for {
+		rest = strings.TrimLeft(rest, " \t;")
+		if rest == "" {
+			return steps, nil
+		}
+		var step Transform
+		var err error
+		if len(rest) > 1 && rest[0] == 's' && !isWordByte(rest[1]) && rest[1] != ' ' {
+			step, rest, err = parseSubstitute(rest)
+		} else {
+			word, next, _ := strings.Cut(rest, ";")
+			step, err = parseStep(strings.Fields(word))
+			rest = next
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '-' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// parseStep parses a named step and its arguments.
+func parseStep(words []string) (Transform, error) {
+	name, args := words[0], words[1:]
+	switch {
+	case name == "urldecode" && len(args) == 0:
+		return func(line string) (string, error) {
+			return url.QueryUnescape(line)
+		}, nil
+	case name == "base64" && len(args) == 1:
+		return jsonFieldTransform(args[0], decodeBase64), nil
+	case name == "json-unwrap" && len(args) == 1:
+		return jsonFieldTransform(args[0], unwrapJSON), nil
+	case name == "json-unwrap" && len(args) == 0:
+		return unwrapLine, nil
+	}
+	return nil, fmt.Errorf("transform: invalid step %q", strings.Join(words, " "))
+}
+
+// sedGroup matches \1 ... \9 in a sed replacement.
+var sedGroup = regexp.MustCompile(`\\([0-9])`)
+
+// parseSubstitute parses "s/regex/replacement/flags" at the start of s and
+// returns the rest after it.
+func parseSubstitute(s string) (Transform, string, error) {
+	delim := s[1]
+	var parts []string
+	var b strings.Builder
+	i := 2
+	for ; i < len(s) && len(parts) < 2; i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == delim:
+			b.WriteByte(delim)
+			i++
+		case s[i] == delim:
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	if len(parts) < 2 {
+		return nil, "", fmt.Errorf("transform: unterminated substitution %q", s)
+	}
+	flags, rest, _ := strings.Cut(s[i:], ";")
+	flags = strings.TrimSpace(flags)
+
+	pattern, global := parts[0], false
+	for _, f := range flags {
+		switch f {
+		case 'g':
+			global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return nil, "", fmt.Errorf("transform: unknown substitution flag %q", f)
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("transform: %w", err)
+	}
+	repl := sedGroup.ReplaceAllString(strings.ReplaceAll(parts[1], "$", "$$"), "$${$1}")
+
+	if global {
+		return func(line string) (string, error) {
+			return re.ReplaceAllString(line, repl), nil
+		}, rest, nil
+	}
+	return func(line string) (string, error) {
+		loc := re.FindStringSubmatchIndex(line)
+		if loc == nil {
+			return line, nil
+		}
+		out := re.ExpandString(nil, repl, line, loc)
+		return line[:loc[0]] + string(out) + line[loc[1]:], nil
+	}, rest, nil
+}
+
+// jsonFieldTransform applies fn to the value at the dotted path field of
+// a JSON object line. Lines that are not JSON objects, or lack the field,
+// pass through unchanged.
+func jsonFieldTransform(field string, fn func(v any) (any, error)) Transform {
+	path := strings.Split(field, ".")
+	return func(line string) (string, error) {
+		var obj map[string]any
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			return line, nil
+		}
+		parent := obj
+		for _, key := range path[:len(path)-1] {
+			next, ok := parent[key].(map[string]any)
+			if !ok {
+				return line, nil
+			}
+			parent = next
+		}
+		key := path[len(path)-1]
+		v, ok := parent[key]
+		if !ok {
+			return line, nil
+		}
+		nv, err := fn(v)
+		if err != nil {
+			return "", fmt.Errorf("transform: field %s: %w", field, err)
+		}
+		parent[key] = nv
+
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(obj); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(b.String(), "\n"), nil
+	}
+}
+
+// decodeBase64 decodes a base64 string value.
+func decodeBase64(v any) (any, error) {
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	b, err := decodeBase64String(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// decodeBase64String decodes standard or URL-safe, padded or unpadded
+// base64.
+func decodeBase64String(s string) ([]byte, error) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, errors.New("not base64")
+}
+
+// unwrapJSON parses a string holding JSON into its value. Strings that are
+// not JSON are kept.
+func unwrapJSON(v any) (any, error) {
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	var inner any
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if err := dec.Decode(&inner); err != nil || dec.More() {
+		return v, nil
+	}
+	return inner, nil
+}
+
+// unwrapLine turns a line that is a JSON string literal into its content.
+func unwrapLine(line string) (string, error) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, `"`) {
+		return line, nil
+	}
+	var s string
+	if err := json.Unmarshal([]byte(trimmed), &s); err != nil {
+		return line, nil
+	}
+	return s, nil
+}
+
+// TransformParser wraps a Parser and rewrites each line with a transform
+// pipeline (--pre-transform) before parsing it. The entry keeps the
+// original line as Raw.
+type TransformParser struct {
+	Parser
+	Transforms []Transform
+}
+
+// NewTransformParser wraps p with the given transforms.
+func NewTransformParser(p Parser, transforms []Transform) *TransformParser {
+	return &TransformParser{Parser: p, Transforms: transforms}
+}
+
+// Unwrap returns the wrapped parser.
+func (p *TransformParser) Unwrap() Parser {
+	return p.Parser
+}
+
+// Rewrap returns a copy of p wrapping inner.
+func (p *TransformParser) Rewrap(inner Parser) Parser {
+	c := *p
+	c.Parser = inner
+	return &c
+}
+
+// Parse transforms line and parses the result.
+func (p *TransformParser) Parse(line string) (*LogEntry, error) {
+	out, err := p.apply(line)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := p.Parser.Parse(out)
+	if err != nil {
+		return entry, err
+	}
+	entry.Raw = line
+	return entry, nil
+}
+
+// CanParse reports whether the wrapped parser handles the transformed line.
+func (p *TransformParser) CanParse(line string) bool {
+	out, err := p.apply(line)
+	return err == nil && p.Parser.CanParse(out)
+}
+
+func (p *TransformParser) apply(line string) (string, error) {
+	for _, t := range p.Transforms {
+		var err error
+		if line, err = t(line); err != nil {
+			return "", err
+		}
+	}
+	return line, nil
+}
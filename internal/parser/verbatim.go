@@ -0,0 +1,23 @@
+package parser
+
+// Verbatim reports whether every string value p produces, outside
+// synthetic "_" fields, is copied from the raw line unchanged, unless the
+// line contains a backslash escape. Raw-line screens such as
+// filter.ParallelFilter's rely on it; wrappers that rewrite, decode or
+// convert values are not verbatim.
+func Verbatim(p Parser) bool {
+	switch t := p.(type) {
+	case *JSONParser, *LogfmtParser, *AccessLogParser, *CEFParser, *LEEFParser, *GrokParser, *RegexParser:
+		return true
+	case *K8sParser:
+		return Verbatim(t.Parser)
+	case *AutoParser:
+		for _, sub := range t.parsers {
+			if !Verbatim(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+// Package version reports build information and supported features for
+// --version and --version --json, so wrapper tools can feature-detect.
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/ishk9/flog/internal/filter"
+)
+
+// Version is the release version, set at build time with
+// -ldflags "-X github.com/ishk9/flog/internal/version.Version=v1.2.3".
+var Version = "dev"
+
+// features lists optional capabilities and whether this build has them.
+var features = map[string]bool{
+	"gzip":  true,
+	"bzip2": true,
+	"zstd":  true,
+	"xz":    true,
+	"s3":    false,
+	"kafka": false,
+	"wasm":  false,
+}
+
+// formats lists the input formats the parser package understands.
+var formats = []string{"json", "logfmt", "access", "csv", "tsv", "cef", "leef"}
+
+// Info describes a flog build.
+type Info struct {
+	Version   string          `json:"version"`
+	Commit    string          `json:"commit,omitempty"`
+	BuildTime string          `json:"build_time,omitempty"`
+	Modified  bool            `json:"modified,omitempty"` // Built from a dirty tree
+	GoVersion string          `json:"go_version"`
+	Platform  string          `json:"platform"`
+	Features  map[string]bool `json:"features"`
+	Formats   []string        `json:"formats"`
+	Operators []string        `json:"operators"`
+}
+
+// Get returns the running build's Info. Commit and build time come from
+// the VCS stamp Go embeds when building from a checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Features:  features,
+		Formats:   formats,
+		Operators: filter.Operators(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Commit = s.Value
+			case "vcs.time":
+				info.BuildTime = s.Value
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// String renders the one-line --version output.
+func (i Info) String() string {
+	s := "flog " + i.Version
+	if i.Commit != "" {
+		commit := i.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if i.Modified {
+			commit += "-dirty"
+		}
+		s += " (" + commit + ")"
+	}
+	return fmt.Sprintf("%s %s %s", s, i.GoVersion, i.Platform)
+}
+
+// WriteJSON writes the --version --json report.
+func (i Info) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) // Keep operators such as ">=" readable
+	return enc.Encode(i)
+}
@@ -0,0 +1,185 @@
+// Package workdir manages the private directory where flog keeps sort
+// spill files, caches and run state (--work-dir), with a size quota and
+// cleanup, so those files do not end up scattered across shared hosts.
+package workdir
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of files kept in a work directory, one subdirectory each.
+const (
+	Spill = "spill" // Temporary files of a single run, such as sort runs
+	Cache = "cache" // Derived data that can be rebuilt, such as indexes
+	State = "state" // Data kept across runs, such as checkpoints
+)
+
+// Kinds lists every kind, in cleanup order.
+var Kinds = []string{Spill, Cache, State}
+
+// ErrQuota is returned when the work directory is over its quota.
+var ErrQuota = errors.New("workdir: over quota")
+
+// Dir is a work directory.
+type Dir struct {
+	Root  string
+	Quota int64 // Maximum total size in bytes; 0 for no limit
+}
+
+// DefaultRoot returns $FLOG_WORK_DIR, else flog under the user's cache
+// directory ($XDG_CACHE_HOME or ~/.cache), which is private to the user.
+func DefaultRoot() (string, error) {
+	if dir := os.Getenv("FLOG_WORK_DIR"); dir != "" {
+		return dir, nil
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("workdir: %w", err)
+	}
+	return filepath.Join(cache, "flog"), nil
+}
+
+// Open creates root if needed, readable by its owner only, and returns it
+// as a Dir. An existing root that other users can access is refused, so a
+// shared path such as /tmp/flog cannot leak or be planted with files.
+func Open(root string, quota int64) (*Dir, error) {
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, fmt.Errorf("workdir: %w", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("workdir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("workdir: %s is not a directory", root)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("workdir: %s is accessible by other users (mode %v); use chmod 700 or another --work-dir", root, info.Mode().Perm())
+	}
+	return &Dir{Root: root, Quota: quota}, nil
+}
+
+// Path returns the subdirectory for kind, creating it if needed.
+func (d *Dir) Path(kind string) (string, error) {
+	path := filepath.Join(d.Root, kind)
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return "", fmt.Errorf("workdir: %w", err)
+	}
+	return path, nil
+}
+
+// Usage returns the total size of the files under kind, or under the
+// whole directory when kind is empty.
+func (d *Dir) Usage(kind string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(filepath.Join(d.Root, kind), func(_ string, e fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || e.IsDir() {
+			return err
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil // Removed while walking
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return total, fmt.Errorf("workdir: %w", err)
+	}
+	return total, nil
+}
+
+// Check reports ErrQuota when the directory, plus n bytes about to be
+// written, would exceed the quota.
+func (d *Dir) Check(n int64) error {
+	if d.Quota <= 0 {
+		return nil
+	}
+	used, err := d.Usage("")
+	if err != nil {
+		return err
+	}
+	if used+n > d.Quota {
+		return fmt.Errorf("%w: %s used of %s in %s; run flog cache clean",
+			ErrQuota, FormatSize(used), FormatSize(d.Quota), d.Root)
+	}
+	return nil
+}
+
+// Clean removes the files of the given kinds (every kind when none are
+// given) that were last modified before cutoff, or all of them for a zero
+// cutoff, and returns the bytes freed (flog cache clean [--older-than]).
+func (d *Dir) Clean(cutoff time.Time, kinds ...string) (int64, error) {
+	if len(kinds) == 0 {
+		kinds = Kinds
+	}
+	var freed int64
+	for _, kind := range kinds {
+		root := filepath.Join(d.Root, kind)
+		err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil || e.IsDir() {
+				return err
+			}
+			info, err := e.Info()
+			if err != nil {
+				return nil
+			}
+			if !cutoff.IsZero() && !info.ModTime().Before(cutoff) {
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			freed += info.Size()
+			return nil
+		})
+		if err != nil {
+			return freed, fmt.Errorf("workdir: %w", err)
+		}
+	}
+	return freed, nil
+}
+
+// ParseSize parses a quota such as "500M", "2G" or "1.5GiB" (binary
+// units; a plain number is bytes).
+func ParseSize(s string) (int64, error) {
+	t := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B"), "I")
+	mult := int64(1)
+	if n := len(t); n > 0 {
+		if i := strings.IndexByte("KMGT", t[n-1]); i >= 0 {
+			mult, t = 1<<(10*(i+1)), t[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(t, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("workdir: invalid size %q", s)
+	}
+	return int64(f * float64(mult)), nil
+}
+
+// FormatSize renders a size with a binary unit.
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
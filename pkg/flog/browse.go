@@ -0,0 +1,132 @@
+package flog
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/output"
+)
+
+// Browser holds a loaded log in memory and refilters it as the query is
+// edited. It is the model behind an interactive front end (flog
+// --interactive): the filter box calls SetQuery on every edit, the
+// results pane shows Results, the sidebar FieldCounts, and the export key
+// calls Export. Drawing and key bindings are up to the front end.
+type Browser struct {
+	matcher Matcher
+	all     []*LogEntry // Entries loaded, in input order
+	query   string
+	results []*LogEntry
+
+	lines, parseErrors int64 // Lines read and lines that did not parse
+}
+
+// FieldCount is how many entries of a result set have a field.
+type FieldCount struct {
+	Field string
+	Count int
+}
+
+// Browse loads the entries of r that match the pipeline's filter, in
+// input order, for refining interactively. They are all kept in memory.
+func (p *Pipeline) Browse(ctx context.Context, r io.Reader) (*Browser, error) {
+	pf := p.newFilter()
+	pf.Ordered = true
+	entries, errc := p.run(ctx, r, pf)
+	b := &Browser{matcher: p.matcher}
+	for e := range entries {
+		b.all = append(b.all, e)
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	b.lines, b.parseErrors = pf.TotalLines(), pf.ParseErrors()
+	b.results = b.all
+	return b, nil
+}
+
+// SetQuery filters the loaded entries with query. A query that does not
+// parse, as while it is being typed, returns the error and keeps the
+// previous results.
+func (b *Browser) SetQuery(query string) error {
+	if strings.TrimSpace(query) == "" {
+		b.query, b.results = query, b.all
+		return nil
+	}
+	chain, err := filter.ParseQuery(query)
+	if err != nil {
+		return err
+	}
+	results := make([]*LogEntry, 0, len(b.results))
+	for _, e := range b.all {
+		e.Matched = nil // Left from the previous query
+		if b.matcher.Match(e, chain) {
+			results = append(results, e)
+		}
+	}
+	b.query, b.results = query, results
+	return nil
+}
+
+// Query returns the query of the current results.
+func (b *Browser) Query() string {
+	return b.query
+}
+
+// Total returns the number of entries loaded.
+func (b *Browser) Total() int {
+	return len(b.all)
+}
+
+// Stats profiles the fields of the current results, or only fields when
+// given, with the lines read and the parse errors of the load.
+func (b *Browser) Stats(fields ...string) *output.Stats {
+	s := output.NewStats()
+	s.Fields = fields
+	for _, e := range b.results {
+		s.Observe(e)
+	}
+	s.TotalLines, s.ParseErrors = b.lines, b.parseErrors
+	return s
+}
+
+// Results returns the entries matching the current query, in input
+// order. The slice must not be modified.
+func (b *Browser) Results() []*LogEntry {
+	return b.results
+}
+
+// FieldCounts returns the fields of the current results with the number
+// of entries having each, most common first, then by name.
+func (b *Browser) FieldCounts() []FieldCount {
+	counts := make(map[string]int)
+	for _, e := range b.results {
+		for k := range e.Fields {
+			counts[k]++
+		}
+	}
+	out := make([]FieldCount, 0, len(counts))
+	for k, n := range counts {
+		out = append(out, FieldCount{Field: k, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Field < out[j].Field
+	})
+	return out
+}
+
+// Export writes the raw lines of the current results to w.
+func (b *Browser) Export(w io.Writer) error {
+	for _, e := range b.results {
+		if _, err := io.WriteString(w, e.Raw+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package flog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ishk9/flog/internal/workdir"
+)
+
+// DefaultCalibrationSample is the input Calibrate reads by default.
+const DefaultCalibrationSample = 64 << 20
+
+// Calibration is a dry run of a query over the start of its input, with
+// projections for the whole input set (--calibrate).
+type Calibration struct {
+	SampleBytes int64         // Input bytes processed
+	TotalBytes  int64         // Size of the whole input set
+	Lines       int64         // Lines in the sample
+	Matches     int64         // Matches in the sample
+	Elapsed     time.Duration // Time spent on the sample
+	PeakHeap    uint64        // Highest heap in use while running, in bytes
+	MatchBytes  int64         // Raw size of the sample's matches
+}
+
+// MatchRate is the fraction of sampled lines that matched.
+func (c *Calibration) MatchRate() float64 {
+	if c.Lines == 0 {
+		return 0
+	}
+	return float64(c.Matches) / float64(c.Lines)
+}
+
+// scale is the ratio of the whole input to the sample.
+func (c *Calibration) scale() float64 {
+	if c.SampleBytes == 0 || c.TotalBytes < c.SampleBytes {
+		return 1
+	}
+	return float64(c.TotalBytes) / float64(c.SampleBytes)
+}
+
+// ProjectedRuntime extrapolates Elapsed to the whole input.
+func (c *Calibration) ProjectedRuntime() time.Duration {
+	return time.Duration(float64(c.Elapsed) * c.scale())
+}
+
+// ProjectedMatches extrapolates Matches to the whole input.
+func (c *Calibration) ProjectedMatches() int64 {
+	return int64(float64(c.Matches) * c.scale())
+}
+
+// ProjectedMatchBytes extrapolates MatchBytes to the whole input: the
+// memory needed by outputs that hold every match, such as sorting.
+// Streaming output needs about PeakHeap however large the input.
+func (c *Calibration) ProjectedMatchBytes() int64 {
+	return int64(float64(c.MatchBytes) * c.scale())
+}
+
+// String renders the report for stderr.
+func (c *Calibration) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "sample:     %s of %s (%d lines) in %s\n",
+		workdir.FormatSize(c.SampleBytes), workdir.FormatSize(c.TotalBytes), c.Lines, c.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "matches:    %d (%.3g%% of lines), ~%d projected\n",
+		c.Matches, 100*c.MatchRate(), c.ProjectedMatches())
+	runtime := c.ProjectedRuntime().Round(time.Millisecond)
+	if runtime > time.Minute {
+		runtime = runtime.Round(time.Second)
+	}
+	fmt.Fprintf(&b, "runtime:    ~%s projected\n", runtime)
+	fmt.Fprintf(&b, "memory:     %s peak heap streaming, ~%s to hold all matches\n",
+		workdir.FormatSize(int64(c.PeakHeap)), workdir.FormatSize(c.ProjectedMatchBytes()))
+	return b.String()
+}
+
+// Calibrate runs the pipeline over the first sample bytes of r (default
+// DefaultCalibrationSample) and measures it. total is the size of the
+// whole input set, in the same units as r yields, for the projections.
+func (p *Pipeline) Calibrate(ctx context.Context, r io.Reader, sample, total int64) (*Calibration, error) {
+	if sample <= 0 {
+		sample = DefaultCalibrationSample
+	}
+	counter := &countingReader{r: io.LimitReader(r, sample)}
+	c := &Calibration{TotalBytes: total}
+
+	runtime.GC()
+	var base runtime.MemStats
+	runtime.ReadMemStats(&base)
+	done := make(chan struct{})
+	peak := make(chan uint64, 1)
+	go func() {
+		var highest uint64
+		var ms runtime.MemStats
+		tick := time.NewTicker(20 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			runtime.ReadMemStats(&ms)
+			if ms.HeapInuse > highest {
+				highest = ms.HeapInuse
+			}
+			select {
+			case <-done:
+				peak <- highest
+				return
+			case <-tick.C:
+			}
+		}
+	}()
+
+	start := time.Now()
+	entries, errc := p.Run(ctx, counter)
+	for e := range entries {
+		c.Matches++
+		c.MatchBytes += int64(len(e.Raw))
+	}
+	err := <-errc
+	c.Elapsed = time.Since(start)
+	close(done)
+	if highest := <-peak; highest > base.HeapInuse {
+		c.PeakHeap = highest - base.HeapInuse
+	}
+
+	c.SampleBytes, c.Lines = counter.n, counter.lines
+	if c.TotalBytes < c.SampleBytes {
+		c.TotalBytes = c.SampleBytes
+	}
+	return c, err
+}
+
+// InputSize sums the sizes of local input files for Calibrate. known is
+// false when a size cannot be found, as for stdin or remote inputs.
+// Compressed files count at their size on disk, which understates their
+// share of the projections.
+func InputSize(paths ...string) (total int64, known bool) {
+	known = true
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if path == "-" || err != nil || !info.Mode().IsRegular() {
+			known = false
+			continue
+		}
+		total += info.Size()
+	}
+	return total, known
+}
+
+// countingReader counts the bytes and lines read through it.
+type countingReader struct {
+	r        io.Reader
+	n, lines int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	c.lines += int64(bytes.Count(p[:n], []byte("\n")))
+	return n, err
+}
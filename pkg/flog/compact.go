@@ -0,0 +1,183 @@
+package flog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ishk9/flog/internal/output"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// CompactOptions configures Compact.
+type CompactOptions struct {
+	Out       string    // Archive path; a .zst or .gz suffix compresses it
+	Format    string    // Archive format; only "ndjson" (the default) for now
+	Since     time.Time // Entries before are left out; zero for no bound
+	Until     time.Time // Entries after are left out; zero for no bound
+	TimeField string    // Empty for the entry's Timestamp or detected time field
+	Recursive bool      // Descend into subdirectories of the inputs
+}
+
+// CompactResult describes an archive written by Compact.
+type CompactResult struct {
+	Files       []CompactFile // Inputs read, in order
+	CompactFile               // Totals over Files; Path is empty
+	Verified    int64         // Entries read back from the archive
+}
+
+// CompactFile accounts for the records of one input of Compact. Records
+// is the sum of the other counts. Lines outside WithLines, or dropped by
+// WithMaxLineSize "skip", are not records.
+type CompactFile struct {
+	Path        string
+	Records     int64 // Records read
+	ParseErrors int64 // Records that did not parse
+	Filtered    int64 // Records not matching the pipeline's filter
+	Untimed     int64 // Entries left out for want of a timestamp to bound
+	OutOfRange  int64 // Entries left out for falling outside Since/Until
+	Entries     int64 // Entries written
+}
+
+// add adds the counts of f to c.
+func (c *CompactFile) add(f CompactFile) {
+	c.Records += f.Records
+	c.ParseErrors += f.ParseErrors
+	c.Filtered += f.Filtered
+	c.Untimed += f.Untimed
+	c.OutOfRange += f.OutOfRange
+	c.Entries += f.Entries
+}
+
+// expected is the number of entries the records should have produced.
+func (c *CompactFile) expected() int64 {
+	return c.Records - c.ParseErrors - c.Filtered - c.Untimed - c.OutOfRange
+}
+
+// Compact rewrites old logs of mixed formats into one archive (flog
+// compact DIR --since 30d --format ndjson --compress zst). Inputs are
+// expanded as on the command line and read in order; every entry
+// matching the pipeline's filter, and the Since/Until bounds when set, is
+// written as one JSON object of its fields. Field names are those left
+// by the pipeline's parser, so WithRename maps the formats to one schema.
+// Lines that do not parse are left out; WithOnParseError reports them.
+//
+// The archive is replaced atomically. It is then read back and the
+// entries counted: the run fails unless the count equals the records read
+// less those left out, and every record of each input is accounted for
+// (see CompactFile).
+func (p *Pipeline) Compact(ctx context.Context, inputs []string, opts CompactOptions) (*CompactResult, error) {
+	if opts.Format != "" && opts.Format != "ndjson" {
+		return nil, fmt.Errorf("flog: compact: unsupported format %q (want ndjson)", opts.Format)
+	}
+	if opts.Out == "" {
+		return nil, fmt.Errorf("flog: compact: no output archive")
+	}
+	files, _, err := parser.ExpandInputs(inputs, parser.ExpandOptions{Recursive: opts.Recursive, RecordSep: p.sep})
+	if err != nil {
+		return nil, fmt.Errorf("flog: compact: %w", err)
+	}
+	w, err := output.CreateFile(opts.Out, false)
+	if err != nil {
+		return nil, fmt.Errorf("flog: compact: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	res := &CompactResult{}
+	bounded := !opts.Since.IsZero() || !opts.Until.IsZero()
+	for _, path := range files {
+		f := CompactFile{Path: path}
+		err = p.compactFile(ctx, &f, func(entry *LogEntry) error {
+			if bounded {
+				t, ok := entryTime(entry, opts.TimeField)
+				if !ok {
+					f.Untimed++
+					return nil
+				}
+				if !opts.Since.IsZero() && t.Before(opts.Since) || !opts.Until.IsZero() && t.After(opts.Until) {
+					f.OutOfRange++
+					return nil
+				}
+			}
+			if err := enc.Encode(output.JSONFields(entry.Fields)); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, entry.LineNum, err)
+			}
+			f.Entries++
+			return nil
+		})
+		if err == nil && f.Entries != f.expected() {
+			err = fmt.Errorf("%s: %d records read, %d accounted for", path, f.Records, f.ParseErrors+f.Filtered+f.Untimed+f.OutOfRange+f.Entries)
+		}
+		if err != nil {
+			w.Abort()
+			return nil, fmt.Errorf("flog: compact: %w", err)
+		}
+		res.Files = append(res.Files, f)
+		res.add(f)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("flog: compact: %w", err)
+	}
+
+	if res.Verified, err = countRecords(opts.Out); err != nil {
+		return res, fmt.Errorf("flog: compact: verify %s: %w", opts.Out, err)
+	}
+	if want := res.expected(); res.Verified != want {
+		return res, fmt.Errorf("flog: compact: verify %s: read back %d entries, want %d (%d records, %d parse errors, %d filtered, %d untimed, %d out of range)",
+			opts.Out, res.Verified, want, res.Records, res.ParseErrors, res.Filtered, res.Untimed, res.OutOfRange)
+	}
+	return res, nil
+}
+
+// compactFile passes the matching entries of f.Path to fn in input order,
+// and counts the records read, the parse errors and the records filtered
+// out into f.
+func (p *Pipeline) compactFile(ctx context.Context, f *CompactFile, fn func(*LogEntry) error) error {
+	rc, err := parser.OpenInput(f.Path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pf := p.newFilter()
+	pf.Ordered = true
+	entries, errc := p.run(ctx, rc, pf)
+	for entry := range entries {
+		if err == nil {
+			if err = fn(entry); err != nil {
+				cancel() // Drain the rest
+			}
+		}
+	}
+	if rerr := <-errc; err == nil {
+		err = rerr
+	}
+	f.Records, f.ParseErrors, f.Filtered = pf.TotalLines(), pf.ParseErrors(), pf.Filtered()
+	return err
+}
+
+// countRecords counts the JSON objects of an ndjson archive, failing on
+// anything else.
+func countRecords(path string) (int64, error) {
+	rc, err := parser.OpenInput(path)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	dec := json.NewDecoder(rc)
+	var n int64
+	for {
+		var obj map[string]any
+		if err := dec.Decode(&obj); err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, fmt.Errorf("record %d: %w", n+1, err)
+		}
+		n++
+	}
+}
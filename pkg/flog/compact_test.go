@@ -0,0 +1,53 @@
+package flog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestCompactAccountsForEveryRecord(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	os.WriteFile(a, []byte(`{"time":"2024-01-02T00:00:00Z","level":"error"}
+{"time":"2024-01-02T00:00:00Z","level":"info"}
+{"level":"error"}
+{"time":"2023-01-02T00:00:00Z","level":"error"}
+{"level":"error"
+`), 0o644)
+	os.WriteFile(b, []byte(`{"time":"2024-01-03T00:00:00Z","level":"error"}
+{"time":"2024-01-03T00:00:00Z","level":"debug"}
+`), 0o644)
+
+	p, err := NewPipeline("level:error", WithParser(parser.NewJSONParser()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := p.Compact(context.Background(), []string{a, b}, CompactOptions{
+		Out:   filepath.Join(dir, "out.ndjson"),
+		Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CompactFile{
+		{Path: a, Records: 5, ParseErrors: 1, Filtered: 1, Untimed: 1, OutOfRange: 1, Entries: 1},
+		{Path: b, Records: 2, Filtered: 1, Entries: 1},
+	}
+	if len(res.Files) != len(want) {
+		t.Fatalf("Files = %+v, want %+v", res.Files, want)
+	}
+	for i, f := range res.Files {
+		if f != want[i] {
+			t.Errorf("Files[%d] = %+v, want %+v", i, f, want[i])
+		}
+	}
+	if res.Records != 7 || res.Entries != 2 || res.Verified != 2 {
+		t.Errorf("totals = %+v, verified %d", res.CompactFile, res.Verified)
+	}
+}
@@ -0,0 +1,35 @@
+package flog
+
+import "io"
+
+// Exit statuses, as grep uses them, so flog works in shell conditionals
+// and health checks.
+const (
+	ExitMatch   = 0 // At least one entry matched
+	ExitNoMatch = 1 // Nothing matched
+	ExitError   = 2 // The input or the query could not be read
+)
+
+// ExitCode returns the exit status for a run that matched (or not) and
+// ended with err. As with grep -q, a match found before an error still
+// exits ExitMatch when quiet is set, since the answer is known.
+func ExitCode(matched bool, err error, quiet bool) int {
+	switch {
+	case matched && (err == nil || quiet):
+		return ExitMatch
+	case err != nil:
+		return ExitError
+	}
+	return ExitNoMatch
+}
+
+// Match reports whether any entry of r matches, reading no further than
+// the first match (-q/--quiet).
+func (p *Pipeline) Match(r io.Reader) (bool, error) {
+	it := p.Iter(r)
+	defer it.Close()
+	if it.Next() {
+		return true, nil
+	}
+	return false, it.Err()
+}
@@ -0,0 +1,112 @@
+package flog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// FinishTimeout bounds how long an on-finish command or webhook may take.
+const FinishTimeout = 30 * time.Second
+
+// Summary describes a finished run. It is passed to WithOnFinish, and
+// FinishCommand and FinishWebhook send it as JSON.
+type Summary struct {
+	Bytes       int64   `json:"bytes"`        // Input read
+	Lines       int64   `json:"lines"`        // Lines parsed and filtered
+	Matches     int64   `json:"matches"`      // Entries emitted
+	ParseErrors int64   `json:"parse_errors"` // Lines that failed to parse
+	Seconds     float64 `json:"duration_seconds"`
+	Interrupted bool    `json:"interrupted"`     // Stopped by its context, or an Iterator closed early
+	Error       string  `json:"error,omitempty"` // Error that ended the run
+}
+
+// WithOnFinish calls fn once when a run has processed its input, or was
+// interrupted or failed, after the last match. It may be given more than
+// once; the functions are called in order.
+func WithOnFinish(fn func(s Summary)) Option {
+	return func(pl *Pipeline) { pl.hooks().onFinish = append(pl.hooks().onFinish, fn) }
+}
+
+// FinishCommand returns a WithOnFinish function that runs command with the
+// shell (--on-finish), with the summary JSON on its standard input and in
+// the FLOG_SUMMARY environment variable. The command's output, and its
+// failure, are written to stderr.
+func FinishCommand(command string, stderr io.Writer) func(Summary) {
+	return func(s Summary) {
+		data, _ := json.Marshal(s)
+		ctx, cancel := context.WithTimeout(context.Background(), FinishTimeout)
+		defer cancel()
+		shell, flag := "sh", "-c"
+		if runtime.GOOS == "windows" {
+			shell, flag = "cmd", "/C"
+		}
+		cmd := exec.CommandContext(ctx, shell, flag, command)
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stdout, cmd.Stderr = stderr, stderr
+		cmd.Env = append(os.Environ(), "FLOG_SUMMARY="+string(data))
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(stderr, "flog: on-finish: %q: %v\n", command, err)
+		}
+	}
+}
+
+// FinishWebhook returns a WithOnFinish function that POSTs the summary
+// JSON to url (--on-finish-webhook). Failures, including responses other
+// than 2xx, are written to stderr.
+func FinishWebhook(url string, stderr io.Writer) func(Summary) {
+	return func(s Summary) {
+		if err := postSummary(url, s); err != nil {
+			fmt.Fprintf(stderr, "flog: on-finish webhook: %v\n", err)
+		}
+	}
+}
+
+func postSummary(url string, s Summary) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), FinishTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// summary builds the Summary of a run that ended with err, errStopped for
+// an Iterator closed early.
+func (t *tracker) summary(err error) Summary {
+	s := Summary{
+		Bytes:       t.bytes.Load(),
+		Lines:       t.filter.TotalLines(),
+		Matches:     t.matches.Load(),
+		ParseErrors: t.filter.ParseErrors(),
+		Seconds:     time.Since(t.start).Seconds(),
+		Interrupted: errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errStopped),
+	}
+	if err != nil && !errors.Is(err, errStopped) {
+		s.Error = err.Error()
+	}
+	return s
+}
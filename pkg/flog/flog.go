@@ -0,0 +1,143 @@
+// Package flog is the public API for embedding flog's log parsing and
+// filtering in Go programs.
+//
+//	p, err := flog.NewPipeline("level:error,status>=500")
+//	if err != nil { ... }
+//	entries, errc := p.Run(ctx, file)
+//	for e := range entries {
+//		fmt.Println(e.Raw)
+//	}
+//	if err := <-errc; err != nil { ... }
+//
+// Query gives a pull-based Iterator instead, for callers that manage
+// concurrency and backpressure themselves. WithOnMatch, WithOnParseError,
+// WithOnProgress and WithOnFinish hook into either without replacing the
+// loop.
+package flog
+
+import (
+	"io"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// LogEntry is a parsed log line with flattened fields.
+type LogEntry = parser.LogEntry
+
+// Parser converts raw lines into LogEntries.
+type Parser = parser.Parser
+
+// Condition is a single filter condition.
+type Condition = filter.Condition
+
+// FilterChain is a tree of conditions combined with AND/OR logic.
+type FilterChain = filter.FilterChain
+
+// Record is an input line with its position, as passed to
+// WithOnParseError.
+type Record = filter.Record
+
+// Rule is a named filter for WithClassifier, such as
+// Rule{Name: "auth-failure", Filter: "event:login,result:failure"}.
+type Rule = filter.Rule
+
+// LintWarning is a hint about one condition of a query, passed to
+// WithLint. Its String method renders it for stderr.
+type LintWarning = filter.Warning
+
+// Matcher evaluates a FilterChain against entries.
+type Matcher = filter.Matcher
+
+// QueryParser parses the filter DSL into FilterChains.
+type QueryParser = filter.QueryParser
+
+// NewQueryParser creates a QueryParser.
+func NewQueryParser() *QueryParser {
+	return filter.NewQueryParser()
+}
+
+// ParseQuery parses a filter expression such as "level:error,status>=500".
+func ParseQuery(query string) (*FilterChain, error) {
+	return filter.ParseQuery(query)
+}
+
+// NewMatcher returns the default Matcher.
+func NewMatcher() Matcher {
+	return filter.NewMatcher()
+}
+
+// LoadWatchlist reads the values for WithWatchlist from a file, one per
+// line, skipping blank lines, # comments and repeats.
+func LoadWatchlist(path string) ([]string, error) {
+	return filter.LoadWatchlist(path)
+}
+
+// K8sOptions selects the pod logs read by OpenK8s.
+type K8sOptions = parser.K8sOptions
+
+// OpenK8s streams the logs of the containers selected by opts through
+// kubectl, for a Pipeline created with WithK8s. Closing it stops kubectl.
+func OpenK8s(opts K8sOptions) (io.ReadCloser, error) {
+	return parser.OpenK8s(opts)
+}
+
+// NewAutoParser returns a Parser that detects JSON, access log, CEF, LEEF
+// and logfmt lines automatically.
+func NewAutoParser() Parser {
+	return parser.NewAutoParser()
+}
+
+// NewJSONParser returns a Parser for JSON-lines input.
+func NewJSONParser() Parser {
+	return parser.NewJSONParser()
+}
+
+// NewLogfmtParser returns a Parser for logfmt input.
+func NewLogfmtParser() Parser {
+	return parser.NewLogfmtParser()
+}
+
+// NewGrokParser returns a Parser for unstructured lines matching a grok
+// pattern such as "%{IP:client} %{WORD:method} %{URIPATH:path}", with the
+// standard pattern library and the extra NAME → regex patterns given.
+func NewGrokParser(pattern string, extra map[string]string) (Parser, error) {
+	g := parser.NewGrok()
+	for name, p := range extra {
+		g.Add(name, p)
+	}
+	return g.Compile(pattern)
+}
+
+// NewRegexParser returns a Parser for unstructured lines matching a
+// regular expression whose named groups become fields, such as
+// `^(?P<ts>\S+) (?P<level>\w+) (?P<message>.*)$`. Values are typed as in
+// logfmt.
+func NewRegexParser(expr string) (Parser, error) {
+	return parser.NewRegexParser(expr)
+}
+
+// NewAccessLogParser returns a Parser for Apache/Nginx access logs.
+func NewAccessLogParser() Parser {
+	return parser.NewAccessLogParser()
+}
+
+// NewCSVParser returns a Parser for comma-separated rows with the given
+// column names. Without them, a Pipeline names the columns from the
+// header row of each input, or col1, col2... for an input without one.
+func NewCSVParser(columns ...string) Parser {
+	return parser.NewCSVParser(columnNames(columns))
+}
+
+// NewTSVParser is NewCSVParser for tab-separated rows.
+func NewTSVParser(columns ...string) Parser {
+	return parser.NewTSVParser(columnNames(columns))
+}
+
+// columnNames returns columns, or nil for none.
+func columnNames(columns []string) []string {
+	if len(columns) == 0 {
+		return nil
+	}
+	return columns
+}
@@ -0,0 +1,40 @@
+package flog
+
+import (
+	"context"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Follow filters the files matching patterns, paths or quoted globs such
+// as "/var/log/app/*.log", as they grow (-t/--follow), until ctx is done:
+// files that appear later are read from their start, and rotated,
+// truncated and deleted files are handled as parser.Follower describes.
+// Files present at the start are read from their end unless fromStart is
+// set. Records are split as Run splits them, with the maximum line size,
+// oversize mode, record separator and ANSI stripping of the options.
+// WithExpectEvery adds warnings when no entry matches for a while.
+//
+// The error channel yields ctx.Err(), or the error that stopped the
+// follow, once; the entries channel must be drained.
+func (p *Pipeline) Follow(ctx context.Context, patterns []string, fromStart bool) (<-chan *LogEntry, <-chan error) {
+	fw, err := parser.NewFollower(patterns, parser.FollowOptions{
+		FromStart: fromStart,
+		ChunkSize: p.chunkSize,
+		Reader:    p.newReader(),
+	})
+	if err != nil {
+		entries, errc := make(chan *LogEntry), make(chan error, 1)
+		close(entries)
+		errc <- err
+		close(errc)
+		return entries, errc
+	}
+	entries, errc := p.stream(ctx, p.newFilter(), func(fn func(parser.Chunk) bool) error {
+		return fw.Run(ctx, fn)
+	})
+	if p.expect > 0 {
+		entries = p.heartbeat(entries)
+	}
+	return entries, errc
+}
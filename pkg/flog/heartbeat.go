@@ -0,0 +1,61 @@
+package flog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// HeartbeatField is set, to the expected interval, on the warning entries
+// WithExpectEvery adds to Follow's output.
+const HeartbeatField = "_heartbeat"
+
+// WithExpectEvery makes Follow emit a warning entry whenever no entry has
+// matched for interval (--expect-every), so a source that went silent is
+// noticed. The warning has level "warn", a message giving the time of the
+// last match (or of the start), and HeartbeatField; it repeats every
+// interval until an entry matches again. Warnings are not matches, so
+// WithOnMatch does not see them. Run and the other batch methods ignore
+// the option.
+func WithExpectEvery(interval time.Duration) Option {
+	return func(pl *Pipeline) { pl.expect = interval }
+}
+
+// heartbeat passes entries through, adding a warning after every interval
+// without one.
+func (p *Pipeline) heartbeat(entries <-chan *LogEntry) <-chan *LogEntry {
+	out := make(chan *LogEntry, cap(entries))
+	go func() {
+		defer close(out)
+		timer := time.NewTimer(p.expect)
+		defer timer.Stop()
+		last := time.Now()
+		for {
+			select {
+			case e, ok := <-entries:
+				if !ok {
+					return
+				}
+				out <- e
+				last = time.Now()
+				timer.Reset(p.expect)
+			case now := <-timer.C:
+				out <- silence(last, now, p.expect)
+				timer.Reset(p.expect)
+			}
+		}
+	}()
+	return out
+}
+
+// silence creates the warning for no match between last and now.
+func silence(last, now time.Time, interval time.Duration) *LogEntry {
+	msg := fmt.Sprintf("no matching lines since %s (expected every %s)", last.Format(time.RFC3339), interval)
+	e := parser.NewLogEntry("flog: "+msg, 0)
+	e.Timestamp = now
+	e.Fields["level"] = "warn"
+	e.Fields["message"] = msg
+	e.Fields[HeartbeatField] = interval.String()
+	return e
+}
@@ -0,0 +1,185 @@
+package flog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// ProgressInterval is how often OnProgress is called at most while a run
+// is going; a final report follows the end of the input.
+const ProgressInterval = 100 * time.Millisecond
+
+// Progress is a snapshot of a run, passed to OnProgress.
+type Progress struct {
+	Bytes       int64 // Input read so far, up to the end of the last record
+	Lines       int64 // Lines parsed and filtered
+	Matches     int64 // Entries emitted
+	ParseErrors int64 // Lines that failed to parse
+	Done        bool  // Set on the final report
+}
+
+// hooks are the callbacks set by WithOnMatch, WithOnParseError,
+// WithOnProgress and WithOnFinish.
+type hooks struct {
+	onMatch      func(e *LogEntry)
+	onParseError func(rec Record, err error)
+	onProgress   func(Progress)
+	onFinish     []func(Summary)
+}
+
+// WithOnMatch calls fn with every matching entry, in the order entries are
+// emitted and before the caller receives them, so embedders can react per
+// match without running the loop themselves.
+func WithOnMatch(fn func(e *LogEntry)) Option {
+	return func(pl *Pipeline) { pl.hooks().onMatch = fn }
+}
+
+// WithOnParseError calls fn with every line that fails to parse and the
+// parser's error. Lines the filter rules out before parsing are not
+// reported (see filter.ParallelFilter).
+func WithOnParseError(fn func(rec Record, err error)) Option {
+	return func(pl *Pipeline) { pl.hooks().onParseError = fn }
+}
+
+// WithOnProgress calls fn every ProgressInterval at most while the input
+// is processed, and once more when it is done.
+func WithOnProgress(fn func(p Progress)) Option {
+	return func(pl *Pipeline) { pl.hooks().onProgress = fn }
+}
+
+func (p *Pipeline) hooks() *hooks {
+	if p.hook == nil {
+		p.hook = &hooks{}
+	}
+	return p.hook
+}
+
+// tracker calls the hooks of one run. Calls are serialized, so the
+// callbacks never run concurrently with each other, and a nil tracker
+// does nothing.
+type tracker struct {
+	hooks   *hooks
+	filter  *filter.ParallelFilter
+	bytes   atomic.Int64
+	matches atomic.Int64
+
+	mu    sync.Mutex
+	last  time.Time // Time of the last progress report
+	start time.Time
+	err   error // Error the input ended with
+}
+
+// track returns the tracker for a run filtered by pf, or nil when no hooks
+// are set. It installs the parse error hook on pf.
+func (p *Pipeline) track(pf *filter.ParallelFilter) *tracker {
+	if p.hook == nil {
+		return nil
+	}
+	now := time.Now()
+	t := &tracker{hooks: p.hook, filter: pf, last: now, start: now}
+	if fn := p.hook.onParseError; fn != nil {
+		pf.OnParseError = func(rec Record, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			fn(rec, err)
+		}
+	}
+	return t
+}
+
+// read records that c was read from the input.
+func (t *tracker) read(c parser.Chunk) {
+	if t == nil || len(c.Offsets) == 0 {
+		return
+	}
+	last := len(c.Lines) - 1
+	t.bytes.Store(c.Offsets[last] + int64(len(c.Lines[last])) + 1)
+}
+
+// end records the error reading the input ended with, if any.
+func (t *tracker) end(err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+}
+
+// match reports a matching entry.
+func (t *tracker) match(e *LogEntry) {
+	if t == nil {
+		return
+	}
+	t.matches.Add(1)
+	if t.hooks.onMatch != nil {
+		t.mu.Lock()
+		t.hooks.onMatch(e)
+		t.mu.Unlock()
+	}
+	t.progress(false)
+}
+
+// progress reports progress when the interval has passed, or always when
+// done.
+func (t *tracker) progress(done bool) {
+	if t == nil || t.hooks.onProgress == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !done && time.Since(t.last) < ProgressInterval {
+		return
+	}
+	t.last = time.Now()
+	t.hooks.onProgress(Progress{
+		Bytes:       t.bytes.Load(),
+		Lines:       t.filter.TotalLines(),
+		Matches:     t.matches.Load(),
+		ParseErrors: t.filter.ParseErrors(),
+		Done:        done,
+	})
+}
+
+// watch passes entries through, calling the hooks, and reports progress
+// even while no entries arrive.
+func (t *tracker) watch(entries <-chan *LogEntry) <-chan *LogEntry {
+	out := make(chan *LogEntry, cap(entries))
+	go func() {
+		defer close(out)
+		tick := time.NewTicker(ProgressInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case e, ok := <-entries:
+				if !ok {
+					t.progress(true)
+					t.finish()
+					return
+				}
+				t.match(e)
+				out <- e
+			case <-tick.C:
+				t.progress(false)
+			}
+		}
+	}()
+	return out
+}
+
+// finish calls the WithOnFinish functions.
+func (t *tracker) finish() {
+	if t == nil || len(t.hooks.onFinish) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.summary(t.err)
+	for _, fn := range t.hooks.onFinish {
+		fn(s)
+	}
+}
@@ -0,0 +1,115 @@
+package flog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ishk9/flog/internal/checkpoint"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// WithIncremental makes RunFiles skip the files that earlier runs with the
+// same state file read to the end and that have not changed since, and
+// read only the appended part of those that have grown (--incremental).
+// Files are recognized by path, size, modification time and a hash of
+// their head. The state file is written when RunFiles has read every
+// input; files are not recorded after an error or cancellation.
+func WithIncremental(state string) Option {
+	return func(pl *Pipeline) { pl.state = state }
+}
+
+// RunFiles filters the files inputs expand to, as on the command line
+// (globs, directories, compressed, remote or "-"), one after another, as
+// Run does for a reader. Entries carry their input as Source. Line
+// numbers of a grown file read with WithIncremental count from where the
+// earlier run stopped.
+func (p *Pipeline) RunFiles(ctx context.Context, inputs []string) (<-chan *LogEntry, <-chan error) {
+	entries, errc := p.stream(ctx, p.newFilter(), func(fn func(parser.Chunk) bool) error {
+		return p.scanFiles(inputs, fn)
+	})
+	if p.sortKey != nil {
+		return p.sorted(ctx, entries, errc)
+	}
+	return entries, errc
+}
+
+// scanFiles reads the chunks of every input in turn, numbering them across
+// inputs, and updates the incremental state once all are read.
+func (p *Pipeline) scanFiles(inputs []string, fn func(parser.Chunk) bool) error {
+	files, _, err := parser.ExpandInputs(inputs, parser.ExpandOptions{RecordSep: p.sep})
+	if err != nil {
+		return err
+	}
+	var state *checkpoint.State
+	if p.state != "" {
+		if state, err = checkpoint.Load(p.state); err != nil {
+			return fmt.Errorf("flog: incremental: %w", err)
+		}
+	}
+
+	seq, stopped := 0, false
+	for _, path := range files {
+		tracked := state != nil && path != "-" && !parser.IsRemote(path) && !parser.IsBlob(path)
+		var offset int64
+		if tracked {
+			status, prev, err := state.Check(path)
+			if err != nil {
+				return fmt.Errorf("flog: incremental: %w", err)
+			}
+			switch status {
+			case checkpoint.StatusUnchanged:
+				continue
+			case checkpoint.StatusGrown:
+				offset = prev.Size
+			}
+		}
+		err := p.scanFile(path, offset, func(c parser.Chunk) bool {
+			c.Seq, c.Source = seq, path
+			seq++
+			stopped = !fn(c)
+			return !stopped
+		})
+		if err != nil || stopped {
+			return err
+		}
+		if tracked {
+			if err := state.Mark(path); err != nil {
+				return fmt.Errorf("flog: incremental: %w", err)
+			}
+		}
+	}
+	if state != nil {
+		if err := state.Save(); err != nil {
+			return fmt.Errorf("flog: incremental: %w", err)
+		}
+	}
+	return nil
+}
+
+// scanFile reads the chunks of path from offset. Compressed files cannot
+// be read from the middle and are read whole.
+func (p *Pipeline) scanFile(path string, offset int64, fn func(parser.Chunk) bool) error {
+	if offset > 0 {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		head := make([]byte, 8)
+		n, _ := f.ReadAt(head, 0)
+		if parser.DetectCompression(head[:n]) == parser.CompressNone {
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				return err
+			}
+			return p.newReader().ScanChunks(f, p.chunkSize, fn)
+		}
+	}
+	rc, err := parser.OpenInput(path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return p.newReader().ScanChunks(rc, p.chunkSize, fn)
+}
@@ -0,0 +1,116 @@
+package flog
+
+import (
+	"io"
+	"iter"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Iterator pulls matching entries from a stream one at a time, on the
+// caller's goroutine: input is read and parsed only as Next is called, so
+// the caller sets the pace and decides what runs concurrently.
+//
+//	it := flog.Query(file, "level:error")
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Println(it.Entry().Raw)
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator struct {
+	next  func() (*LogEntry, bool)
+	stop  func()
+	entry *LogEntry
+	err   error
+}
+
+// Query returns an Iterator over the entries of src matching query,
+// configured by opts as NewPipeline is. Options that only affect
+// Pipeline.Run, such as WithWorkers, are ignored. An invalid query is
+// reported by Err.
+func Query(src io.Reader, query string, opts ...Option) *Iterator {
+	p, err := NewPipeline(query, opts...)
+	if err != nil {
+		return &Iterator{err: err}
+	}
+	return p.Iter(src)
+}
+
+// Iter returns an Iterator over the entries of r that match.
+func (p *Pipeline) Iter(r io.Reader) *Iterator {
+	it := &Iterator{}
+	pf := p.newFilter()
+	t := p.track(pf)
+	it.next, it.stop = iter.Pull(func(yield func(*LogEntry) bool) {
+		scan := func(yield func(*LogEntry) bool) error {
+			return p.newReader().ScanChunks(r, p.chunkSize, func(c parser.Chunk) bool {
+				t.read(c)
+				for _, e := range pf.FilterChunk(c, p.chain) {
+					t.match(e)
+					if !yield(e) {
+						return false
+					}
+				}
+				t.progress(false)
+				return true
+			})
+		}
+		closed := false
+		emit := func(e *LogEntry) bool {
+			closed = !yield(e)
+			return !closed
+		}
+		var err error
+		if p.sortKey != nil {
+			err = p.sortEach(scan, emit)
+		} else {
+			err = scan(emit)
+		}
+		if err != nil {
+			it.err = err
+		}
+		t.progress(true)
+		if closed && err == nil {
+			err = errStopped
+		}
+		t.end(err)
+		t.finish()
+	})
+	return it
+}
+
+// Next advances to the next matching entry. It returns false at the end
+// of the input, on error (see Err) or after Close.
+func (it *Iterator) Next() bool {
+	if it.next == nil {
+		return false
+	}
+	e, ok := it.next()
+	if !ok {
+		it.Close()
+		return false
+	}
+	it.entry = e
+	return true
+}
+
+// Entry returns the entry Next advanced to.
+func (it *Iterator) Entry() *LogEntry {
+	return it.entry
+}
+
+// Err returns the error that ended the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close stops the iteration and releases its resources. It does not close
+// the source. Close may be called more than once.
+func (it *Iterator) Close() error {
+	if it.stop != nil {
+		it.stop()
+		it.next, it.stop = nil, nil
+	}
+	it.entry = nil
+	return nil
+}
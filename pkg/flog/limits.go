@@ -0,0 +1,125 @@
+package flog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// ErrBusy is returned by Collect when every query slot is taken.
+var ErrBusy = errors.New("flog: too many concurrent queries")
+
+// Limits caps the resources one query may use, for network-facing callers
+// where one heavy query must not starve the others. Zero values mean no
+// limit.
+type Limits struct {
+	MaxBytes   int64         // Input bytes scanned
+	MaxRuntime time.Duration // Time spent scanning
+	MaxResults int           // Matching entries returned
+	Slots      *Slots        // Shared bound on queries running at once
+}
+
+// Slots bounds how many queries run at once across the callers sharing
+// it.
+type Slots struct {
+	c chan struct{}
+}
+
+// NewSlots returns Slots admitting n concurrent queries.
+func NewSlots(n int) *Slots {
+	return &Slots{c: make(chan struct{}, max(n, 1))}
+}
+
+func (s *Slots) acquire() bool {
+	if s == nil {
+		return true
+	}
+	select {
+	case s.c <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Slots) release() {
+	if s != nil {
+		<-s.c
+	}
+}
+
+// Result is the outcome of Collect. When a limit stopped the query early,
+// Truncated is set, Reason names the limit ("bytes", "runtime" or
+// "results") and Entries holds the matches found up to then.
+type Result struct {
+	Entries   []*LogEntry
+	Scanned   int64 // Input bytes scanned
+	Truncated bool
+	Reason    string
+}
+
+// Collect runs the pipeline over r within lim and returns the matches.
+// The query runs on the calling goroutine only, whatever WithWorkers
+// says, so concurrent queries share the CPUs instead of each claiming all
+// of them. Reaching a limit is not an error; ErrBusy is returned when lim
+// has no free slot, and read errors as usual, with the partial result.
+func (p *Pipeline) Collect(ctx context.Context, r io.Reader, lim Limits) (*Result, error) {
+	if !lim.Slots.acquire() {
+		return nil, ErrBusy
+	}
+	defer lim.Slots.release()
+	if lim.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lim.MaxRuntime)
+		defer cancel()
+	}
+
+	res := &Result{}
+	stop := func(reason string) bool {
+		res.Truncated, res.Reason = true, reason
+		return false
+	}
+	pf := p.newFilter()
+	err := p.newReader().ScanChunks(r, p.chunkSize, func(c parser.Chunk) bool {
+		if ctx.Err() != nil {
+			return stop("runtime")
+		}
+		if lim.MaxBytes > 0 && len(c.Offsets) > 0 && c.Offsets[0] >= lim.MaxBytes {
+			return stop("bytes")
+		}
+		n := len(c.Lines)
+		if lim.MaxBytes > 0 {
+			// Only the records that start within the budget.
+			for n > 0 && c.Offsets[n-1] >= lim.MaxBytes {
+				n--
+			}
+		}
+		part := c
+		part.Lines, part.Offsets = c.Lines[:n], c.Offsets[:n]
+		if c.LineNums != nil {
+			part.LineNums = c.LineNums[:n]
+		}
+		if c.Context != nil {
+			part.Context = c.Context[:n]
+		}
+		last := n - 1
+		res.Scanned = c.Offsets[last] + int64(len(c.Lines[last])) + 1
+		for _, e := range pf.FilterChunk(part, p.chain) {
+			if lim.MaxResults > 0 && len(res.Entries) == lim.MaxResults {
+				return stop("results")
+			}
+			res.Entries = append(res.Entries, e)
+		}
+		if n < len(c.Lines) {
+			return stop("bytes")
+		}
+		return true
+	})
+	if errors.Is(err, context.DeadlineExceeded) && lim.MaxRuntime > 0 {
+		err = nil
+	}
+	return res, err
+}
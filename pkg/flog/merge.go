@@ -0,0 +1,129 @@
+package flog
+
+import (
+	"container/heap"
+	"context"
+	"io"
+	"time"
+
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Merge filters several inputs at once and interleaves their matching
+// entries into one stream in time order (--merge-by-time), to correlate
+// events across services. Each input is expected to be in time order
+// already, as logs are, so this is a k-way merge rather than a sort: an
+// entry that goes back in time still follows its predecessors. Entries
+// without a timestamp keep the time of the entry before them in their
+// input, so continuation lines stay with their event; ties go to the
+// input listed first. Entries carry their input as Source.
+//
+// Inputs are opened as on the command line (compressed, remote or "-").
+// As with Run, the entries channel must be drained, and the error channel
+// yields the first error once.
+func (p *Pipeline) Merge(ctx context.Context, inputs []string) (<-chan *LogEntry, <-chan error) {
+	out := make(chan *LogEntry, p.chunkSize)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(out)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		h := &timeHeap{}
+		var srcs []*timeSource
+		var err error
+		for i, path := range inputs {
+			rc, oerr := parser.OpenInput(path)
+			if oerr != nil {
+				err = oerr
+				break
+			}
+			pf := p.newFilter()
+			pf.Ordered = true
+			entries, ec := p.run(ctx, rc, pf)
+			srcs = append(srcs, &timeSource{order: i, path: path, entries: entries, errc: ec, closer: rc})
+		}
+		if err != nil {
+			cancel() // Stop the inputs already started
+		} else {
+			for _, src := range srcs {
+				if src.advance() {
+					heap.Push(h, src)
+				}
+			}
+			for h.Len() > 0 && ctx.Err() == nil {
+				src := h.sources[0]
+				out <- src.entry
+				if src.advance() {
+					heap.Fix(h, 0)
+				} else {
+					heap.Pop(h)
+				}
+			}
+		}
+
+		for _, src := range srcs {
+			for range src.entries {
+			}
+			if serr := <-src.errc; err == nil {
+				err = serr
+			}
+			src.closer.Close()
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		errc <- err
+	}()
+	return out, errc
+}
+
+// timeSource is one input being merged.
+type timeSource struct {
+	order   int // Input position; breaks ties
+	path    string
+	entries <-chan *LogEntry
+	errc    <-chan error
+	closer  io.Closer
+
+	entry *LogEntry // Next entry to emit
+	at    time.Time // Its time, or that of the last timed entry
+}
+
+// advance reads the next entry of src, reporting false at its end.
+func (src *timeSource) advance() bool {
+	e, ok := <-src.entries
+	if !ok {
+		return false
+	}
+	if e.Source == "" {
+		e.Source = src.path
+	}
+	if t, ok := entryTime(e, ""); ok {
+		src.at = t
+	}
+	src.entry = e
+	return true
+}
+
+type timeHeap struct {
+	sources []*timeSource
+}
+
+func (h *timeHeap) Len() int { return len(h.sources) }
+func (h *timeHeap) Less(i, j int) bool {
+	a, b := h.sources[i], h.sources[j]
+	if c := a.at.Compare(b.at); c != 0 {
+		return c < 0
+	}
+	return a.order < b.order
+}
+func (h *timeHeap) Swap(i, j int) { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+func (h *timeHeap) Push(x any)    { h.sources = append(h.sources, x.(*timeSource)) }
+func (h *timeHeap) Pop() any {
+	src := h.sources[len(h.sources)-1]
+	h.sources = h.sources[:len(h.sources)-1]
+	return src
+}
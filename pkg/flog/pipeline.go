@@ -0,0 +1,500 @@
+package flog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"time"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/output"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// Pipeline reads a log stream, parses it and emits the entries matching a
+// filter, using a worker pool.
+type Pipeline struct {
+	chain     *FilterChain
+	parser    Parser
+	matcher   Matcher
+	workers   int
+	chunkSize int
+	ordered   bool
+	timeField *string           // Set by WithTimestamps
+	locale    string            // Set by WithLocale
+	transform string            // Set by WithPreTransform
+	expand    []string          // Set by WithExpandJSON
+	decode    map[string]string // Set by WithDecodeField
+	skip      int               // Set by WithLines
+	head      int               // Set by WithLines
+	jsonDocs  bool              // Set by WithJSONDocuments
+	recordSep string            // Set by WithRecordSeparator
+	sep       *regexp.Regexp    // Parsed recordSep
+	maxLine   int               // Set by WithMaxLineSize
+	oversize  string            // Set by WithMaxLineSize
+	mode      parser.OversizeMode
+	hook      *hooks // Set by the WithOn options
+	levels    string // Set by WithLevels
+	levelKeys []string
+	derive    []string          // Set by WithDerive
+	rename    []parser.Rename   // Set by WithRename
+	stripANSI bool              // Set by WithStripANSI
+	multiline *string           // Set by WithMultiline
+	mlStart   *regexp.Regexp    // Parsed multiline
+	rules     []filter.Rule     // Set by WithClassifier
+	watch     []string          // Set by WithWatchlist
+	watchKey  string            // Set by WithWatchlist
+	k8s       *string           // Set by WithK8s
+	header    string            // Set by WithHeaderContext
+	headerRe  *regexp.Regexp    // Parsed header
+	rangeKey  string            // Set by WithTimeRange
+	since     string            // Set by WithTimeRange
+	until     string            // Set by WithTimeRange
+	lint      func(LintWarning) // Set by WithLint
+	lintOn    []*LogEntry       // Set by WithLint
+	state     string            // Set by WithIncremental
+	expect    time.Duration     // Set by WithExpectEvery
+	sort      string            // Set by WithSort
+	sortMem   int64             // Set by WithSort
+	sortKey   *output.SortKey   // Parsed sort
+}
+
+// Option configures a Pipeline.
+type Option func(*Pipeline)
+
+// WithParser sets the line parser (default: NewAutoParser()).
+func WithParser(p Parser) Option {
+	return func(pl *Pipeline) { pl.parser = p }
+}
+
+// WithMatcher sets the condition evaluator (default: NewMatcher()).
+func WithMatcher(m Matcher) Option {
+	return func(pl *Pipeline) { pl.matcher = m }
+}
+
+// WithChain filters with a prebuilt chain instead of the query string.
+func WithChain(chain *FilterChain) Option {
+	return func(pl *Pipeline) { pl.chain = chain }
+}
+
+// WithWorkers sets the number of parse/filter workers (default: NumCPU).
+func WithWorkers(n int) Option {
+	return func(pl *Pipeline) { pl.workers = n }
+}
+
+// WithChunkSize sets the number of lines handed to a worker at once
+// (default: 1000).
+func WithChunkSize(n int) Option {
+	return func(pl *Pipeline) { pl.chunkSize = n }
+}
+
+// WithOrdered makes the pipeline emit matches in input order.
+func WithOrdered(ordered bool) Option {
+	return func(pl *Pipeline) { pl.ordered = ordered }
+}
+
+// WithTimestamps sets LogEntry.Timestamp on every entry, read from field or
+// from the detected time field when field is empty.
+func WithTimestamps(field string) Option {
+	return func(pl *Pipeline) { pl.timeField = &field }
+}
+
+// WithLocale converts string field values written in a locale's number
+// and month-name conventions, such as "de" for "1.234,5" and "12. März 2024".
+func WithLocale(name string) Option {
+	return func(pl *Pipeline) { pl.locale = name }
+}
+
+// WithPreTransform rewrites raw lines before parsing with a transform
+// pipeline such as "s/^\\w+ //; json-unwrap log" (see parser.ParseTransforms).
+func WithPreTransform(spec string) Option {
+	return func(pl *Pipeline) { pl.transform = spec }
+}
+
+// WithExpandJSON parses string fields holding JSON, such as a
+// double-encoded payload, and flattens them under the field's key ("*"
+// for every such field).
+func WithExpandJSON(fields ...string) Option {
+	return func(pl *Pipeline) { pl.expand = fields }
+}
+
+// WithDecodeField decodes field in place before filtering, with encoding
+// "base64", "url" or "hex". It may be given once per field.
+func WithDecodeField(field, encoding string) Option {
+	return func(pl *Pipeline) {
+		if pl.decode == nil {
+			pl.decode = make(map[string]string)
+		}
+		pl.decode[field] = encoding
+	}
+}
+
+// WithLines drops the first skip lines of the stream and reads at most
+// head lines after them (0 for no limit), before any parsing.
+func WithLines(skip, head int) Option {
+	return func(pl *Pipeline) { pl.skip, pl.head = skip, head }
+}
+
+// WithJSONDocuments reads the stream as a JSON array, or a sequence of
+// pretty-printed JSON objects, with one entry per element. Such input is
+// detected without it when it starts with "[{" or a lone "{" line.
+func WithJSONDocuments() Option {
+	return func(pl *Pipeline) { pl.jsonDocs = true }
+}
+
+// WithRecordSeparator splits the stream at sep instead of at newlines:
+// "rs" (RFC 7464 JSON text sequences), "nul", an escaped string such as
+// "\\x1e", or a regex between slashes (see parser.ParseRecordSeparator).
+func WithRecordSeparator(sep string) Option {
+	return func(pl *Pipeline) { pl.recordSep = sep }
+}
+
+// WithMaxLineSize sets the longest line accepted, in bytes (default 64
+// MiB), and what to do with longer ones: "fail" (the default), "split",
+// "truncate" or "skip".
+func WithMaxLineSize(n int, oversize string) Option {
+	return func(pl *Pipeline) { pl.maxLine, pl.oversize = n, oversize }
+}
+
+// WithStripANSI removes ANSI color and control sequences from lines as
+// they are read, before parsing, for logs captured from a terminal.
+func WithStripANSI() Option {
+	return func(pl *Pipeline) { pl.stripANSI = true }
+}
+
+// WithMultiline joins continuation lines, such as the frames of a stack
+// trace, to the record they continue, with "\n". A line matching the
+// regex start begins a new record; with an empty start, continuation lines
+// are detected (see parser.IsContinuation). A record's entry has the
+// number of its first line.
+func WithMultiline(start string) Option {
+	return func(pl *Pipeline) { pl.multiline = &start }
+}
+
+// WithLevels sets the severity order of log levels used by ordering
+// conditions such as level>=warn, least severe first with synonyms
+// separated by "|" (default filter.DefaultLevelOrder), and the fields
+// holding a level (default filter.DefaultLevelFields). It applies to the
+// default matcher, and to a FieldMatcher given to WithMatcher through a
+// copy, leaving the caller's matcher unchanged; other matchers ignore it.
+func WithLevels(order string, fields ...string) Option {
+	return func(pl *Pipeline) { pl.levels, pl.levelKeys = order, fields }
+}
+
+// WithRename renames the field from, as some inputs log it, to the common
+// name to before anything else reads the entry, as in WithRename("lvl",
+// "level"). It may be given once per field; renames apply in the order
+// given (see parser.RenameParser).
+func WithRename(from, to string) Option {
+	return func(pl *Pipeline) {
+		pl.rename = append(pl.rename, parser.Rename{From: from, To: to})
+	}
+}
+
+// WithDerive computes fields before matching, from specs such as
+// "latency_ms=duration*1000" or `host=split(addr,":")[0]` (see
+// filter.ParseDerivation), applied in order.
+func WithDerive(specs ...string) Option {
+	return func(pl *Pipeline) { pl.derive = append(pl.derive, specs...) }
+}
+
+// WithClassifier tags every entry with the names of the rules it matches,
+// in rule order, in the _tags field, which queries and later stages can
+// read, as in "_tags[]:auth-failure". Rule names must be unique. It may be
+// given more than once.
+func WithClassifier(rules ...Rule) Option {
+	return func(pl *Pipeline) { pl.rules = append(pl.rules, rules...) }
+}
+
+// WithWatchlist narrows the query to entries whose field contains any of
+// values, such as IPs or hashes read with LoadWatchlist; an empty field
+// searches the whole line. With four values or more, the values found are
+// listed in the _matched_patterns field.
+func WithWatchlist(field string, values []string) Option {
+	return func(pl *Pipeline) { pl.watchKey, pl.watch = field, values }
+}
+
+// WithK8s reads merged pod logs, as returned by OpenK8s: the
+// "[pod/NAME/CONTAINER] " prefix of each line is stripped before parsing
+// and kept in the _pod and _container fields, and namespace, when not
+// empty, in _namespace.
+func WithK8s(namespace string) Option {
+	return func(pl *Pipeline) { pl.k8s = &namespace }
+}
+
+// WithHeaderContext attaches session context to entries: a line matching
+// the regex header, such as `^=== run `, starts a section whose fields
+// (header's named groups, or else the line's key=value pairs) are added to
+// every following entry of the same input until the next header. Fields
+// an entry has are kept. Header lines are not entries themselves unless
+// they parse.
+func WithHeaderContext(header string) Option {
+	return func(pl *Pipeline) { pl.header = header }
+}
+
+// WithTimeRange narrows the query to entries whose field holds a time in
+// [since, until], or whose detected timestamp does when field is empty.
+// Bounds are times in any format flog parses, or durations such as "90s",
+// "2h" or "7d" meaning that long before NewPipeline is called; an empty
+// bound is left open.
+func WithTimeRange(field, since, until string) Option {
+	return func(pl *Pipeline) { pl.rangeKey, pl.since, pl.until = field, since, until }
+}
+
+// WithLint calls fn from NewPipeline with each anti-pattern found in the
+// query: regexes with a redundant leading or trailing .*, regexes that are
+// plain literals, and ordering comparisons that fall back to comparing
+// strings, judged from the query and from the sample entries, when given.
+// The pipeline runs the query as written.
+func WithLint(fn func(w LintWarning), sample ...*LogEntry) Option {
+	return func(pl *Pipeline) { pl.lint, pl.lintOn = fn, sample }
+}
+
+// NewPipeline creates a Pipeline for the given query. An empty query
+// matches every entry.
+func NewPipeline(query string, opts ...Option) (*Pipeline, error) {
+	p := &Pipeline{
+		parser:    parser.NewAutoParser(),
+		matcher:   filter.NewMatcher(),
+		workers:   runtime.NumCPU(),
+		chunkSize: 1000,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.recordSep != "" {
+		sep, err := parser.ParseRecordSeparator(p.recordSep)
+		if err != nil {
+			return nil, err
+		}
+		p.sep = sep
+	}
+	if p.oversize != "" {
+		mode, err := parser.ParseOversizeMode(p.oversize)
+		if err != nil {
+			return nil, err
+		}
+		p.mode = mode
+	}
+	if p.multiline != nil && *p.multiline != "" {
+		re, err := regexp.Compile(*p.multiline)
+		if err != nil {
+			return nil, fmt.Errorf("flog: multiline start: %w", err)
+		}
+		p.mlStart = re
+	}
+	if p.header != "" {
+		re, err := regexp.Compile(p.header)
+		if err != nil {
+			return nil, fmt.Errorf("flog: header context: %w", err)
+		}
+		p.headerRe = re
+	}
+	if p.sort != "" {
+		key, err := output.ParseSortKey(p.sort)
+		if err != nil {
+			return nil, err
+		}
+		p.sortKey = &key
+		p.ordered = true // Ties keep input order
+	}
+	if p.levels != "" || len(p.levelKeys) > 0 {
+		order := p.levels
+		if order == "" {
+			order = filter.DefaultLevelOrder
+		}
+		levels, err := filter.ParseLevels(order, p.levelKeys)
+		if err != nil {
+			return nil, err
+		}
+		if m, ok := p.matcher.(*filter.FieldMatcher); ok {
+			// The matcher may be shared with other pipelines. Its caches
+			// are built per chain, so the copy starts without them.
+			p.matcher = &filter.FieldMatcher{Highlight: m.Highlight, Missing: m.Missing, Levels: levels}
+		}
+	}
+	if p.transform != "" {
+		steps, err := parser.ParseTransforms(p.transform)
+		if err != nil {
+			return nil, err
+		}
+		p.parser = parser.NewTransformParser(p.parser, steps)
+	}
+	if p.k8s != nil {
+		p.parser = parser.NewK8sParser(p.parser, *p.k8s)
+	}
+	if len(p.rename) > 0 {
+		if err := parser.CheckRenames(p.rename); err != nil {
+			return nil, err
+		}
+		p.parser = parser.NewRenameParser(p.parser, p.rename)
+	}
+	if len(p.decode) > 0 {
+		for field, encoding := range p.decode {
+			if _, _, err := parser.ParseDecodeField(field + "=" + encoding); err != nil {
+				return nil, err
+			}
+		}
+		p.parser = parser.NewDecodeParser(p.parser, p.decode)
+	}
+	if len(p.expand) > 0 {
+		p.parser = parser.NewExpandParser(p.parser, p.expand)
+	}
+	if p.locale != "" {
+		l, err := parser.LookupLocale(p.locale)
+		if err != nil {
+			return nil, err
+		}
+		p.parser = parser.NewLocaleParser(p.parser, l)
+	}
+	if p.timeField != nil {
+		p.parser = parser.NewTimeParser(p.parser, *p.timeField)
+	}
+	if len(p.derive) > 0 {
+		ds := make([]*filter.Derivation, len(p.derive))
+		for i, spec := range p.derive {
+			d, err := filter.ParseDerivation(spec)
+			if err != nil {
+				return nil, err
+			}
+			ds[i] = d
+		}
+		p.parser = filter.NewDeriveParser(p.parser, ds)
+	}
+	if len(p.rules) > 0 {
+		c, err := filter.NewClassifier(p.rules, p.matcher)
+		if err != nil {
+			return nil, err
+		}
+		p.parser = filter.NewClassifyParser(p.parser, c)
+	}
+
+	if p.chain == nil {
+		chain, err := filter.ParseQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		p.chain = chain
+	}
+	if len(p.watch) > 0 {
+		field := p.watchKey
+		if field == "" {
+			field = filter.RawField
+		}
+		p.chain = filter.And(p.chain, filter.WatchlistChain(field, p.watch))
+	}
+	if p.since != "" || p.until != "" {
+		field := p.rangeKey
+		if field == "" {
+			field = filter.TimestampField
+		}
+		var bounds [2]time.Time
+		now := time.Now()
+		for i, s := range []string{p.since, p.until} {
+			if s == "" {
+				continue
+			}
+			t, err := filter.ParseTimeBound(s, now)
+			if err != nil {
+				return nil, err
+			}
+			bounds[i] = t
+		}
+		conds := filter.TimeRange(field, bounds[0], bounds[1])
+		p.chain = filter.And(p.chain, &filter.FilterChain{Logic: filter.LogicAnd, Conditions: conds})
+	}
+	if p.lint != nil {
+		for _, w := range filter.Lint(p.chain, p.lintOn...) {
+			p.lint(w)
+		}
+	}
+	return p, nil
+}
+
+// Run filters r and returns a channel of matching entries and a channel
+// that yields the read error (or ctx.Err()) once, after which both are
+// closed. The entries channel must be drained.
+func (p *Pipeline) Run(ctx context.Context, r io.Reader) (<-chan *LogEntry, <-chan error) {
+	entries, errc := p.run(ctx, r, p.newFilter())
+	if p.sortKey != nil {
+		return p.sorted(ctx, entries, errc)
+	}
+	return entries, errc
+}
+
+// run is Run with the ParallelFilter given.
+func (p *Pipeline) run(ctx context.Context, r io.Reader, pf *filter.ParallelFilter) (<-chan *LogEntry, <-chan error) {
+	return p.stream(ctx, pf, func(fn func(parser.Chunk) bool) error {
+		return p.newReader().ScanChunks(r, p.chunkSize, fn)
+	})
+}
+
+// stream filters the chunks scan passes to its function, as run does for
+// a reader.
+func (p *Pipeline) stream(ctx context.Context, pf *filter.ParallelFilter, scan func(fn func(parser.Chunk) bool) error) (<-chan *LogEntry, <-chan error) {
+	chunks := make(chan parser.Chunk, p.workers)
+	errc := make(chan error, 1)
+	t := p.track(pf)
+
+	go func() {
+		defer close(errc)
+		defer close(chunks)
+		err := scan(func(c parser.Chunk) bool {
+			select {
+			case chunks <- c:
+				t.read(c)
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if err == nil {
+			err = ctx.Err()
+		}
+		t.end(err)
+		errc <- err
+	}()
+
+	entries := pf.Filter(chunks, p.chain)
+	if t != nil {
+		entries = t.watch(entries)
+	}
+	return entries, errc
+}
+
+// newReader creates a StreamReader configured by the options.
+func (p *Pipeline) newReader() *parser.StreamReader {
+	reader := parser.NewStreamReader()
+	reader.SetLimits(p.skip, p.head)
+	if p.jsonDocs {
+		reader.SetJSONDocuments()
+	}
+	if p.sep != nil {
+		reader.SetRecordSeparator(p.sep)
+	}
+	if p.multiline != nil {
+		reader.SetMultiline(p.mlStart)
+	}
+	if p.headerRe != nil {
+		reader.SetHeaderContext(p.headerRe)
+	}
+	reader.SetMaxLineSize(p.maxLine, p.mode)
+	reader.SetStripANSI(p.stripANSI)
+	if comma, ok := parser.CSVComma(p.parser); ok {
+		reader.SetCSVHeader(comma)
+	}
+	return reader
+}
+
+// newFilter creates the ParallelFilter for the parser and matcher.
+func (p *Pipeline) newFilter() *filter.ParallelFilter {
+	return &filter.ParallelFilter{
+		Workers:   p.workers,
+		ChunkSize: p.chunkSize,
+		Ordered:   p.ordered,
+		Parser:    p.parser,
+		Matcher:   p.matcher,
+	}
+}
@@ -0,0 +1,705 @@
+package flog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ishk9/flog/internal/filter"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+func TestWithLevelsLeavesCallerMatcher(t *testing.T) {
+	m := &filter.FieldMatcher{Missing: filter.MissingNull}
+	shared, err := NewPipeline("level>=warn", WithMatcher(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom, err := NewPipeline("level>=warn", WithMatcher(m), WithLevels("warn<info<error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Levels != nil {
+		t.Fatalf("WithLevels set Levels on the caller's matcher")
+	}
+
+	count := func(p *Pipeline) int {
+		entries, errc := p.Run(context.Background(), strings.NewReader("{\"level\":\"info\"}\n"))
+		n := 0
+		for range entries {
+			n++
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(shared); n != 0 {
+		t.Errorf("default order: %d matches, want 0", n)
+	}
+	if n := count(custom); n != 1 {
+		t.Errorf("custom order: %d matches, want 1", n)
+	}
+	if cm := custom.matcher.(*filter.FieldMatcher); cm.Missing != filter.MissingNull {
+		t.Errorf("copy lost Missing: %v", cm.Missing)
+	}
+}
+
+func TestWithStripANSI(t *testing.T) {
+	input := "\x1b[31m{\"level\":\"error\",\"user\":\"Zdeněk\"}\x1b[0m\n" +
+		"\x1b[32m{\"level\":\"info\",\"user\":\"Zdeněk\"}\x1b[0m\n"
+	p, err := NewPipeline(`level:error,user:"Zdeněk"`, WithStripANSI(), WithOrdered(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, errc := p.Run(context.Background(), strings.NewReader(input))
+	var raws []string
+	for e := range entries {
+		raws = append(raws, e.Raw)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"level":"error","user":"Zdeněk"}`; len(raws) != 1 || raws[0] != want {
+		t.Errorf("matches = %q, want [%q]", raws, want)
+	}
+}
+
+// TestCSVHeaderPerInput runs one pipeline over inputs with different
+// headers, with many workers and one line per chunk, so no worker sees a
+// header it could take columns from.
+func TestCSVHeaderPerInput(t *testing.T) {
+	p, err := NewPipeline("status>=500", WithParser(NewCSVParser()), WithWorkers(8), WithChunkSize(1), WithOrdered(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []string{
+		"user,status\nalice,200\nbob,500\ncarol,503\n",
+		"status,user\n502,dave\n200,erin\n",
+	}
+	var users []string
+	for _, input := range inputs {
+		entries, errc := p.Run(context.Background(), strings.NewReader(input))
+		for e := range entries {
+			users = append(users, e.Fields["user"].(string))
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := strings.Join(users, ","), "bob,carol,dave"; got != want {
+		t.Errorf("matched users %s, want %s", got, want)
+	}
+
+	// Without a header the columns are numbered.
+	p, err = NewPipeline("col2>=500", WithParser(NewTSVParser()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, errc := p.Run(context.Background(), strings.NewReader("alice\t200\nbob\t500\n"))
+	var n int
+	for e := range entries {
+		if e.Fields["col1"] != "bob" {
+			t.Errorf("unexpected match %v", e.Fields)
+		}
+		n++
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("%d matches, want 1", n)
+	}
+}
+
+// TestLocate checks that an entry's ID leads back to it through Locate.
+func TestLocate(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}
+	for i, path := range paths {
+		data := "{\"level\":\"info\",\"n\":" + strconv.Itoa(i) + "}\x1e{\"level\":\"error\",\"n\":" + strconv.Itoa(i) + "}\x1e"
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := NewPipeline("level:error,n:1", WithRecordSeparator("rs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, errc := p.Run(context.Background(), f)
+	var ids []string
+	for e := range entries {
+		ids = append(ids, e.ID.String())
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 {
+		t.Fatalf("%d matches, want 1", len(ids))
+	}
+	ex, err := p.Locate(paths, ids[0], 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"level":"error","n":1}`; ex.Path != paths[1] || ex.Lines[ex.Match] != want || ex.Match != 1 {
+		t.Errorf("Locate = %+v, want %s in %s after one line", ex, want, paths[1])
+	}
+}
+
+// TestFollow checks that Follow filters lines appended to a file after it
+// has been read, and stops when its context is done.
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("{\"level\":\"error\",\"n\":0}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPipeline("level:error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries, errc := p.Follow(ctx, []string{path}, true)
+	if e := <-entries; e.Fields["n"] != int64(0) {
+		t.Fatalf("first match n=%v, want 0", e.Fields["n"])
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString("{\"level\":\"info\",\"n\":1}\n{\"level\":\"error\",\"n\":2}\n")
+
+	e := <-entries
+	if e.Fields["n"] != int64(2) || e.LineNum != 3 {
+		t.Errorf("appended match n=%v line %d, want n=2 line 3", e.Fields["n"], e.LineNum)
+	}
+	cancel()
+	for range entries {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestWithSort(t *testing.T) {
+	input := "{\"ms\":30,\"n\":1}\n{\"n\":2}\n{\"ms\":5,\"n\":3}\n{\"ms\":30,\"n\":4}\n{\"ms\":12,\"n\":5}\n"
+	want := "1,4,5,3,2"
+	p, err := NewPipeline("", WithSort("ms:desc", 64), WithWorkers(4), WithChunkSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := func(next func() (*LogEntry, bool)) string {
+		var ns []string
+		for e, ok := next(); ok; e, ok = next() {
+			ns = append(ns, fmt.Sprint(e.Fields["n"]))
+		}
+		return strings.Join(ns, ",")
+	}
+
+	entries, errc := p.Run(context.Background(), strings.NewReader(input))
+	got := order(func() (*LogEntry, bool) {
+		e, ok := <-entries
+		return e, ok
+	})
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Run order %s, want %s", got, want)
+	}
+
+	it := p.Iter(strings.NewReader(input))
+	defer it.Close()
+	got = order(func() (*LogEntry, bool) {
+		ok := it.Next()
+		return it.Entry(), ok
+	})
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Iter order %s, want %s", got, want)
+	}
+
+	if _, err := NewPipeline("", WithSort("ms:up", 0)); err == nil {
+		t.Errorf("WithSort(ms:up): no error")
+	}
+}
+
+// collect runs p over input and returns the matches in input order.
+func collect(t *testing.T, p *Pipeline, input string) []*LogEntry {
+	t.Helper()
+	entries, errc := p.Run(context.Background(), strings.NewReader(input))
+	var out []*LogEntry
+	for e := range entries {
+		out = append(out, e)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// TestWithMultiline checks that stack frames join the record they follow,
+// with detected continuations and with a start pattern.
+func TestWithMultiline(t *testing.T) {
+	input := "level=error msg=boom\n" +
+		"  at com.example.Main(Main.java:12)\n" +
+		"Caused by: java.io.IOException\n" +
+		"level=info msg=ok\n" +
+		"level=error msg=again\n"
+	for _, start := range []string{"", "^level="} {
+		p, err := NewPipeline(`level:error`, WithMultiline(start), WithOrdered(true), WithChunkSize(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := collect(t, p, input)
+		if len(got) != 2 {
+			t.Fatalf("start %q: %d matches, want 2", start, len(got))
+		}
+		want := "boom\n  at com.example.Main(Main.java:12)\nCaused by: java.io.IOException"
+		if got[0].Fields["msg"] != want || got[0].LineNum != 1 {
+			t.Errorf("start %q: first match msg=%q line %d, want %q line 1", start, got[0].Fields["msg"], got[0].LineNum, want)
+		}
+		if got[1].Fields["msg"] != "again" || got[1].LineNum != 5 {
+			t.Errorf("start %q: second match msg=%q line %d, want again line 5", start, got[1].Fields["msg"], got[1].LineNum)
+		}
+	}
+
+	if _, err := NewPipeline("", WithMultiline("(")); err == nil {
+		t.Errorf("WithMultiline(\"(\"): no error")
+	}
+}
+
+// TestWithClassifier checks that rule names are set before matching, so
+// the query can select on them.
+func TestWithClassifier(t *testing.T) {
+	rules := []Rule{
+		{Name: "auth-failure", Filter: "event:login,result:failure"},
+		{Name: "slow", Filter: "ms>1000"},
+	}
+	input := `{"event":"login","result":"failure","ms":1500,"n":1}` + "\n" +
+		`{"event":"login","result":"success","ms":20,"n":2}` + "\n" +
+		`{"event":"query","ms":3000,"n":3}` + "\n"
+	p, err := NewPipeline("_tags[]:slow", WithClassifier(rules...), WithOrdered(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collect(t, p, input)
+	if len(got) != 2 {
+		t.Fatalf("%d matches, want 2", len(got))
+	}
+	if tags := fmt.Sprint(got[0].Fields["_tags"]); tags != "[auth-failure slow]" {
+		t.Errorf("n=1 tags %s, want [auth-failure slow]", tags)
+	}
+	if tags := fmt.Sprint(got[1].Fields["_tags"]); tags != "[slow]" {
+		t.Errorf("n=3 tags %s, want [slow]", tags)
+	}
+
+	all, err := NewPipeline("", WithClassifier(rules...), WithOrdered(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, all, input); len(got) != 3 || got[1].Fields["_tags"] != nil {
+		t.Errorf("untagged entry: %v", got[1].Fields)
+	}
+
+	if _, err := NewPipeline("", WithClassifier(Rule{Name: "a", Filter: "x:1"}, Rule{Name: "a", Filter: "y:1"})); err == nil {
+		t.Errorf("duplicate rule names: no error")
+	}
+}
+
+// TestWithWatchlist checks that a watchlist read from a file narrows the
+// query, on a field and on whole lines.
+func TestWithWatchlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "iocs.txt")
+	list := "# known bad\n10.0.0.7\n\n 10.0.0.9 \n10.0.0.7\nevil.example\nbad.example\n"
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	values, err := LoadWatchlist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(values, ","); got != "10.0.0.7,10.0.0.9,evil.example,bad.example" {
+		t.Fatalf("LoadWatchlist = %s", got)
+	}
+
+	input := `{"level":"error","ip":"10.0.0.7","host":"bad.example"}` + "\n" +
+		`{"level":"info","ip":"10.0.0.9","host":"ok.example"}` + "\n" +
+		`{"level":"error","ip":"10.0.0.1","host":"ok.example"}` + "\n" +
+		`{"level":"error","ip":"10.0.0.2","ref":"evil.example"}` + "\n"
+	tests := []struct {
+		field    string
+		query    string
+		want     string // Matching IPs
+		patterns string // _matched_patterns of the first match
+	}{
+		{"ip", "", "10.0.0.7,10.0.0.9", "[10.0.0.7]"},
+		{"ip", "level:error", "10.0.0.7", "[10.0.0.7]"},
+		{"", "level:error", "10.0.0.7,10.0.0.2", "[10.0.0.7 bad.example]"},
+	}
+	for _, tt := range tests {
+		p, err := NewPipeline(tt.query, WithWatchlist(tt.field, values), WithOrdered(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ips []string
+		got := collect(t, p, input)
+		for _, e := range got {
+			ips = append(ips, e.Fields["ip"].(string))
+		}
+		if strings.Join(ips, ",") != tt.want {
+			t.Errorf("field %q query %q: matched %s, want %s", tt.field, tt.query, strings.Join(ips, ","), tt.want)
+			continue
+		}
+		if patterns := fmt.Sprint(got[0].Fields[filter.MatchedPatternsField]); patterns != tt.patterns {
+			t.Errorf("field %q query %q: patterns %s, want %s", tt.field, tt.query, patterns, tt.patterns)
+		}
+	}
+}
+
+// TestWithK8s runs a pipeline over OpenK8s with a stand-in for kubectl
+// that prints prefixed pod logs.
+func TestWithK8s(t *testing.T) {
+	dir := t.TempDir()
+	kubectl := filepath.Join(dir, "kubectl")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" > " + filepath.Join(dir, "args") + "\n" +
+		"echo '[pod/web-1/app] {\"level\":\"error\",\"msg\":\"boom\"}'\n" +
+		"echo '[pod/web-2/app] {\"level\":\"info\",\"msg\":\"ok\"}'\n" +
+		"echo '[pod/web-2/sidecar] level=error msg=\"proxy down\"'\n"
+	if err := os.WriteFile(kubectl, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(cmd string) { parser.KubectlCommand = cmd }(parser.KubectlCommand)
+	parser.KubectlCommand = kubectl
+
+	rc, err := OpenK8s(K8sOptions{Namespace: "shop", Selector: "app=web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	p, err := NewPipeline("level:error", WithK8s("shop"), WithOrdered(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, errc := p.Run(context.Background(), rc)
+	var got []string
+	for e := range entries {
+		got = append(got, fmt.Sprintf("%s/%s/%s:%s", e.Fields["_namespace"], e.Fields["_pod"], e.Fields["_container"], e.Fields["msg"]))
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if want := "shop/web-1/app:boom,shop/web-2/sidecar:proxy down"; strings.Join(got, ",") != want {
+		t.Errorf("matches %s, want %s", strings.Join(got, ","), want)
+	}
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "--prefix") || !strings.Contains(string(args), "-n shop") {
+		t.Errorf("kubectl args %q lack --prefix or -n shop", args)
+	}
+}
+
+// TestWithHeaderContext checks that header fields reach the entries of
+// their section across chunks, and that entry fields win.
+func TestWithHeaderContext(t *testing.T) {
+	input := "=== run id=abc env=prod ===\n" +
+		`{"level":"error","n":1}` + "\n" +
+		`{"level":"info","n":2}` + "\n" +
+		"=== run id=def env=staging ===\n" +
+		`{"level":"error","n":3,"env":"canary"}` + "\n"
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{`^=== run `, "1:abc/prod,3:def/canary"},
+		{`^=== run id=(?P<run>\w+)`, "1:abc/<nil>,3:def/canary"},
+	}
+	for _, tt := range tests {
+		p, err := NewPipeline("level:error", WithHeaderContext(tt.header), WithOrdered(true), WithWorkers(4), WithChunkSize(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range collect(t, p, input) {
+			run := e.Fields["id"]
+			if run == nil {
+				run = e.Fields["run"]
+			}
+			got = append(got, fmt.Sprintf("%v:%v/%v", e.Fields["n"], run, e.Fields["env"]))
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("header %q: got %s, want %s", tt.header, strings.Join(got, ","), tt.want)
+		}
+	}
+
+	if _, err := NewPipeline("", WithHeaderContext("[")); err == nil {
+		t.Errorf("WithHeaderContext(\"[\"): no error")
+	}
+}
+
+// TestWithLint checks the warnings reported for a query, with and without
+// sample entries, and that the query still runs as written.
+func TestWithLint(t *testing.T) {
+	sample := []*LogEntry{
+		{Fields: map[string]any{"latency": "12ms"}},
+		{Fields: map[string]any{"latency": "40ms"}},
+	}
+	tests := []struct {
+		query  string
+		sample []*LogEntry
+		want   []string // Substrings of the warnings, in order
+	}{
+		{"level:error", nil, nil},
+		{`msg~=".*timeout.*"`, nil, []string{"leading or trailing .*", "plain literal"}},
+		{`msg~="timeout"`, nil, []string{"plain literal"}},
+		{"latency>100", nil, nil},
+		{"latency>100", sample, []string{"latency holds non-numeric values"}},
+		{"version>abc", nil, []string{`"abc" is not a number or time`}},
+	}
+	for _, tt := range tests {
+		var got []string
+		_, err := NewPipeline(tt.query, WithLint(func(w LintWarning) { got = append(got, w.String()) }, tt.sample...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: warnings %q, want %d", tt.query, got, len(tt.want))
+			continue
+		}
+		for i, w := range tt.want {
+			if !strings.Contains(got[i], w) {
+				t.Errorf("%s: warning %q lacks %q", tt.query, got[i], w)
+			}
+		}
+	}
+
+	p, err := NewPipeline(`msg~=".*time.*"`, WithLint(func(LintWarning) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, p, "msg=timeout\nmsg=ok\n"); len(got) != 1 {
+		t.Errorf("%d matches, want 1", len(got))
+	}
+}
+
+// TestWithTimeRange checks absolute and relative bounds, on a named field
+// and on detected timestamps.
+func TestWithTimeRange(t *testing.T) {
+	recent := time.Now().Add(-30 * time.Minute).UTC().Format(time.RFC3339)
+	input := `{"ts":"2024-03-01T09:59:59Z","at":"2024-03-01T10:30:00Z","n":1}` + "\n" +
+		`{"ts":"2024-03-01T10:00:00Z","n":2}` + "\n" +
+		`{"ts":"2024-03-01T11:00:00Z","n":3}` + "\n" +
+		`{"ts":"2024-03-01T11:00:01Z","n":4}` + "\n" +
+		`{"ts":"` + recent + `","n":5}` + "\n"
+	tests := []struct {
+		field, since, until string
+		want                string
+	}{
+		{"", "2024-03-01T10:00:00Z", "2024-03-01T11:00:00Z", "2,3"},
+		{"ts", "2024-03-01T11:00:00Z", "", "3,4,5"},
+		{"", "", "2024-03-01T10:00:00Z", "1,2"},
+		{"", "1h", "", "5"},
+		{"at", "2024-03-01T10:00:00Z", "", "1"},
+	}
+	for _, tt := range tests {
+		p, err := NewPipeline("", WithTimeRange(tt.field, tt.since, tt.until), WithOrdered(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ns []string
+		for _, e := range collect(t, p, input) {
+			ns = append(ns, fmt.Sprint(e.Fields["n"]))
+		}
+		if got := strings.Join(ns, ","); got != tt.want {
+			t.Errorf("%q [%s, %s]: matched %s, want %s", tt.field, tt.since, tt.until, got, tt.want)
+		}
+	}
+
+	if _, err := NewPipeline("", WithTimeRange("", "yesterday", "")); err == nil {
+		t.Errorf("WithTimeRange(yesterday): no error")
+	}
+}
+
+// TestWithIncremental runs RunFiles three times over a directory with a
+// state file: unchanged files are skipped, and only the appended part of
+// a grown file is read.
+func TestWithIncremental(t *testing.T) {
+	dir := t.TempDir()
+	logs := filepath.Join(dir, "logs")
+	if err := os.Mkdir(logs, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, data string, flag int) {
+		f, err := os.OpenFile(filepath.Join(logs, name), flag|os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.log", "{\"level\":\"error\",\"n\":1}\n{\"level\":\"info\",\"n\":2}\n", os.O_TRUNC)
+	write("b.log", "{\"level\":\"error\",\"n\":3}\n", os.O_TRUNC)
+
+	p, err := NewPipeline("level:error", WithIncremental(filepath.Join(dir, "state.json")), WithOrdered(true), WithChunkSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func() string {
+		entries, errc := p.RunFiles(context.Background(), []string{logs})
+		var got []string
+		for e := range entries {
+			got = append(got, fmt.Sprintf("%s:%v@%d", filepath.Base(e.Source), e.Fields["n"], e.LineNum))
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(got, ",")
+	}
+	if got, want := run(), "a.log:1@1,b.log:3@1"; got != want {
+		t.Errorf("first run %s, want %s", got, want)
+	}
+	if got := run(); got != "" {
+		t.Errorf("second run %s, want nothing", got)
+	}
+	write("a.log", "{\"level\":\"error\",\"n\":4}\n", os.O_APPEND)
+	write("c.log", "{\"level\":\"error\",\"n\":5}\n", os.O_TRUNC)
+	if got, want := run(), "a.log:4@1,c.log:5@1"; got != want {
+		t.Errorf("third run %s, want %s", got, want)
+	}
+
+	// Without a state file every file is read.
+	all, err := NewPipeline("level:error", WithOrdered(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, errc := all.RunFiles(context.Background(), []string{logs})
+	n := 0
+	for range entries {
+		n++
+	}
+	if err := <-errc; err != nil || n != 4 {
+		t.Errorf("without state: %d matches, %v; want 4", n, err)
+	}
+}
+
+// TestWithExpectEvery checks that Follow warns while no entry matches,
+// and passes matches through.
+func TestWithExpectEvery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("{\"level\":\"info\"}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPipeline("level:error", WithExpectEvery(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries, errc := p.Follow(ctx, []string{path}, true)
+	for range 2 {
+		e := <-entries
+		if e.Fields[HeartbeatField] != "50ms" || e.Fields["level"] != "warn" {
+			t.Fatalf("got %v, want a heartbeat warning", e.Fields)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString("{\"level\":\"error\"}\n")
+	for e := range entries {
+		if e.Fields[HeartbeatField] == nil {
+			if e.Fields["level"] != "error" {
+				t.Errorf("match %v, want level error", e.Fields)
+			}
+			break
+		}
+	}
+	cancel()
+	for range entries {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+// TestWithOnFinish checks the summary passed at the end of Run and of an
+// Iterator closed early, and that it reaches a command and a webhook.
+func TestWithOnFinish(t *testing.T) {
+	dir := t.TempDir()
+	var posted Summary
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	var sums []Summary
+	var stderr strings.Builder
+	out := filepath.Join(dir, "summary.json")
+	p, err := NewPipeline("level:error",
+		WithOnFinish(func(s Summary) { sums = append(sums, s) }),
+		WithOnFinish(FinishCommand("cat > "+out, &stderr)),
+		WithOnFinish(FinishWebhook(srv.URL, &stderr)),
+		WithOnFinish(FinishWebhook(srv.URL+"/missing\x7f", &stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "{\"level\":\"error\"}\nnot a log line\n{\"level\":\"info\"}\n{\"level\":\"error\"}\n"
+	collect(t, p, input)
+	if len(sums) != 1 {
+		t.Fatalf("%d summaries, want 1", len(sums))
+	}
+	s := sums[0]
+	if s.Lines != 4 || s.Matches != 2 || s.Bytes != int64(len(input)) || s.Interrupted || s.Error != "" {
+		t.Errorf("summary %+v, want 4 lines and 2 matches", s)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromCmd Summary
+	if err := json.Unmarshal(data, &fromCmd); err != nil || fromCmd.Matches != 2 {
+		t.Errorf("command got %s (%v), want the summary", data, err)
+	}
+	if posted.Matches != 2 {
+		t.Errorf("webhook got %+v, want the summary", posted)
+	}
+	if !strings.Contains(stderr.String(), "on-finish webhook") {
+		t.Errorf("stderr %q lacks the failed webhook", stderr.String())
+	}
+
+	sums = nil
+	it := p.Iter(strings.NewReader(input))
+	it.Next()
+	it.Close()
+	if len(sums) != 1 || !sums[0].Interrupted || sums[0].Error != "" {
+		t.Errorf("closed iterator: summaries %+v, want one interrupted", sums)
+	}
+}
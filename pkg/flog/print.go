@@ -0,0 +1,172 @@
+package flog
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync/atomic"
+
+	"github.com/ishk9/flog/internal/output"
+)
+
+// ThemeSpec defines a theme of pretty output over a built-in one; see
+// WithThemeSpec.
+type ThemeSpec = output.ThemeSpec
+
+// Printer writes entries as pretty output (--output pretty): one line per
+// entry with the timestamp, the level and the message first, then the
+// other fields as key=value in name order.
+//
+//	pr, err := flog.NewPrinter(os.Stdout, flog.WithTheme("dark"), flog.WithTerminal(os.Stdout, true))
+//	if err != nil { ... }
+//	defer pr.Close()
+//	for e := range entries {
+//		if err := pr.Print(e); err != nil { ... }
+//	}
+type Printer struct {
+	w     io.Writer
+	f     *output.PrettyFormatter
+	buf   []byte
+	width atomic.Int64   // Columns, updated on resize
+	stop  chan struct{}  // Closed by Close
+	sigs  chan os.Signal // Terminal resizes
+
+	theme   string     // Set by WithTheme
+	spec    *ThemeSpec // Set by WithThemeSpec
+	symbols string     // Set by WithSymbols
+	diff    *string    // Set by WithDiffFields
+	cols    int        // Set by WithWidth
+	wrap    bool       // Set by WithWidth and WithTerminal
+	term    *os.File   // Set by WithTerminal
+}
+
+// PrintOption configures a Printer.
+type PrintOption func(*Printer)
+
+// WithTheme colors the output with the built-in theme called name
+// ("dark", "light" or "solarized"; see ThemeNames). "none", like not
+// setting a theme, prints without color.
+func WithTheme(name string) PrintOption {
+	return func(pr *Printer) { pr.theme, pr.spec = name, nil }
+}
+
+// WithThemeSpec colors the output with the theme spec defines: styles per
+// token class, level value and field name over a built-in base theme.
+func WithThemeSpec(spec ThemeSpec) PrintOption {
+	return func(pr *Printer) { pr.theme, pr.spec = "", &spec }
+}
+
+// WithSymbols starts lines with a symbol of their level: ✖ for errors, ⚠
+// for warnings and ℹ for info, or x, ! and i in ASCII. mode is "unicode",
+// "ascii", "auto" for Unicode when LC_ALL, LC_CTYPE or LANG selects a
+// UTF-8 locale and ASCII otherwise, or "none", the default.
+func WithSymbols(mode string) PrintOption {
+	return func(pr *Printer) { pr.symbols = mode }
+}
+
+// WithDiffFields marks the field values that changed since the previous
+// entry with the same value of key, or since the previous entry when key
+// is empty, to show state transitions in event streams. Changed values are
+// styled by the theme, or without one marked with a '*' before their name.
+func WithDiffFields(key string) PrintOption {
+	return func(pr *Printer) { pr.diff = &key }
+}
+
+// WithWidth fits lines into cols columns: longer ones are cut with an
+// ellipsis or, with wrap, continued on lines indented to the message.
+func WithWidth(cols int, wrap bool) PrintOption {
+	return func(pr *Printer) { pr.cols, pr.wrap, pr.term = cols, wrap, nil }
+}
+
+// WithTerminal fits lines into the width of the terminal f, as WithWidth
+// does, and follows changes of its size until Close. Lines are not fitted
+// when f is not a terminal, as when output is redirected.
+func WithTerminal(f *os.File, wrap bool) PrintOption {
+	return func(pr *Printer) { pr.cols, pr.wrap, pr.term = 0, wrap, f }
+}
+
+// ThemeNames returns the names of the built-in themes.
+func ThemeNames() []string {
+	return output.ThemeNames()
+}
+
+// NewPrinter creates a Printer writing to w.
+func NewPrinter(w io.Writer, opts ...PrintOption) (*Printer, error) {
+	pr := &Printer{w: w}
+	for _, opt := range opts {
+		opt(pr)
+	}
+
+	var theme *output.Theme
+	var err error
+	switch {
+	case pr.spec != nil:
+		theme, err = pr.spec.Theme()
+	case pr.theme != "":
+		theme, err = output.LookupTheme(pr.theme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pr.f = output.NewPrettyFormatter(theme)
+	if pr.symbols != "" {
+		if pr.f.Symbols, err = output.LevelSymbols(pr.symbols); err != nil {
+			return nil, err
+		}
+	}
+	pr.f.Wrap = pr.wrap
+	if pr.diff != nil {
+		pr.f.Diff = output.NewFieldDiff(*pr.diff)
+	}
+	pr.width.Store(int64(pr.cols))
+	if pr.term != nil {
+		pr.watchTerminal()
+	}
+	return pr, nil
+}
+
+// watchTerminal reads the width of pr.term now and on every resize.
+func (pr *Printer) watchTerminal() {
+	cols, ok := output.TerminalWidth(pr.term)
+	if !ok {
+		return
+	}
+	pr.width.Store(int64(cols))
+	if len(output.ResizeSignals) == 0 {
+		return
+	}
+	pr.stop = make(chan struct{})
+	pr.sigs = make(chan os.Signal, 1)
+	signal.Notify(pr.sigs, output.ResizeSignals...)
+	go func() {
+		for {
+			select {
+			case <-pr.sigs:
+				if cols, ok := output.TerminalWidth(pr.term); ok {
+					pr.width.Store(int64(cols))
+				}
+			case <-pr.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Print writes e as one line, or several when wrapped.
+func (pr *Printer) Print(e *LogEntry) error {
+	pr.f.Width = int(pr.width.Load())
+	pr.buf = append(pr.f.AppendFormat(pr.buf[:0], e), '\n')
+	_, err := pr.w.Write(pr.buf)
+	return err
+}
+
+// Close stops following the size of the terminal given to WithTerminal.
+// The writer is not closed.
+func (pr *Printer) Close() error {
+	if pr.stop != nil {
+		signal.Stop(pr.sigs)
+		close(pr.stop)
+		pr.stop = nil
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package flog
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrinter(t *testing.T) {
+	p, err := NewPipeline("", WithParser(NewJSONParser()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := collect(t, p, `{"level":"error","msg":"failed","user":"ann"}`+"\n")
+
+	var plain strings.Builder
+	pr, err := NewPrinter(&plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := pr.Print(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "ERROR failed user=ann\n"; plain.String() != want {
+		t.Errorf("plain output = %q, want %q", plain.String(), want)
+	}
+
+	var colored strings.Builder
+	pr, err = NewPrinter(&colored, WithThemeSpec(ThemeSpec{Base: "light", Fields: map[string]string{"user": "green"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr.Print(entries[0])
+	if got := colored.String(); !strings.Contains(got, "\x1b[32mann\x1b[0m") || !strings.Contains(got, "\x1b[1;31mERROR") {
+		t.Errorf("themed output = %q", got)
+	}
+
+	if _, err := NewPrinter(&plain, WithTheme("neon")); err == nil {
+		t.Error("unknown theme accepted")
+	}
+}
+
+func TestPrinterWidth(t *testing.T) {
+	p, err := NewPipeline("", WithParser(NewJSONParser()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := collect(t, p, `{"level":"info","msg":"request done","path":"/api/users"}`+"\n")
+
+	var out strings.Builder
+	pr, err := NewPrinter(&out, WithWidth(22, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr.Print(entries[0])
+	if want := "INFO  request done\n      path=/api/users\n"; out.String() != want {
+		t.Errorf("wrapped output = %q, want %q", out.String(), want)
+	}
+
+	// A file is no terminal: lines are left whole.
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	out.Reset()
+	pr, err = NewPrinter(&out, WithTerminal(f, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	pr.Print(entries[0])
+	if want := "INFO  request done path=/api/users\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPrinterSymbols(t *testing.T) {
+	p, err := NewPipeline("", WithParser(NewJSONParser()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := collect(t, p, `{"level":"warn","msg":"slow"}`+"\n"+`{"level":"debug","msg":"tick"}`+"\n")
+
+	var out strings.Builder
+	pr, err := NewPrinter(&out, WithSymbols("unicode"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		pr.Print(e)
+	}
+	if want := "⚠ WARN  slow\n  DEBUG tick\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if _, err := NewPrinter(&out, WithSymbols("emoji")); err == nil {
+		t.Error("unknown symbols mode accepted")
+	}
+}
+
+func TestPrinterDiffFields(t *testing.T) {
+	p, err := NewPipeline("", WithParser(NewJSONParser()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := collect(t, p, `{"job":"a","state":"queued"}
+{"job":"b","state":"queued"}
+{"job":"a","state":"running"}
+`)
+	var out strings.Builder
+	pr, err := NewPrinter(&out, WithDiffFields("job"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		pr.Print(e)
+	}
+	if want := "job=a state=queued\njob=b state=queued\njob=a *state=running\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,147 @@
+package flog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ishk9/flog/internal/output"
+)
+
+// DefaultSample is the number of matches REPL shows after each query.
+const DefaultSample = 5
+
+// REPL is the engine of flog repl: it answers queries typed one per line
+// against a log loaded once into a Browser, with the match count and the
+// first few matches. Lines starting with ':' are commands:
+//
+//	:fields          fields of the matches, most common first
+//	:stats [f1,f2]   totals and field statistics of the matches
+//	:history         the queries entered so far, numbered
+//	:N               run query N of the history again
+//	:sample N        show N matches after each query
+//	:quit            stop, as does the end of input
+//
+// An empty line shows the current matches again. A query that does not
+// parse is reported and the previous one stays in effect.
+type REPL struct {
+	Prompt string // Written before each line is read; empty for none
+	Sample int    // Matches shown after each query
+
+	browser *Browser
+	history []string
+}
+
+// NewREPL creates a REPL over b with the "flog> " prompt.
+func NewREPL(b *Browser) *REPL {
+	return &REPL{Prompt: "flog> ", Sample: DefaultSample, browser: b}
+}
+
+// History returns the queries entered so far, oldest first. Repeating
+// the last query does not add it again.
+func (r *REPL) History() []string {
+	return r.history
+}
+
+// Run reads lines from in and writes the answers to out until :quit, the
+// end of in, or ctx is done, which is checked between lines.
+func (r *REPL) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	sc := bufio.NewScanner(in)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if r.Prompt != "" {
+			if _, err := io.WriteString(out, r.Prompt); err != nil {
+				return err
+			}
+		}
+		if !sc.Scan() {
+			return sc.Err()
+		}
+		quit, err := r.Exec(sc.Text(), out)
+		if quit || err != nil {
+			return err
+		}
+	}
+}
+
+// Exec runs one line of input, writing its answer to out. It reports
+// whether the line was :quit; errors are those of writing to out.
+func (r *REPL) Exec(line string, out io.Writer) (quit bool, err error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, ":") {
+		if line == "" {
+			return false, r.show(out)
+		}
+		return false, r.query(line, out)
+	}
+	cmd, arg, _ := strings.Cut(line[1:], " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "quit", "q":
+		return true, nil
+	case "fields":
+		for _, fc := range r.browser.FieldCounts() {
+			if _, err := fmt.Fprintf(out, "%s\t%d\n", fc.Field, fc.Count); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	case "stats":
+		return false, r.browser.Stats(output.ParseStatsFields(arg)...).Render(out)
+	case "history":
+		for i, q := range r.history {
+			if _, err := fmt.Fprintf(out, "%d\t%s\n", i+1, q); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	case "sample":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			_, err := fmt.Fprintf(out, "error: :sample takes a count, not %q\n", arg)
+			return false, err
+		}
+		r.Sample = n
+		return false, nil
+	}
+	if n, err := strconv.Atoi(cmd); err == nil && arg == "" {
+		if n < 1 || n > len(r.history) {
+			_, err := fmt.Fprintf(out, "error: no query %d in the history\n", n)
+			return false, err
+		}
+		return false, r.query(r.history[n-1], out)
+	}
+	_, err = fmt.Fprintf(out, "error: unknown command %q (want :fields, :stats, :history, :N, :sample or :quit)\n", line)
+	return false, err
+}
+
+// query runs q and shows its matches.
+func (r *REPL) query(q string, out io.Writer) error {
+	if err := r.browser.SetQuery(q); err != nil {
+		_, err := fmt.Fprintf(out, "error: %v\n", err)
+		return err
+	}
+	if n := len(r.history); n == 0 || r.history[n-1] != q {
+		r.history = append(r.history, q)
+	}
+	return r.show(out)
+}
+
+// show writes the match count and the first Sample matches.
+func (r *REPL) show(out io.Writer) error {
+	results := r.browser.Results()
+	if _, err := fmt.Fprintf(out, "%d of %d entries match\n", len(results), r.browser.Total()); err != nil {
+		return err
+	}
+	for _, e := range results[:min(r.Sample, len(results))] {
+		if _, err := io.WriteString(out, e.Raw+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package flog
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	log := `{"level":"error","status":500}
+{"level":"info","status":200}
+{"level":"error","status":503,"path":"/a"}
+not json
+`
+	p, err := NewPipeline("", WithParser(NewJSONParser()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.Browse(context.Background(), strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewREPL(b)
+	r.Prompt = ""
+	r.Sample = 1
+	in := strings.Join([]string{
+		"level:error",
+		":fields",
+		"(level:error",
+		"status>=503",
+		"status>=503",
+		":1",
+		":history",
+		":stats status",
+		":quit",
+		"level:info",
+	}, "\n")
+	var out strings.Builder
+	if err := r.Run(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"2 of 3 entries match\n" + `{"level":"error","status":500}` + "\n",
+		"level\t2\nstatus\t2\npath\t1\n",
+		"error: ",
+		"1 of 3 entries match\n" + `{"level":"error","status":503,"path":"/a"}` + "\n",
+		"1\tlevel:error\n2\tstatus>=503\n3\tlevel:error\n",
+		"lines: 4  matched: 2  parse errors: 1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+	if want := []string{"level:error", "status>=503", "level:error"}; !reflect.DeepEqual(r.History(), want) {
+		t.Errorf("History() = %q, want %q", r.History(), want)
+	}
+}
@@ -0,0 +1,21 @@
+package flog
+
+import "github.com/ishk9/flog/internal/parser"
+
+// Excerpt is an entry re-located by its ID, with lines of context.
+type Excerpt = parser.Excerpt
+
+// Locate finds the entry whose LogEntry.ID rendered as id (file@offset,
+// as --with-id prints it) among paths, and returns its record with up to
+// before and after records of context (flog show). The inputs are read
+// as the pipeline reads them, so IDs of records split by
+// WithRecordSeparator or WithJSONDocuments are found as well.
+func (p *Pipeline) Locate(paths []string, id string, before, after int) (*Excerpt, error) {
+	eid, err := parser.ParseEntryID(id)
+	if err != nil {
+		return nil, err
+	}
+	r := p.newReader()
+	r.SetLimits(0, 0)
+	return r.Locate(paths, eid, before, after)
+}
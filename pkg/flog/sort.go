@@ -0,0 +1,90 @@
+package flog
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ishk9/flog/internal/output"
+)
+
+// errStopped ends a Sorter's Each when the consumer stops.
+var errStopped = errors.New("flog: stopped")
+
+// WithSort emits the matching entries ordered by a field once the whole
+// input is read (--sort), given as field[:asc|desc] such as
+// "duration:desc"; see output.Sorter for how values compare. Entries
+// beyond maxMemory bytes (0 for output.DefaultSortMemory) are spilled to
+// temporary files. It applies to Run and Iter, and implies WithOrdered.
+func WithSort(key string, maxMemory int64) Option {
+	return func(pl *Pipeline) { pl.sort, pl.sortMem = key, maxMemory }
+}
+
+// newSorter creates the Sorter for WithSort.
+func (p *Pipeline) newSorter() *output.Sorter {
+	mem := p.sortMem
+	if mem <= 0 {
+		mem = output.DefaultSortMemory
+	}
+	return output.NewSorter(*p.sortKey, mem)
+}
+
+// sortEach collects the entries scan passes to its function and passes
+// them to yield in sorted order, until yield returns false.
+func (p *Pipeline) sortEach(scan func(add func(*LogEntry) bool) error, yield func(*LogEntry) bool) error {
+	s := p.newSorter()
+	defer s.Close()
+	var addErr error
+	err := scan(func(e *LogEntry) bool {
+		addErr = s.Add(e)
+		return addErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	if addErr != nil {
+		return addErr
+	}
+	err = s.Each(func(e *LogEntry) error {
+		if !yield(e) {
+			return errStopped
+		}
+		return nil
+	})
+	if errors.Is(err, errStopped) {
+		return nil
+	}
+	return err
+}
+
+// sorted returns the entries of a Run in sorted order, once they are all
+// read, and its error.
+func (p *Pipeline) sorted(ctx context.Context, entries <-chan *LogEntry, errc <-chan error) (<-chan *LogEntry, <-chan error) {
+	out := make(chan *LogEntry, p.workers)
+	errOut := make(chan error, 1)
+	go func() {
+		defer close(errOut)
+		defer close(out)
+		err := p.sortEach(func(add func(*LogEntry) bool) error {
+			for e := range entries {
+				if !add(e) {
+					for range entries {
+					}
+					break
+				}
+			}
+			return <-errc
+		}, func(e *LogEntry) bool {
+			select {
+			case out <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if err == nil {
+			err = ctx.Err()
+		}
+		errOut <- err
+	}()
+	return out, errOut
+}
@@ -0,0 +1,196 @@
+package flog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ishk9/flog/internal/output"
+	"github.com/ishk9/flog/internal/parser"
+)
+
+// maxOpenSplits caps the part files Split keeps open at once. Logs are
+// mostly in time order, so reaching it is rare; when it happens, all are
+// closed and reopened for appending as entries arrive.
+const maxOpenSplits = 64
+
+// SplitOptions configures Split.
+type SplitOptions struct {
+	By        time.Duration // Time span of each part, such as time.Hour
+	OutDir    string        // Directory for the parts, created if missing
+	Name      string        // Name the parts are derived from (default "split.log")
+	TimeField string        // Empty for the entry's Timestamp or detected time field
+}
+
+// SplitResult describes the parts Split wrote.
+type SplitResult struct {
+	Files   []string // Part files in time order
+	Entries int64    // Entries written
+	Untimed int64    // Entries skipped for want of a parseable timestamp
+}
+
+// Split partitions the entries of r that match the pipeline's filter into
+// one file per time span (flog split --by 1h --out-dir DIR), to prepare
+// ranged archives. Spans are aligned to UTC and named after opts.Name with
+// their start inserted before the extension: app.log gives
+// app.2024-05-01T13.log for the hour from 13:00, and daily parts are
+// named app.2024-05-01.log. A .gz or .zst name compresses the parts.
+//
+// Records are copied verbatim, in input order. Parts are replaced
+// atomically, as with --output-file; existing files in OutDir for spans
+// the input does not cover are left alone.
+func (p *Pipeline) Split(ctx context.Context, r io.Reader, opts SplitOptions) (*SplitResult, error) {
+	if opts.By < time.Second {
+		return nil, fmt.Errorf("flog: split: span must be at least 1s, got %v", opts.By)
+	}
+	if opts.Name == "" {
+		opts.Name = "split.log"
+	}
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return nil, fmt.Errorf("flog: split: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pf := p.newFilter()
+	pf.Ordered = true
+	entries, errc := p.run(ctx, r, pf)
+
+	s := &splitter{opts: opts, open: make(map[int64]*output.Writer), created: make(map[int64]string)}
+	res := &SplitResult{}
+	var err error
+	for entry := range entries {
+		if err != nil {
+			continue // Drain after a write error
+		}
+		t, ok := entryTime(entry, opts.TimeField)
+		if !ok {
+			res.Untimed++
+			continue
+		}
+		if err = s.write(t, entry.Raw); err != nil {
+			cancel()
+			continue
+		}
+		res.Entries++
+	}
+	if rerr := <-errc; err == nil {
+		err = rerr
+	}
+	if err != nil {
+		s.abort()
+		return nil, err
+	}
+	if err := s.closeAll(); err != nil {
+		return nil, err
+	}
+	res.Files = s.files()
+	return res, nil
+}
+
+// entryTime returns the time an entry is filed under: its Timestamp, or
+// the value of field (or the detected time field) when that is not set or
+// a field is named.
+func entryTime(entry *LogEntry, field string) (time.Time, bool) {
+	if field == "" && !entry.Timestamp.IsZero() {
+		return entry.Timestamp, true
+	}
+	probe := parser.LogEntry{Fields: entry.Fields}
+	if !parser.NormalizeTime(&probe, field) {
+		return time.Time{}, false
+	}
+	return probe.Timestamp, true
+}
+
+// splitter writes the parts of a Split.
+type splitter struct {
+	opts    SplitOptions
+	open    map[int64]*output.Writer // Span start (unix nanos) → open part
+	created map[int64]string         // Span start → path of every part written
+}
+
+func (s *splitter) write(t time.Time, raw string) error {
+	start := t.UTC().Truncate(s.opts.By).UnixNano()
+	w, ok := s.open[start]
+	if !ok {
+		if len(s.open) == maxOpenSplits {
+			if err := s.closeAll(); err != nil {
+				return err
+			}
+		}
+		path, seen := s.created[start]
+		if !seen {
+			path = s.path(time.Unix(0, start).UTC())
+		}
+		// The first open replaces the part; later ones extend it.
+		var err error
+		if w, err = output.CreateFile(path, seen); err != nil {
+			return fmt.Errorf("flog: split: %w", err)
+		}
+		s.open[start], s.created[start] = w, path
+	}
+	if err := w.WriteLine(raw); err != nil {
+		return fmt.Errorf("flog: split: %s: %w", s.created[start], err)
+	}
+	return nil
+}
+
+// path names the part for the span starting at start.
+func (s *splitter) path(start time.Time) string {
+	layout := "2006-01-02T150405"
+	switch {
+	case s.opts.By%(24*time.Hour) == 0:
+		layout = "2006-01-02"
+	case s.opts.By%time.Hour == 0:
+		layout = "2006-01-02T15"
+	case s.opts.By%time.Minute == 0:
+		layout = "2006-01-02T1504"
+	}
+	base := filepath.Base(s.opts.Name)
+	codec := ""
+	for _, c := range []string{".gz", ".zst"} {
+		if b, ok := strings.CutSuffix(base, c); ok {
+			base, codec = b, c
+		}
+	}
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	return filepath.Join(s.opts.OutDir, stem+"."+start.Format(layout)+ext+codec)
+}
+
+func (s *splitter) closeAll() error {
+	var err error
+	for start, w := range s.open {
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("flog: split: %s: %w", s.created[start], cerr)
+		}
+		delete(s.open, start)
+	}
+	return err
+}
+
+func (s *splitter) abort() {
+	for start, w := range s.open {
+		w.Abort()
+		delete(s.open, start)
+	}
+}
+
+// files lists the parts written, in time order.
+func (s *splitter) files() []string {
+	starts := make([]int64, 0, len(s.created))
+	for start := range s.created {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	out := make([]string, len(starts))
+	for i, start := range starts {
+		out[i] = s.created[start]
+	}
+	return out
+}